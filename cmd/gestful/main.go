@@ -0,0 +1,145 @@
+// Command gestful tooling of gestful
+//
+//	gestful gen [-o gestful_gen.go] [dir]
+//	gestful new <module> [dir]
+//	gestful scaffold resource [-dir .] <Name> field:type[:options]...
+//	gestful migrate [-db app.db] [-dir migrations] [-dry-run] up [version] | down [steps] | status
+//
+// gen generates the columns, the mapper, the repository, the requests and the resource
+// of the structs annotated with //gestful:resource in the package of dir, e.g. by
+//
+//	//go:generate go run github.com/go-gosh/gestful/cmd/gestful gen
+//
+// new makes a gin server project serving the resources added by scaffold, e.g.
+//
+//	gestful new example.com/shop shop
+//	cd shop
+//	gestful scaffold resource Book title:string:required,search price:float published_at:time
+//	go mod tidy && go test ./... && go run .
+//
+// The field types are string, text, int, int64, uint, float, float64, bool, time and datetime,
+// the options are required and search.
+//
+// migrate runs the SQL migrations <version>_<name>.up.sql and <version>_<name>.down.sql of dir on a sqlite database,
+// the applications with Go migrations run them by migration.Command instead
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-gosh/gestful/component/gen"
+	"github.com/go-gosh/gestful/component/migration"
+	"github.com/go-gosh/gestful/component/scaffold"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = runGen(os.Args[2:])
+	case "new":
+		err = runNew(os.Args[2:])
+	case "scaffold":
+		err = runScaffold(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+	gestful gen [-o file] [dir]
+	gestful new <module> [dir]
+	gestful scaffold resource [-dir dir] <Name> field:type[:options]...
+	gestful migrate [-db file] [-dir dir] [-dry-run] up [version] | down [steps] | status`)
+	os.Exit(2)
+}
+
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	output := fs.String("o", gen.Output, "name of the generated file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	return gen.Run(dir, *output)
+}
+
+func runNew(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		usage()
+	}
+	module, dir := args[0], args[0][strings.LastIndex(args[0], "/")+1:]
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	if err := scaffold.New(dir, module); err != nil {
+		return err
+	}
+	fmt.Printf("created %s in %s, add the resources by gestful scaffold resource and run go mod tidy\n", module, dir)
+	return nil
+}
+
+func runScaffold(args []string) error {
+	if len(args) == 0 || args[0] != "resource" {
+		usage()
+	}
+	fs := flag.NewFlagSet("scaffold resource", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory of the project")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		usage()
+	}
+	fields := make([]scaffold.Field, 0, fs.NArg()-1)
+	for _, arg := range fs.Args()[1:] {
+		field, err := scaffold.ParseField(arg)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, field)
+	}
+	return scaffold.Resource(*dir, fs.Arg(0), fields...)
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("db", "app.db", "sqlite database")
+	dir := fs.String("dir", "migrations", "directory of the SQL migrations")
+	dryRun := fs.Bool("dry-run", false, "write the SQL instead of executing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	db, err := gorm.Open(sqlite.Open(*dsn), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	m := migration.New(db)
+	if err := m.AddFS(os.DirFS(*dir), "."); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if *dryRun {
+		args = append([]string{"-dry-run"}, args...)
+	}
+	return migration.Command(context.Background(), m, args, os.Stdout)
+}
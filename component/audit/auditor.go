@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/security"
+	"gorm.io/gorm/schema"
+)
+
+// AuditorAware the current auditor, e.g. the user id of the request principal
+type AuditorAware interface {
+	CurrentAuditor(ctx context.Context) (string, bool)
+}
+
+// AuditorFunc function as AuditorAware
+type AuditorFunc func(ctx context.Context) (string, bool)
+
+func (f AuditorFunc) CurrentAuditor(ctx context.Context) (string, bool) {
+	return f(ctx)
+}
+
+// PrincipalAuditor auditor named from the principal of type P, see security.PrincipalFromContext
+func PrincipalAuditor[P any](name func(principal P) string) AuditorAware {
+	return AuditorFunc(func(ctx context.Context) (string, bool) {
+		principal, ok := security.PrincipalFromContext[P](ctx)
+		if !ok {
+			return "", false
+		}
+		return name(principal), true
+	})
+}
+
+const (
+	FieldCreatedBy = "CreatedBy"
+	FieldUpdatedBy = "UpdatedBy"
+	FieldCreatedAt = "CreatedAt"
+	FieldUpdatedAt = "UpdatedAt"
+)
+
+// Touch populate the audit fields of entity at now, CreatedBy and CreatedAt are populated only when created.
+// The auditor fields are zeroed without a current auditor, so that they are never the ones of the client
+func Touch(ctx context.Context, s *schema.Schema, entity reflect.Value, auditor AuditorAware, now time.Time, created bool) error {
+	values := map[string]interface{}{FieldUpdatedAt: now, FieldUpdatedBy: nil}
+	if created {
+		values[FieldCreatedAt] = now
+		values[FieldCreatedBy] = nil
+	}
+	if auditor != nil {
+		if name, ok := auditor.CurrentAuditor(ctx); ok {
+			values[FieldUpdatedBy] = name
+			if created {
+				values[FieldCreatedBy] = name
+			}
+		}
+	}
+	for name, value := range values {
+		field := s.LookUpField(name)
+		if field == nil {
+			continue
+		}
+		if value == nil {
+			value = reflect.Zero(field.FieldType).Interface()
+		}
+		if err := field.Set(ctx, entity, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TouchChanges populate UpdatedBy and UpdatedAt at now in the changes of an update,
+// the changes of CreatedBy and CreatedAt are dropped and so is UpdatedBy without a current auditor
+func TouchChanges(ctx context.Context, s *schema.Schema, updated map[string]interface{}, auditor AuditorAware, now time.Time) {
+	for _, name := range []string{FieldCreatedBy, FieldCreatedAt, FieldUpdatedBy, FieldUpdatedAt} {
+		if field := s.LookUpField(name); field != nil {
+			delete(updated, field.DBName)
+			delete(updated, field.Name)
+		}
+	}
+	if field := s.LookUpField(FieldUpdatedAt); field != nil {
+		updated[field.DBName] = now
+	}
+	if auditor == nil {
+		return
+	}
+	if field := s.LookUpField(FieldUpdatedBy); field != nil {
+		if name, ok := auditor.CurrentAuditor(ctx); ok {
+			updated[field.DBName] = name
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Operation of a write
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Log audit log of a write
+type Log struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	EntityType string    `gorm:"index:idx_audit_log_entity" json:"entity_type"`
+	EntityID   string    `gorm:"index:idx_audit_log_entity" json:"entity_id"`
+	Operation  Operation `json:"operation"`
+	Actor      string    `json:"actor"`
+	Before     string    `json:"before"`
+	After      string    `json:"after"`
+	Diff       string    `json:"diff"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (Log) TableName() string {
+	return "audit_logs"
+}
+
+// Recorder record the audit logs, db is the transaction of the write
+type Recorder interface {
+	Record(ctx context.Context, db *gorm.DB, log *Log) error
+}
+
+// TableRecorder record the audit logs in the audit_logs table
+type TableRecorder struct{}
+
+func (TableRecorder) Record(_ context.Context, db *gorm.DB, log *Log) error {
+	return db.Create(log).Error
+}
+
+// NewLog audit log of entity changed from before to after at now, before is nil for create and after is nil for delete
+func NewLog(ctx context.Context, entityType, entityID string, op Operation, auditor AuditorAware, now time.Time, before, after interface{}) (*Log, error) {
+	log := &Log{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Operation:  op,
+		CreatedAt:  now,
+	}
+	if auditor != nil {
+		log.Actor, _ = auditor.CurrentAuditor(ctx)
+	}
+	b, bm, err := marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	a, am, err := marshal(after)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := json.Marshal(Diff(bm, am))
+	if err != nil {
+		return nil, err
+	}
+	log.Before, log.After, log.Diff = b, a, string(diff)
+	return log, nil
+}
+
+// Change of a field
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Diff the changed json fields from before to after
+func Diff(before, after map[string]interface{}) map[string]Change {
+	diff := make(map[string]Change)
+	for k, v := range before {
+		if w, ok := after[k]; !ok || !reflect.DeepEqual(v, w) {
+			diff[k] = Change{Before: v, After: after[k]}
+		}
+	}
+	for k, w := range after {
+		if _, ok := before[k]; !ok {
+			diff[k] = Change{After: w}
+		}
+	}
+	return diff
+}
+
+func marshal(v interface{}) (string, map[string]interface{}, error) {
+	if rv := reflect.ValueOf(v); v == nil || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return "", nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", nil, err
+	}
+	return string(b), m, nil
+}
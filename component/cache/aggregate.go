@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+)
+
+// aggregateRows the aggregate rows cached with the types of their group values, which a plain json round trip
+// turns into float64, e.g. the int64 of an integer column
+type aggregateRows []mapper.AggregateRow
+
+// typedValue a group value of aggregateRows, Type is the reflect kind of the value, time or bytes,
+// the values of the other types are decoded as plain json, nil is a null of no type
+type typedValue struct {
+	Type  string          `json:"t,omitempty"`
+	Value json.RawMessage `json:"v"`
+}
+
+type typedRow struct {
+	Group   map[string]typedValue `json:"g"`
+	Metrics map[string]float64    `json:"m"`
+}
+
+var typedKinds = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), "", false,
+	} {
+		typedKinds[reflect.TypeOf(v).Kind().String()] = reflect.TypeOf(v)
+	}
+	typedKinds["time"] = reflect.TypeOf(time.Time{})
+	typedKinds["bytes"] = reflect.TypeOf([]byte(nil))
+}
+
+func (r aggregateRows) MarshalJSON() ([]byte, error) {
+	rows := make([]typedRow, 0, len(r))
+	for _, row := range r {
+		typed := typedRow{Group: make(map[string]typedValue, len(row.Group)), Metrics: row.Metrics}
+		for column, v := range row.Group {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			typed.Group[column] = typedValue{Type: typeName(v), Value: b}
+		}
+		rows = append(rows, typed)
+	}
+	return json.Marshal(rows)
+}
+
+func (r *aggregateRows) UnmarshalJSON(b []byte) error {
+	rows := make([]typedRow, 0)
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return err
+	}
+	res := make(aggregateRows, 0, len(rows))
+	for _, typed := range rows {
+		row := mapper.AggregateRow{Group: make(map[string]interface{}, len(typed.Group)), Metrics: typed.Metrics}
+		for column, v := range typed.Group {
+			t, ok := typedKinds[v.Type]
+			if !ok {
+				t = reflect.TypeOf((*interface{})(nil)).Elem()
+			}
+			value := reflect.New(t)
+			if err := json.Unmarshal(v.Value, value.Interface()); err != nil {
+				return err
+			}
+			row.Group[column] = value.Elem().Interface()
+		}
+		res = append(res, row)
+	}
+	*r = res
+	return nil
+}
+
+// typeName the key of the type of v in typedKinds, empty when v is nil or of another type
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return "time"
+	case []byte:
+		return "bytes"
+	}
+	if t := reflect.TypeOf(v); typedKinds[t.Kind().String()] == t {
+		return t.Kind().String()
+	}
+	return ""
+}
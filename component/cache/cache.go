@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache pluggable cache store, e.g. the in memory LRU or redis
+type Cache interface {
+	// Get the value of key, false when it is missed or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set the value of key expiring after ttl, 0 means no expiration
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
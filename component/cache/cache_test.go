@@ -0,0 +1,334 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testCacheFoo struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type _testCacheAccount struct {
+	ID       uint `gorm:"primaryKey"`
+	Email    string
+	Password string `json:"-"`
+}
+
+func TestLRU_Eviction(t *testing.T) {
+	ctx := context.TODO()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatal("b should be evicted")
+	}
+	if v, ok, _ := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatal("a should be kept")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("len %d", c.Len())
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Now()
+	c := NewLRU(0)
+	c.now = func() time.Time { return now }
+	_ = c.Set(ctx, "a", []byte("1"), time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("a should not be expired")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("a should be expired")
+	}
+}
+
+func TestLoad_Singleflight(t *testing.T) {
+	l := &loader{cache: NewLRU(0), namespace: "test"}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := load(context.TODO(), l, "key", func() (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil || v != 42 {
+				t.Errorf("load %d %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls %d", n)
+	}
+}
+
+func TestGroup_Panic(t *testing.T) {
+	g := &group{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic of the load is left to its caller")
+			}
+		}()
+		_, _ = g.do("key", func() ([]byte, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	go func() {
+		_, err := g.do("key", func() ([]byte, error) { return nil, nil })
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	select {
+	case err := <-done:
+		if !errors.Is(err, errLoadPanicked) {
+			t.Fatalf("waiter %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the waiter is blocked")
+	}
+	v, err := g.do("key", func() ([]byte, error) { return []byte("v"), nil })
+	if err != nil || string(v) != "v" {
+		t.Fatalf("load after the panic %q %v", v, err)
+	}
+}
+
+type _testCachedMapper struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper mapper.BaseMapper[_testCacheFoo]
+}
+
+func (t *_testCachedMapper) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(filepath.Join(t.T().TempDir(), "cache.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(t.db.AutoMigrate(&_testCacheFoo{}))
+	t.mapper = NewMapper[_testCacheFoo](t.db, mapper.NewBaseMapper[_testCacheFoo](t.db), NewLRU(128), time.Minute)
+	t.Require().NoError(t.mapper.Create(context.TODO(), &_testCacheFoo{Name: "foo"}))
+}
+
+func (t *_testCachedMapper) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testCachedMapper) Test_OneById_Cached() {
+	ctx := context.TODO()
+	res, err := t.mapper.OneById(ctx, 1)
+	t.Require().NoError(err)
+	t.Equal("foo", res.Name)
+	t.Require().NoError(t.db.Model(&_testCacheFoo{}).Where("id = ?", 1).Update("name", "bar").Error)
+	res, err = t.mapper.OneById(ctx, 1)
+	t.Require().NoError(err)
+	t.Equal("foo", res.Name)
+}
+
+func (t *_testCachedMapper) Test_Write_Invalidates() {
+	ctx := context.TODO()
+	count, err := t.mapper.Count(ctx, func(db *gorm.DB) *gorm.DB { return db })
+	t.Require().NoError(err)
+	t.Equal(1, count)
+	res, err := t.mapper.All(ctx, func(db *gorm.DB) *gorm.DB { return db })
+	t.Require().NoError(err)
+	t.Len(res, 1)
+
+	t.Require().NoError(t.mapper.Create(ctx, &_testCacheFoo{Name: "bar"}))
+	count, err = t.mapper.Count(ctx, func(db *gorm.DB) *gorm.DB { return db })
+	t.Require().NoError(err)
+	t.Equal(2, count)
+	res, err = t.mapper.All(ctx, func(db *gorm.DB) *gorm.DB { return db })
+	t.Require().NoError(err)
+	t.Len(res, 2)
+
+	t.Require().NoError(t.mapper.UpdateById(ctx, 1, map[string]interface{}{"name": "baz"}))
+	one, err := t.mapper.OneById(ctx, 1)
+	t.Require().NoError(err)
+	t.Equal("baz", one.Name)
+}
+
+func (t *_testCachedMapper) Test_Query_KeyedBySQL() {
+	ctx := context.TODO()
+	t.Require().NoError(t.mapper.Create(ctx, &_testCacheFoo{Name: "bar"}))
+	foo, err := t.mapper.One(ctx, func(db *gorm.DB) *gorm.DB { return db.Where("name = ?", "foo") })
+	t.Require().NoError(err)
+	bar, err := t.mapper.One(ctx, func(db *gorm.DB) *gorm.DB { return db.Where("name = ?", "bar") })
+	t.Require().NoError(err)
+	t.Equal("foo", foo.Name)
+	t.Equal("bar", bar.Name)
+}
+
+func (t *_testCachedMapper) Test_Transaction_Bypass() {
+	ctx := context.TODO()
+	_, err := t.mapper.OneById(ctx, 1)
+	t.Require().NoError(err)
+	t.Require().NoError(t.db.Model(&_testCacheFoo{}).Where("id = ?", 1).Update("name", "bar").Error)
+	t.Require().NoError(t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		res, err := t.mapper.OneById(ctx, 1)
+		t.Require().NoError(err)
+		t.Equal("bar", res.Name)
+		return nil
+	}))
+}
+
+func (t *_testCachedMapper) Test_InvalidateAfterCommit() {
+	ctx := context.TODO()
+	count, err := t.mapper.Count(ctx, mapper.EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Equal(1, count)
+	t.Require().NoError(mapper.Transaction(ctx, t.db, func(txCtx context.Context) error {
+		if err := t.mapper.Create(txCtx, &_testCacheFoo{Name: "bar"}); err != nil {
+			return err
+		}
+		// a reader out of the transaction caches the rows committed so far
+		count, err := t.mapper.Count(ctx, mapper.EmptyWrapperFunc)
+		t.Equal(1, count)
+		return err
+	}))
+	count, err = t.mapper.Count(ctx, mapper.EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal(2, count)
+
+	t.Error(mapper.Transaction(ctx, t.db, func(txCtx context.Context) error {
+		if err := t.mapper.Create(txCtx, &_testCacheFoo{Name: "baz"}); err != nil {
+			return err
+		}
+		return gorm.ErrInvalidData
+	}))
+	count, err = t.mapper.Count(ctx, mapper.EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal(2, count)
+}
+
+func (t *_testCachedMapper) Test_HiddenFields_NotCached() {
+	ctx := context.TODO()
+	t.Require().NoError(t.db.AutoMigrate(&_testCacheAccount{}))
+	accounts := NewMapper[_testCacheAccount](t.db, mapper.NewBaseMapper[_testCacheAccount](t.db), NewLRU(128), time.Minute)
+	t.Require().NoError(accounts.Create(ctx, &_testCacheAccount{Email: "a@example.com", Password: "hash"}))
+	for i := 0; i < 2; i++ {
+		res, err := accounts.OneById(ctx, 1)
+		t.Require().NoError(err)
+		t.Equal("hash", res.Password)
+		all, err := accounts.All(ctx, mapper.EmptyWrapperFunc)
+		t.Require().NoError(err)
+		t.Equal("hash", all[0].Password)
+	}
+	t.False(roundTrips(reflect.TypeOf(&_testCacheAccount{})))
+	t.True(roundTrips(reflect.TypeOf(&mapper.PageRes[_testCacheFoo]{})))
+}
+
+func (t *_testCachedMapper) Test_ShardRoute_Keyed() {
+	other, err := gorm.Open(sqlite.Open(filepath.Join(t.T().TempDir(), "shard1.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(other.AutoMigrate(&_testCacheFoo{}))
+	sharded := NewMapper[_testCacheFoo](t.db, mapper.NewBaseMapper[_testCacheFoo](t.db,
+		mapper.WithSharding("id", mapper.RangeSharding{Bounds: []int64{10}, DBs: []*gorm.DB{t.db, other}})), NewLRU(128), time.Minute)
+	ctx := context.TODO()
+	t.Require().NoError(sharded.Create(ctx, &_testCacheFoo{ID: 11, Name: "bar"}))
+	for _, c := range []struct {
+		key   uint
+		names []string
+	}{{1, []string{"foo"}}, {11, []string{"bar"}}, {1, []string{"foo"}}} {
+		res, err := sharded.All(mapper.WithShardKey(ctx, c.key), mapper.EmptyWrapperFunc)
+		t.Require().NoError(err)
+		t.Len(res, len(c.names))
+		t.Equal(c.names[0], res[0].Name)
+	}
+	res, err := sharded.All(ctx, mapper.EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Len(res, 2)
+
+	byId := func(db *gorm.DB) *gorm.DB { return db.Where("id = ?", 1) }
+	_, err = sharded.One(ctx, byId)
+	t.Require().NoError(err)
+	t.Require().NoError(t.db.Model(&_testCacheFoo{}).Where("id = ?", 1).Update("name", "baz").Error)
+	// the read begins the transaction on its shard, it is not served from the cache
+	t.Require().NoError(sharded.Transaction(ctx, func(ctx context.Context) error {
+		one, err := sharded.One(ctx, byId)
+		t.Require().NoError(err)
+		t.Equal("baz", one.Name)
+		_, ok := mapper.TxFromContext(ctx)
+		t.True(ok)
+		return nil
+	}))
+}
+
+func (t *_testCachedMapper) Test_Aggregate_TypedGroups() {
+	ctx := context.TODO()
+	t.Require().NoError(t.mapper.Create(ctx, &_testCacheFoo{Name: "bar"}))
+	aggregation := mapper.GroupBy("id", "name").Aggregate(mapper.Count())
+	expected, err := mapper.NewBaseMapper[_testCacheFoo](t.db).Aggregate(ctx, aggregation, mapper.EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Require().Len(expected, 2)
+	t.IsType(int64(0), expected[0].Group["id"])
+	for i := 0; i < 2; i++ {
+		rows, err := t.mapper.Aggregate(ctx, aggregation, mapper.EmptyWrapperFunc)
+		t.Require().NoError(err)
+		t.Equal(expected, rows, "loaded and cached")
+	}
+}
+
+func TestAggregateRows(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	rows := aggregateRows{{
+		Group: map[string]interface{}{
+			"int": int64(1), "uint": uint32(2), "float": 1.5, "string": "s", "bool": true,
+			"time": at, "bytes": []byte("b"), "null": nil,
+		},
+		Metrics: map[string]float64{"count": 3},
+	}}
+	b, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res aggregateRows
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, res) {
+		t.Fatalf("round trip %#v", res)
+	}
+}
+
+func (t *_testCachedMapper) Test_NotFound() {
+	_, err := t.mapper.OneById(context.TODO(), 100)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func TestCachedMapper(t *testing.T) {
+	suite.Run(t, &_testCachedMapper{})
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loader cache aside loader of a namespace, every write bumps the generation of the namespace
+// so that all the cached entries of it are invalidated at once
+type loader struct {
+	cache     Cache
+	namespace string
+	ttl       time.Duration
+	group     group
+}
+
+var generationSeq atomic.Uint64
+
+func (l *loader) generationKey() string {
+	return l.namespace + ":gen"
+}
+
+// generation the current generation of the namespace, a new one is made when it is missing
+func (l *loader) generation(ctx context.Context) (string, error) {
+	gen, ok, err := l.cache.Get(ctx, l.generationKey())
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(gen), nil
+	}
+	return l.bump(ctx)
+}
+
+// bump invalidate the cached entries of the namespace
+func (l *loader) bump(ctx context.Context) (string, error) {
+	gen := strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(generationSeq.Add(1), 36)
+	return gen, l.cache.Set(ctx, l.generationKey(), []byte(gen), 0)
+}
+
+// load the cached value of key into v, or load it by fn and cache it,
+// the values which do not survive a json round trip are never cached, see roundTrips
+func load[V any](ctx context.Context, l *loader, key string, fn func() (V, error)) (V, error) {
+	var res V
+	if !roundTrips(reflect.TypeOf(&res).Elem()) {
+		return fn()
+	}
+	gen, err := l.generation(ctx)
+	if err != nil {
+		return fn()
+	}
+	key = l.namespace + ":" + gen + ":" + key
+	if b, ok, err := l.cache.Get(ctx, key); err == nil && ok {
+		if err := json.Unmarshal(b, &res); err == nil {
+			return res, nil
+		}
+	}
+	b, err := l.group.do(key, func() ([]byte, error) {
+		v, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		_ = l.cache.Set(ctx, key, b, l.ttl)
+		return b, nil
+	})
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
+
+var (
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	roundTripTypes  sync.Map
+)
+
+// roundTrips whether the values of t are cached as they are, a struct with an unexported field
+// or a field tagged `json:"-"`, e.g. a password hash, would come back from the cache without it
+func roundTrips(t reflect.Type) bool {
+	if ok, found := roundTripTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := roundTrip(t, make(map[reflect.Type]bool))
+	roundTripTypes.Store(t, ok)
+	return ok
+}
+
+func roundTrip(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return true
+	}
+	visited[t] = true
+	ptr := reflect.PointerTo(t)
+	if (t.Implements(jsonMarshaler) || ptr.Implements(jsonMarshaler)) && ptr.Implements(jsonUnmarshaler) {
+		return true
+	}
+	if (t.Implements(textMarshaler) || ptr.Implements(textMarshaler)) && ptr.Implements(textUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return roundTrip(t.Elem(), visited)
+	case reflect.Map:
+		return roundTrip(t.Key(), visited) && roundTrip(t.Elem(), visited)
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get("json") == "-" || !field.IsExported() && !field.Anonymous {
+				return false
+			}
+			if !roundTrip(field.Type, visited) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/tenant"
+	"gorm.io/gorm"
+)
+
+// NewMapper cache decorator of m, db is the database of m to key the queries by their sql, tenant and shard route.
+// The reads in a transaction and the queries with preloads are not cached, every write invalidates the cache of T
+// once its transaction commits
+func NewMapper[T any](db *gorm.DB, m mapper.BaseMapper[T], c Cache, ttl time.Duration) mapper.BaseMapper[T] {
+	var t T
+	return &cachedMapper[T]{
+		db:     db,
+		mapper: m,
+		loader: &loader{cache: c, namespace: "gestful:mapper:" + reflect.TypeOf(t).String(), ttl: ttl},
+	}
+}
+
+type cachedMapper[T any] struct {
+	db     *gorm.DB
+	mapper mapper.BaseMapper[T]
+	loader *loader
+}
+
+// cacheable whether the reads of ctx are cacheable, the reads in the transaction of a sharded mapper not begun yet
+// are not, as they begin it
+func cacheable(ctx context.Context) bool {
+	return !mapper.InTransaction(ctx)
+}
+
+// scope of the reads of ctx in the keys, the tenant and the shard route
+func scope(ctx context.Context) string {
+	id, _ := tenant.FromContext(ctx)
+	route, _ := mapper.ShardRoute(ctx)
+	return id + ":" + route
+}
+
+// key of the query built by build, false when it is not cacheable
+func (c *cachedMapper[T]) key(ctx context.Context, kind string, build func(tx *gorm.DB) *gorm.DB) (string, bool) {
+	if !cacheable(ctx) {
+		return "", false
+	}
+	tx := build(c.db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true, NewDB: true}))
+	if tx.Error != nil || len(tx.Statement.Preloads) > 0 {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(c.db.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)))
+	return kind + ":" + scope(ctx) + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// invalidate the cache of T after the write succeeded and its transaction, if any, commits,
+// the readers out of the transaction would cache the rows it has not committed yet otherwise
+func (c *cachedMapper[T]) invalidate(ctx context.Context, err error) error {
+	if err == nil {
+		mapper.AfterCommit(ctx, func() {
+			_, _ = c.loader.bump(context.WithoutCancel(ctx))
+		})
+	}
+	return err
+}
+
+func (c *cachedMapper[T]) One(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*T, error) {
+	key, ok := c.key(ctx, "one", func(tx *gorm.DB) *gorm.DB {
+		var t T
+		return wrapper(tx).First(&t)
+	})
+	if !ok {
+		return c.mapper.One(ctx, wrapper)
+	}
+	return load(ctx, c.loader, key, func() (*T, error) {
+		return c.mapper.One(ctx, wrapper)
+	})
+}
+
+func (c *cachedMapper[T]) OneById(ctx context.Context, id uint) (*T, error) {
+	if !cacheable(ctx) {
+		return c.mapper.OneById(ctx, id)
+	}
+	return load(ctx, c.loader, fmt.Sprintf("id:%s:%d", scope(ctx), id), func() (*T, error) {
+		return c.mapper.OneById(ctx, id)
+	})
+}
+
+func (c *cachedMapper[T]) All(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, error) {
+	key, ok := c.key(ctx, "all", func(tx *gorm.DB) *gorm.DB {
+		res := make([]T, 0)
+		return wrapper(tx).Find(&res)
+	})
+	if !ok {
+		return c.mapper.All(ctx, wrapper)
+	}
+	return load(ctx, c.loader, key, func() ([]T, error) {
+		return c.mapper.All(ctx, wrapper)
+	})
+}
+
+func (c *cachedMapper[T]) Paginate(ctx context.Context, pager mapper.Paginator, wrapper func(*gorm.DB) *gorm.DB) (*mapper.PageRes[T], error) {
+	key, ok := c.key(ctx, fmt.Sprintf("page:%d:%d", pager.StartId, pager.Limit), func(tx *gorm.DB) *gorm.DB {
+		res := make([]T, 0)
+		return wrapper(tx).Find(&res)
+	})
+	if !ok {
+		return c.mapper.Paginate(ctx, pager, wrapper)
+	}
+	return load(ctx, c.loader, key, func() (*mapper.PageRes[T], error) {
+		return c.mapper.Paginate(ctx, pager, wrapper)
+	})
+}
+
+func (c *cachedMapper[T]) Count(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (int, error) {
+	key, ok := c.key(ctx, "count", func(tx *gorm.DB) *gorm.DB {
+		var t T
+		var count int64
+		return wrapper(tx.Model(&t)).Count(&count)
+	})
+	if !ok {
+		return c.mapper.Count(ctx, wrapper)
+	}
+	return load(ctx, c.loader, key, func() (int, error) {
+		return c.mapper.Count(ctx, wrapper)
+	})
+}
+
+func (c *cachedMapper[T]) Aggregate(ctx context.Context, aggregation mapper.Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]mapper.AggregateRow, error) {
+	key, ok := c.key(ctx, fmt.Sprintf("aggregate:%v", aggregation), func(tx *gorm.DB) *gorm.DB {
+		res := make([]T, 0)
+		return wrapper(tx).Find(&res)
+	})
+	if !ok {
+		return c.mapper.Aggregate(ctx, aggregation, wrapper)
+	}
+	rows, err := load(ctx, c.loader, key, func() (aggregateRows, error) {
+		return c.mapper.Aggregate(ctx, aggregation, wrapper)
+	})
+	return rows, err
+}
+
+func (c *cachedMapper[T]) metric(ctx context.Context, metric mapper.Metric, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	rows, err := c.Aggregate(ctx, mapper.GroupBy().Aggregate(metric), wrapper)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Metrics[metric.Name()], nil
+}
+
+func (c *cachedMapper[T]) Sum(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.metric(ctx, mapper.Sum(column), wrapper)
+}
+
+func (c *cachedMapper[T]) Avg(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.metric(ctx, mapper.Avg(column), wrapper)
+}
+
+func (c *cachedMapper[T]) Min(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.metric(ctx, mapper.Min(column), wrapper)
+}
+
+func (c *cachedMapper[T]) Max(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.metric(ctx, mapper.Max(column), wrapper)
+}
+
+func (c *cachedMapper[T]) Search(q string) func(*gorm.DB) *gorm.DB {
+	return mapper.SearchWrapperFunc(c.mapper, q)
+}
+
+func (c *cachedMapper[T]) Fields(fields ...string) (*mapper.FieldSet, error) {
+	return c.mapper.Fields(fields...)
+}
+
+func (c *cachedMapper[T]) Delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) error {
+	return c.invalidate(ctx, c.mapper.Delete(ctx, wrapper))
+}
+
+func (c *cachedMapper[T]) DeleteById(ctx context.Context, id uint) error {
+	return c.invalidate(ctx, c.mapper.DeleteById(ctx, id))
+}
+
+func (c *cachedMapper[T]) Create(ctx context.Context, entity *T) error {
+	return c.invalidate(ctx, c.mapper.Create(ctx, entity))
+}
+
+func (c *cachedMapper[T]) Update(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB, updated map[string]interface{}) error {
+	return c.invalidate(ctx, c.mapper.Update(ctx, wrapper, updated))
+}
+
+func (c *cachedMapper[T]) UpdateById(ctx context.Context, id uint, updated map[string]interface{}) error {
+	return c.invalidate(ctx, c.mapper.UpdateById(ctx, id, updated))
+}
+
+func (c *cachedMapper[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.invalidate(ctx, c.mapper.Transaction(ctx, fn))
+}
+
+func (c *cachedMapper[T]) CreateBatch(ctx context.Context, entities []*T, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	res, err := c.mapper.CreateBatch(ctx, entities, mode)
+	return res, c.invalidate(ctx, err)
+}
+
+func (c *cachedMapper[T]) UpdateBatch(ctx context.Context, updates []mapper.BatchUpdate, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	res, err := c.mapper.UpdateBatch(ctx, updates, mode)
+	return res, c.invalidate(ctx, err)
+}
+
+func (c *cachedMapper[T]) DeleteBatch(ctx context.Context, ids []uint, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	res, err := c.mapper.DeleteBatch(ctx, ids, mode)
+	return res, c.invalidate(ctx, err)
+}
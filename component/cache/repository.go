@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/repository"
+)
+
+// NewRepository cache decorator of repo, every write invalidates the cache of T
+func NewRepository[T, ID any](repo repository.CrudRepository[T, ID], c Cache, ttl time.Duration) repository.CrudRepository[T, ID] {
+	var t T
+	return &cachedRepository[T, ID]{
+		repo:   repo,
+		loader: &loader{cache: c, namespace: "gestful:repository:" + reflect.TypeOf(t).String(), ttl: ttl},
+	}
+}
+
+type cachedRepository[T, ID any] struct {
+	repo   repository.CrudRepository[T, ID]
+	loader *loader
+}
+
+func (c *cachedRepository[T, ID]) invalidate(err error) error {
+	if err == nil {
+		_, _ = c.loader.bump(context.Background())
+	}
+	return err
+}
+
+func (c *cachedRepository[T, ID]) Save(entity *T) (*T, error) {
+	res, err := c.repo.Save(entity)
+	return res, c.invalidate(err)
+}
+
+func (c *cachedRepository[T, ID]) SaveAll(entity ...*T) ([]*T, error) {
+	res, err := c.repo.SaveAll(entity...)
+	return res, c.invalidate(err)
+}
+
+func (c *cachedRepository[T, ID]) FindById(id ID) (*T, error) {
+	return load(context.Background(), c.loader, fmt.Sprintf("id:%v", id), func() (*T, error) {
+		return c.repo.FindById(id)
+	})
+}
+
+func (c *cachedRepository[T, ID]) ExistsById(id ID) (bool, error) {
+	return load(context.Background(), c.loader, fmt.Sprintf("exists:%v", id), func() (bool, error) {
+		return c.repo.ExistsById(id)
+	})
+}
+
+func (c *cachedRepository[T, ID]) FindAll() ([]T, error) {
+	return load(context.Background(), c.loader, "all", c.repo.FindAll)
+}
+
+func (c *cachedRepository[T, ID]) FindAllById(id ...ID) ([]T, error) {
+	return load(context.Background(), c.loader, fmt.Sprintf("all:%v", id), func() ([]T, error) {
+		return c.repo.FindAllById(id...)
+	})
+}
+
+func (c *cachedRepository[T, ID]) Count() (int, error) {
+	return load(context.Background(), c.loader, "count", c.repo.Count)
+}
+
+func (c *cachedRepository[T, ID]) DeleteById(id ID) error {
+	return c.invalidate(c.repo.DeleteById(id))
+}
+
+func (c *cachedRepository[T, ID]) Delete(entity T) error {
+	return c.invalidate(c.repo.Delete(entity))
+}
+
+func (c *cachedRepository[T, ID]) DeleteAllById(id ...ID) error {
+	return c.invalidate(c.repo.DeleteAllById(id...))
+}
+
+func (c *cachedRepository[T, ID]) DeleteAll(entity ...T) error {
+	return c.invalidate(c.repo.DeleteAll(entity...))
+}
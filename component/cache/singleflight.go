@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// errLoadPanicked the result of the callers sharing a load which panicked
+var errLoadPanicked = errors.New("load panicked")
+
+// group suppress the duplicate loads of the same key, the concurrent callers share the result of the first one
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+func (g *group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &call{err: errLoadPanicked}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// the waiters are released and the key is dropped when fn panics too, the panic is left to the caller of fn
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
@@ -0,0 +1,139 @@
+package datasource
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go-gosh/gestful/component/web"
+	"gorm.io/gorm"
+)
+
+const (
+	// PluginName name of the Router plugin
+	PluginName = "gestful:datasource"
+	poolKey    = "gestful:datasource:pool"
+)
+
+type primaryKey struct{}
+
+type stickyKey struct{}
+
+// sticky record of the writes of a request
+type sticky struct {
+	written atomic.Bool
+}
+
+// Primary make the reads with ctx go to the primary
+func Primary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Sticky make the reads with ctx go to the primary once a write with ctx succeeded, i.e. read your writes,
+// e.g. within a request, see Middleware
+func Sticky(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(stickyKey{}).(*sticky); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, stickyKey{}, &sticky{})
+}
+
+// Written a write with the Sticky ctx succeeded
+func Written(ctx context.Context) bool {
+	s, ok := ctx.Value(stickyKey{}).(*sticky)
+	return ok && s.written.Load()
+}
+
+// Middleware make the context of every request Sticky
+func Middleware() web.Middleware {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			ctx.SetContext(Sticky(ctx.Context()))
+			next(ctx)
+		}
+	}
+}
+
+// Router gorm plugin routing the reads to the replicas in turn and the writes to the primary, e.g.
+//
+//	err := primary.Use(datasource.NewRouter(replica1, replica2))
+//
+// The mappers and the repositories of the primary use it as they are. The reads are the queries, e.g. Find, First,
+// Count and Scan, built by gorm; the raw SQL, the reads in a transaction and the reads with a Primary ctx or a
+// Sticky ctx which wrote go to the primary
+type Router struct {
+	replicas []gorm.ConnPool
+	next     atomic.Uint64
+}
+
+// NewRouter router of the replicas, they are opened with the dialect of the primary
+func NewRouter(replicas ...*gorm.DB) *Router {
+	r := &Router{replicas: make([]gorm.ConnPool, 0, len(replicas))}
+	for _, replica := range replicas {
+		r.replicas = append(r.replicas, replica.ConnPool)
+	}
+	return r
+}
+
+func (r *Router) Name() string {
+	return PluginName
+}
+
+func (r *Router) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register(PluginName+":read", r.read); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:after_query").Register(PluginName+":restore", restore); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register(PluginName+":read", r.read); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register(PluginName+":restore", restore); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:after_create").Register(PluginName+":write", write); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:after_update").Register(PluginName+":write", write); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:after_delete").Register(PluginName+":write", write); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register(PluginName+":write", write)
+}
+
+// read switch the connection of the statement to a replica
+func (r *Router) read(db *gorm.DB) {
+	if len(r.replicas) == 0 || db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+	ctx := db.Statement.Context
+	if ctx != nil && (ctx.Value(primaryKey{}) != nil || Written(ctx)) {
+		return
+	}
+	db.InstanceSet(poolKey, db.Statement.ConnPool)
+	db.Statement.ConnPool = r.replicas[(r.next.Add(1)-1)%uint64(len(r.replicas))]
+}
+
+// restore switch the connection of the statement back, the statement may be reused by a write
+func restore(db *gorm.DB) {
+	if pool, ok := db.InstanceGet(poolKey); ok && pool != nil {
+		db.Statement.ConnPool = pool.(gorm.ConnPool)
+		db.InstanceSet(poolKey, nil)
+	}
+}
+
+// write record the write of a Sticky ctx
+func write(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if s, ok := db.Statement.Context.Value(stickyKey{}).(*sticky); ok {
+		s.written.Store(true)
+	}
+}
@@ -0,0 +1,159 @@
+package datasource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/repository/support"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testFoo struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type _testRouter struct {
+	suite.Suite
+	dbs    []*gorm.DB
+	db     *gorm.DB
+	mapper mapper.BaseMapper[_testFoo]
+}
+
+// SetupTest the primary and the two replicas are sqlite files, every one has a row named after it
+func (t *_testRouter) SetupTest() {
+	dir := t.T().TempDir()
+	t.dbs = nil
+	for _, name := range []string{"primary", "replica1", "replica2"} {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, name+".db")), &gorm.Config{})
+		t.Require().NoError(err)
+		t.Require().NoError(db.AutoMigrate(&_testFoo{}))
+		t.Require().NoError(db.Create(&_testFoo{Name: name}).Error)
+		t.dbs = append(t.dbs, db)
+	}
+	t.db = t.dbs[0].Debug()
+	t.Require().NoError(t.db.Use(NewRouter(t.dbs[1], t.dbs[2])))
+	t.mapper = mapper.NewBaseMapper[_testFoo](t.db)
+}
+
+func (t *_testRouter) TearDownTest() {
+	for _, db := range t.dbs {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}
+}
+
+func (t *_testRouter) names(ctx context.Context) []string {
+	res, err := t.mapper.All(ctx, mapper.EmptyWrapperFunc)
+	t.Require().NoError(err)
+	names := make([]string, 0, len(res))
+	for _, foo := range res {
+		names = append(names, foo.Name)
+	}
+	return names
+}
+
+func (t *_testRouter) Test_ReadReplicas() {
+	ctx := context.TODO()
+	t.Equal([]string{"replica1"}, t.names(ctx))
+	t.Equal([]string{"replica2"}, t.names(ctx))
+	foo, err := t.mapper.OneById(ctx, 1)
+	t.Require().NoError(err)
+	t.Equal("replica1", foo.Name)
+	count, err := t.mapper.Count(ctx, mapper.EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal(1, count)
+	page, err := t.mapper.Paginate(ctx, mapper.Paginator{Limit: 10}, mapper.EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Equal("replica1", page.Data[0].Name)
+
+	t.Equal([]string{"primary"}, t.names(Primary(ctx)))
+	var name string
+	t.NoError(t.db.Raw("SELECT name FROM _test_foos").Scan(&name).Error)
+	t.Equal("primary", name)
+}
+
+func (t *_testRouter) Test_WritePrimary() {
+	ctx := context.TODO()
+	t.Require().NoError(t.mapper.Create(ctx, &_testFoo{Name: "created"}))
+	t.Require().NoError(t.mapper.UpdateById(ctx, 1, map[string]interface{}{"name": "updated"}))
+	t.Equal([]string{"updated", "created"}, t.names(Primary(ctx)))
+	t.Equal([]string{"replica1"}, t.names(ctx))
+	var count int64
+	t.NoError(t.dbs[1].Model(&_testFoo{}).Count(&count).Error)
+	t.EqualValues(1, count)
+}
+
+func (t *_testRouter) Test_Sticky() {
+	ctx := Sticky(context.TODO())
+	t.Equal([]string{"replica1"}, t.names(ctx))
+	t.False(Written(ctx))
+	t.Require().NoError(t.mapper.Create(ctx, &_testFoo{Name: "created"}))
+	t.True(Written(ctx))
+	t.Equal([]string{"primary", "created"}, t.names(ctx))
+	t.Equal([]string{"replica2"}, t.names(context.TODO()))
+}
+
+func (t *_testRouter) Test_Transaction() {
+	ctx := context.TODO()
+	err := t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		t.Equal([]string{"primary"}, t.names(ctx))
+		return nil
+	})
+	t.NoError(err)
+	var foos []_testFoo
+	t.NoError(t.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Find(&foos).Error
+	}))
+	t.Equal("primary", foos[0].Name)
+}
+
+func (t *_testRouter) Test_Repository() {
+	repo := support.GormJpaRepository[_testFoo, uint]{DB: t.db}
+	foos, err := repo.FindAll()
+	t.Require().NoError(err)
+	t.Equal("replica1", foos[0].Name)
+	saved, err := repo.Save(&_testFoo{Name: "saved"})
+	t.Require().NoError(err)
+	t.EqualValues(2, saved.ID)
+	_, err = repo.FindById(2)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+	found, err := support.GormJpaRepository[_testFoo, uint]{DB: t.db.WithContext(Primary(context.TODO()))}.FindById(2)
+	t.Require().NoError(err)
+	t.Equal("saved", found.Name)
+}
+
+func (t *_testRouter) Test_Middleware() {
+	var names []string
+	mux := http.NewServeMux()
+	web.WithMiddleware(web.ServeMux(mux, ""), Middleware()).Handle(http.MethodPost, "/foos", func(ctx web.Context) {
+		t.Require().NoError(t.mapper.Create(ctx.Context(), &_testFoo{Name: "created"}))
+		names = t.names(ctx.Context())
+		ctx.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/foos", nil))
+	t.EqualValues(http.StatusNoContent, w.Code)
+	t.Equal([]string{"primary", "created"}, names)
+}
+
+func (t *_testRouter) Test_Sources() {
+	sources := NewSources(t.db).Add("replica1", t.dbs[1])
+	t.Equal(t.db, sources.DB(Default))
+	t.Equal(t.dbs[1], sources.DB("replica1"))
+	t.Equal([]string{Default, "replica1"}, sources.Names())
+	t.Panics(func() { sources.DB("unknown") })
+	t.Panics(func() { sources.Add("replica1", t.dbs[2]) })
+}
+
+func TestRouter(t *testing.T) {
+	suite.Run(t, &_testRouter{})
+}
@@ -0,0 +1,59 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Default name of the default datasource of Sources
+const Default = "default"
+
+// Sources named datasources, e.g. to select the datasource of a resource:
+//
+//	gestful.Resource[Report]("reports").DB(sources.DB("analytics"))
+type Sources struct {
+	mu  sync.RWMutex
+	dbs map[string]*gorm.DB
+}
+
+// NewSources sources of the default datasource db
+func NewSources(db *gorm.DB) *Sources {
+	return &Sources{dbs: map[string]*gorm.DB{Default: db}}
+}
+
+// Add add the datasource of name, it panics when the name is already added
+func (s *Sources) Add(name string, db *gorm.DB) *Sources {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dbs[name]; ok {
+		panic(fmt.Sprintf("datasource: %s is already added", name))
+	}
+	s.dbs[name] = db
+	return s
+}
+
+// DB the datasource of name, it panics when the name is unknown
+func (s *Sources) DB(name string) *gorm.DB {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	db, ok := s.dbs[name]
+	if !ok {
+		panic(fmt.Sprintf("datasource: unknown datasource %s", name))
+	}
+	return db
+}
+
+// Names the names of the datasources in order
+func (s *Sources) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]string, 0, len(s.dbs))
+	for name := range s.dbs {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
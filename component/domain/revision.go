@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// RevisionType the write making a revision
+type RevisionType string
+
+const (
+	RevisionTypeCreate RevisionType = "create"
+	RevisionTypeUpdate RevisionType = "update"
+	RevisionTypeDelete RevisionType = "delete"
+)
+
+// Revision state of an entity after a write, the entity is the state before the delete for RevisionTypeDelete
+type Revision[T any] struct {
+	Number    uint         `json:"number"`
+	Type      RevisionType `json:"type"`
+	Actor     string       `json:"actor"`
+	Timestamp time.Time    `json:"timestamp"`
+	Entity    T            `json:"entity"`
+}
@@ -0,0 +1,83 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler subscriber of events
+type Handler func(ctx context.Context, e Event) error
+
+// AllEvents subscribe every event
+const AllEvents = "*"
+
+// Bus in process event bus, sync subscribers run in the transaction of the write and fail it by an error,
+// async subscribers run in goroutines after the transaction is committed and their errors are reported to OnError
+type Bus struct {
+	mu    sync.RWMutex
+	sync  map[string][]Handler
+	async map[string][]Handler
+	wg    sync.WaitGroup
+	// OnError report the errors of async subscribers, optional
+	OnError func(e Event, err error)
+}
+
+// NewBus new in process event bus
+func NewBus() *Bus {
+	return &Bus{sync: make(map[string][]Handler), async: make(map[string][]Handler)}
+}
+
+// Subscribe subscribe the event name synchronously, name AllEvents subscribes every event
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sync[name] = append(b.sync[name], handler)
+}
+
+// SubscribeAsync subscribe the event name asynchronously, name AllEvents subscribes every event
+func (b *Bus) SubscribeAsync(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.async[name] = append(b.async[name], handler)
+}
+
+func (b *Bus) handlers(m map[string][]Handler, name string) []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	res := make([]Handler, 0, len(m[name])+len(m[AllEvents]))
+	res = append(res, m[name]...)
+	return append(res, m[AllEvents]...)
+}
+
+// Publish run the sync subscribers of events in order, it stops at the first error
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	for _, e := range events {
+		for _, handler := range b.handlers(b.sync, e.EventName()) {
+			if err := handler(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PublishAsync run the async subscribers of events in goroutines
+func (b *Bus) PublishAsync(ctx context.Context, events ...Event) {
+	ctx = context.WithoutCancel(ctx)
+	for _, e := range events {
+		for _, handler := range b.handlers(b.async, e.EventName()) {
+			b.wg.Add(1)
+			go func(e Event, handler Handler) {
+				defer b.wg.Done()
+				if err := handler(ctx, e); err != nil && b.OnError != nil {
+					b.OnError(e, err)
+				}
+			}(e, handler)
+		}
+	}
+}
+
+// Wait wait for the running async subscribers
+func (b *Bus) Wait() {
+	b.wg.Wait()
+}
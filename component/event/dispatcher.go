@@ -0,0 +1,35 @@
+package event
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Dispatcher dispatch the events of the writes to the bus and the outbox
+type Dispatcher struct {
+	// Bus optional
+	Bus *Bus
+	// Outbox store the events to the outbox table in the transaction of the write
+	Outbox bool
+}
+
+// InTransaction run the sync subscribers and store the outbox messages in tx
+func (d *Dispatcher) InTransaction(ctx context.Context, tx *gorm.DB, events ...Event) error {
+	if d.Bus != nil {
+		if err := d.Bus.Publish(ctx, events...); err != nil {
+			return err
+		}
+	}
+	if d.Outbox {
+		return Store(tx.WithContext(ctx), events...)
+	}
+	return nil
+}
+
+// AfterCommit run the async subscribers
+func (d *Dispatcher) AfterCommit(ctx context.Context, events ...Event) {
+	if d.Bus != nil {
+		d.Bus.PublishAsync(ctx, events...)
+	}
+}
@@ -0,0 +1,68 @@
+package event
+
+import "reflect"
+
+// Event domain event
+type Event interface {
+	EventName() string
+}
+
+// Aggregate entity registering domain events, they are dispatched after it is written by the service
+type Aggregate interface {
+	DomainEvents() []Event
+	ClearDomainEvents()
+}
+
+// AggregateRoot embeddable Aggregate, e.g.
+//
+//	type Order struct {
+//		event.AggregateRoot
+//		ID uint
+//	}
+type AggregateRoot struct {
+	events []Event
+}
+
+// RegisterEvent register an event to dispatch after the entity is written
+func (a *AggregateRoot) RegisterEvent(e Event) {
+	a.events = append(a.events, e)
+}
+
+func (a *AggregateRoot) DomainEvents() []Event {
+	return a.events
+}
+
+func (a *AggregateRoot) ClearDomainEvents() {
+	a.events = nil
+}
+
+// Lifecycle event of a write through the service, named <Entity>.<Action>, e.g. Order.created
+type Lifecycle struct {
+	Entity string      `json:"entity"`
+	Action string      `json:"action"`
+	Data   interface{} `json:"data"`
+}
+
+func (l Lifecycle) EventName() string {
+	return l.Entity + "." + l.Action
+}
+
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// Collect the lifecycle event of the write and the events registered by entity, the registered events are cleared
+func Collect(entity interface{}, action string) []Event {
+	t := reflect.TypeOf(entity)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	events := []Event{Lifecycle{Entity: t.Name(), Action: action, Data: entity}}
+	if aggregate, ok := entity.(Aggregate); ok {
+		events = append(events, aggregate.DomainEvents()...)
+		aggregate.ClearDomainEvents()
+	}
+	return events
+}
@@ -0,0 +1,31 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher Publisher keeping the messages in memory, for tests
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []OutboxMessage
+	// Err returned by Publish when it is not nil
+	Err error
+}
+
+func (m *MemoryPublisher) Publish(_ context.Context, message OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages the published messages
+func (m *MemoryPublisher) Messages() []OutboxMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]OutboxMessage(nil), m.messages...)
+}
@@ -0,0 +1,48 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxMessage event stored in the outbox table until it is delivered by the Relay
+type OutboxMessage struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"size:191"`
+	Payload     string
+	CreatedAt   time.Time
+	PublishedAt *time.Time `gorm:"index"`
+	Attempts    int
+	LastError   string
+	// ClaimedBy the relay delivering the message, until ClaimedUntil
+	ClaimedBy    string `gorm:"size:64"`
+	ClaimedUntil *time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
+
+// Store write events to the outbox table in tx, the transaction of the write
+func Store(tx *gorm.DB, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	messages := make([]OutboxMessage, 0, len(events))
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, OutboxMessage{Name: e.EventName(), Payload: string(payload), CreatedAt: time.Now()})
+	}
+	return tx.Create(&messages).Error
+}
+
+// Publisher deliver the outbox messages to other systems
+type Publisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error
+}
@@ -0,0 +1,133 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Relay deliver the outbox messages to the publisher in order, failed messages are retried on the next run.
+// The relays sharing an outbox lease the messages they deliver, so a message is published by a single relay
+// and a relay never delivers the messages after the ones leased by another
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+	// BatchSize messages delivered by a run
+	BatchSize int
+	// MaxAttempts messages failed more are not retried, 0 means retry forever
+	MaxAttempts int
+	// ID of the relay in the leases, random by default
+	ID string
+	// Lease how long the messages of a run are leased to the relay, it is to exceed the delivery of a batch
+	Lease time.Duration
+}
+
+// NewRelay relay of the outbox table in db
+func NewRelay(db *gorm.DB, publisher Publisher) *Relay {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &Relay{db: db, publisher: publisher, BatchSize: 100, ID: hex.EncodeToString(id), Lease: time.Minute}
+}
+
+// RelayOnce deliver a batch of pending messages, return the number of delivered messages.
+// The batch stops at the first failed message, the next run retries from it to keep the order
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for i, message := range messages {
+		updated := map[string]interface{}{"attempts": message.Attempts + 1, "claimed_by": "", "claimed_until": nil}
+		failed := r.publisher.Publish(ctx, message)
+		if failed != nil {
+			updated["last_error"] = failed.Error()
+		} else {
+			updated["published_at"] = time.Now()
+			updated["last_error"] = ""
+			delivered++
+		}
+		if err := r.db.WithContext(ctx).Model(&OutboxMessage{}).Where("id=? AND claimed_by=?", message.ID, r.ID).Updates(updated).Error; err != nil {
+			return delivered, err
+		}
+		if failed != nil {
+			return delivered, r.release(ctx, messages[i+1:])
+		}
+	}
+	return delivered, nil
+}
+
+// claim lease the first pending messages to the relay, up to the first one leased by another relay
+func (r *Relay) claim(ctx context.Context) ([]OutboxMessage, error) {
+	now := time.Now()
+	pending := make([]OutboxMessage, 0, r.BatchSize)
+	db := r.db.WithContext(ctx).Where("published_at IS NULL")
+	if r.MaxAttempts > 0 {
+		db = db.Where("attempts<?", r.MaxAttempts)
+	}
+	if err := db.Order("id asc").Limit(r.BatchSize).Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(pending))
+	for _, message := range pending {
+		if message.ClaimedBy != r.ID && message.ClaimedUntil != nil && message.ClaimedUntil.After(now) {
+			break
+		}
+		ids = append(ids, message.ID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	err := r.db.WithContext(ctx).Model(&OutboxMessage{}).
+		Where("id IN ? AND published_at IS NULL", ids).
+		Where("claimed_until IS NULL OR claimed_until<? OR claimed_by=?", now, r.ID).
+		Updates(map[string]interface{}{"claimed_by": r.ID, "claimed_until": now.Add(r.Lease)}).Error
+	if err != nil {
+		return nil, err
+	}
+	claimed := make([]OutboxMessage, 0, len(ids))
+	if err := r.db.WithContext(ctx).Where("id IN ? AND claimed_by=?", ids, r.ID).Order("id asc").Find(&claimed).Error; err != nil {
+		return nil, err
+	}
+	// another relay leased or delivered some of the messages in the meantime, only the leading ones are in order
+	n := 0
+	for n < len(claimed) && claimed[n].ID == ids[n] {
+		n++
+	}
+	if err := r.release(ctx, claimed[n:]); err != nil {
+		return nil, err
+	}
+	return claimed[:n], nil
+}
+
+// release the leases of the messages not delivered by the run
+func (r *Relay) release(ctx context.Context, messages []OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return r.db.WithContext(ctx).Model(&OutboxMessage{}).Where("id IN ? AND claimed_by=?", ids, r.ID).
+		Updates(map[string]interface{}{"claimed_by": "", "claimed_until": nil}).Error
+}
+
+// Run deliver the pending messages every interval until ctx is done, errors are reported to onError
+func (r *Relay) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// Annotation marks a model struct as a resource, optionally followed by the name of the resource, e.g.
+//
+//	//gestful:resource users
+//	type User struct {...}
+//
+// The name defaults to the table name of the model
+const Annotation = "gestful:resource"
+
+// Output default file name of the generated code
+const Output = "gestful_gen.go"
+
+const generatedPrefix = "// Code generated"
+
+// ErrNoResource no struct of the package is annotated
+var ErrNoResource = errors.New("no annotated resource")
+
+// Package the annotated resources of a package
+type Package struct {
+	Name      string
+	Imports   []string
+	Resources []Resource
+}
+
+// Resource annotated model struct
+type Resource struct {
+	Model  string
+	Name   string
+	Fields []Field
+}
+
+// Field column of a Resource
+type Field struct {
+	Name string
+	// Type the type of the field, Elem is the type without the pointer
+	Type, Elem string
+	Column     string
+	JSON       string
+	// JSONTag the json tag of the field in the create request
+	JSONTag string
+	Binding string
+	// ReadOnly the field is not in the create and update requests,
+	// e.g. the primary key, the timestamps and the fields tagged `gestful:"readonly"`
+	ReadOnly bool
+	// Filter the field is an equality filter of the page request, tagged `gestful:"filter"`
+	Filter bool
+}
+
+// Writable the fields of the create and update requests
+func (r Resource) Writable() []Field {
+	res := make([]Field, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		if !f.ReadOnly && f.JSON != "-" {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Filters the fields of the page request
+func (r Resource) Filters() []Field {
+	res := make([]Field, 0)
+	for _, f := range r.Fields {
+		if f.Filter && f.JSON != "-" {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+var timestamps = map[string]bool{"CreatedAt": true, "UpdatedAt": true, "DeletedAt": true}
+
+// Parse the annotated resources of the package in dir, the test files and the generated files are skipped
+func Parse(dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(file) {
+			continue
+		}
+		files = append(files, file)
+	}
+	p := &parsed{structs: make(map[string]*ast.StructType), files: make(map[string]*ast.File), types: make(map[string]bool), imports: make(map[string]string)}
+	for _, file := range files {
+		p.collect(file)
+	}
+	pkg := &Package{}
+	for _, file := range files {
+		pkg.Name = file.Name.Name
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.TypeSpec)
+				doc := spec.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				name, ok := annotation(doc)
+				if !ok {
+					continue
+				}
+				st, ok := spec.Type.(*ast.StructType)
+				if !ok || spec.TypeParams != nil {
+					return nil, fmt.Errorf("gen: %s is not a struct", spec.Name.Name)
+				}
+				res, err := p.resource(file, spec.Name.Name, name, st)
+				if err != nil {
+					return nil, err
+				}
+				pkg.Resources = append(pkg.Resources, *res)
+			}
+		}
+	}
+	if len(pkg.Resources) == 0 {
+		return nil, fmt.Errorf("gen: %s: %w", dir, ErrNoResource)
+	}
+	sort.Slice(pkg.Resources, func(i, j int) bool { return pkg.Resources[i].Model < pkg.Resources[j].Model })
+	pkg.Imports = p.importList()
+	return pkg, nil
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, generatedPrefix) && strings.HasSuffix(c.Text, "DO NOT EDIT.") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func annotation(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, c := range doc.List {
+		text := strings.TrimPrefix(c.Text, "//")
+		if text == Annotation || strings.HasPrefix(text, Annotation+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(text, Annotation)), true
+		}
+	}
+	return "", false
+}
+
+type parsed struct {
+	// structs the struct types of the package
+	structs map[string]*ast.StructType
+	// files the files declaring the structs
+	files map[string]*ast.File
+	// types the other named types of the package
+	types map[string]bool
+	// imports the imports used by the fields, path by name
+	imports map[string]string
+}
+
+func (p *parsed) collect(file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				p.structs[spec.Name.Name] = st
+				p.files[spec.Name.Name] = file
+			} else {
+				p.types[spec.Name.Name] = true
+			}
+		}
+	}
+}
+
+func (p *parsed) importList() []string {
+	res := make([]string, 0, len(p.imports))
+	for name, path := range p.imports {
+		if base(path) == name {
+			res = append(res, strconv.Quote(path))
+		} else {
+			res = append(res, name+" "+strconv.Quote(path))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (p *parsed) resource(file *ast.File, model, name string, st *ast.StructType) (*Resource, error) {
+	naming := schema.NamingStrategy{}
+	if name == "" {
+		name = naming.TableName(model)
+	}
+	res := &Resource{Model: model, Name: name}
+	if err := p.fields(file, res, st, map[string]bool{model: true}); err != nil {
+		return nil, err
+	}
+	for _, f := range res.Fields {
+		if f.Column == "id" && f.Type != "uint" {
+			return nil, fmt.Errorf("gen: the primary key of %s is %s, the services require uint", model, f.Type)
+		}
+	}
+	return res, nil
+}
+
+func (p *parsed) fields(file *ast.File, res *Resource, st *ast.StructType, visiting map[string]bool) error {
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			v, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(v)
+		}
+		settings := schema.ParseTagSetting(tag.Get("gorm"), ";")
+		if settings["-"] == "-" {
+			continue
+		}
+		if len(field.Names) == 0 {
+			if err := p.embedded(file, res, field.Type, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+		elem := field.Type
+		if star, ok := elem.(*ast.StarExpr); ok {
+			elem = star.X
+		}
+		if !p.isColumn(elem) {
+			continue
+		}
+		p.use(file, field.Type)
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			res.Fields = append(res.Fields, p.field(ident.Name, field.Type, elem, tag, settings))
+		}
+	}
+	return nil
+}
+
+func (p *parsed) field(name string, typ, elem ast.Expr, tag reflect.StructTag, settings map[string]string) Field {
+	f := Field{
+		Name:    name,
+		Type:    types.ExprString(typ),
+		Elem:    types.ExprString(elem),
+		Column:  settings["COLUMN"],
+		JSON:    name,
+		JSONTag: tag.Get("json"),
+		Binding: tag.Get("binding"),
+	}
+	if f.Column == "" {
+		f.Column = schema.NamingStrategy{}.ColumnName("", name)
+	}
+	if json := strings.Split(f.JSONTag, ",")[0]; json != "" {
+		f.JSON = json
+	}
+	if f.JSONTag == "" {
+		f.JSONTag = f.JSON
+	}
+	_, primary := settings["PRIMARYKEY"]
+	_, primaryKey := settings["PRIMARY_KEY"]
+	f.ReadOnly = primary || primaryKey || name == "ID" || timestamps[name] ||
+		settings["AUTOCREATETIME"] != "" || settings["AUTOUPDATETIME"] != ""
+	for _, option := range strings.Split(tag.Get("gestful"), ",") {
+		switch strings.TrimSpace(option) {
+		case "readonly", "version":
+			f.ReadOnly = true
+		case "filter":
+			f.Filter = true
+		}
+	}
+	return f
+}
+
+// embedded flatten the embedded gorm.Model and the embedded structs of the package like gorm does
+func (p *parsed) embedded(file *ast.File, res *Resource, typ ast.Expr, visiting map[string]bool) error {
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && t.Sel.Name == "Model" && p.importPath(file, x.Name) == "gorm.io/gorm" {
+			p.imports["time"] = "time"
+			p.imports[x.Name] = "gorm.io/gorm"
+			res.Fields = append(res.Fields,
+				Field{Name: "ID", Type: "uint", Elem: "uint", Column: "id", JSON: "ID", JSONTag: "ID", ReadOnly: true},
+				Field{Name: "CreatedAt", Type: "time.Time", Elem: "time.Time", Column: "created_at", JSON: "CreatedAt", JSONTag: "CreatedAt", ReadOnly: true},
+				Field{Name: "UpdatedAt", Type: "time.Time", Elem: "time.Time", Column: "updated_at", JSON: "UpdatedAt", JSONTag: "UpdatedAt", ReadOnly: true},
+				Field{Name: "DeletedAt", Type: x.Name + ".DeletedAt", Elem: x.Name + ".DeletedAt", Column: "deleted_at", JSON: "DeletedAt", JSONTag: "DeletedAt", ReadOnly: true},
+			)
+		}
+	case *ast.Ident:
+		st, ok := p.structs[t.Name]
+		if !ok || visiting[t.Name] {
+			return nil
+		}
+		visiting[t.Name] = true
+		defer delete(visiting, t.Name)
+		return p.fields(p.files[t.Name], res, st, visiting)
+	}
+	return nil
+}
+
+// isColumn the field of typ is a column instead of a relation
+func (p *parsed) isColumn(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return basicTypes[t.Name] || p.types[t.Name]
+	case *ast.SelectorExpr:
+		return true
+	case *ast.ArrayType:
+		elem, ok := t.Elt.(*ast.Ident)
+		return t.Len == nil && ok && elem.Name == "byte"
+	}
+	return false
+}
+
+// use record the imports used by typ
+func (p *parsed) use(file *ast.File, typ ast.Expr) {
+	ast.Inspect(typ, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				if path := p.importPath(file, x.Name); path != "" {
+					p.imports[x.Name] = path
+				}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+func (p *parsed) importPath(file *ast.File, name string) string {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil && spec.Name.Name == name || spec.Name == nil && base(path) == name {
+			return path
+		}
+	}
+	return ""
+}
+
+func base(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
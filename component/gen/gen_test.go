@@ -0,0 +1,77 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type _testGen struct {
+	suite.Suite
+}
+
+func (t *_testGen) Test_Parse() {
+	pkg, err := Parse("internal/example")
+	t.Require().NoError(err)
+	t.Equal("example", pkg.Name)
+	t.Equal([]string{`"gorm.io/gorm"`, `"time"`}, pkg.Imports)
+	t.Require().Len(pkg.Resources, 2)
+
+	author := pkg.Resources[0]
+	t.Equal("authors", author.Name)
+	t.Equal([]string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt", "Name", "Email"}, names(author.Fields))
+	t.Equal([]string{"Name", "Email"}, names(author.Writable()))
+	t.Equal("email_address", author.Fields[5].Column)
+	t.Equal("required", author.Fields[4].Binding)
+
+	book := pkg.Resources[1]
+	t.Equal("library_books", book.Name)
+	t.Equal([]string{"Title", "AuthorID", "Status", "PublishedAt"}, names(book.Writable()))
+	t.Equal([]string{"AuthorID", "Status"}, names(book.Filters()))
+	t.Equal("*time.Time", book.Fields[4].Type)
+	t.Equal("time.Time", book.Fields[4].Elem)
+}
+
+// Test_Generate the generated code of the example is up to date, the example package compiles it
+func (t *_testGen) Test_Generate() {
+	pkg, err := Parse("internal/example")
+	t.Require().NoError(err)
+	src, err := Generate(pkg)
+	t.Require().NoError(err)
+	expected, err := os.ReadFile(filepath.Join("internal/example", Output))
+	t.Require().NoError(err)
+	t.Equal(string(expected), string(src))
+}
+
+func (t *_testGen) Test_Errors() {
+	dir := t.T().TempDir()
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "model.go"), []byte("package model\n\ntype Foo struct{}\n"), 0o644))
+	_, err := Parse(dir)
+	t.ErrorIs(err, ErrNoResource)
+
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "model.go"), []byte("package model\n\n//gestful:resource\ntype Foo struct {\n\tID string\n}\n"), 0o644))
+	_, err = Parse(dir)
+	t.ErrorContains(err, "uint")
+
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "model.go"), []byte("package model\n\n//gestful:resource\ntype Foo struct {\n\tID uint\n}\n"), 0o644))
+	t.NoError(Run(dir, Output))
+	_, err = os.Stat(filepath.Join(dir, Output))
+	t.NoError(err)
+	pkg, err := Parse(dir)
+	t.Require().NoError(err)
+	t.Len(pkg.Resources, 1)
+}
+
+func names(fields []Field) []string {
+	res := make([]string, 0, len(fields))
+	for _, f := range fields {
+		res = append(res, f.Name)
+	}
+	return res
+}
+
+func TestGen(t *testing.T) {
+	suite.Run(t, &_testGen{})
+}
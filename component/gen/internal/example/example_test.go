@@ -0,0 +1,86 @@
+package example
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-gosh/gestful"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testExample struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testExample) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&Author{}, &Book{}))
+	registry := gestful.NewRegistry()
+	RegisterResources(registry, t.db)
+	mux := http.NewServeMux()
+	registry.Register(web.ServeMux(mux, ""))
+	t.handler = mux
+}
+
+func (t *_testExample) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testExample) do(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testExample) Test_Resources() {
+	t.NotEqualValues(http.StatusOK, t.do(http.MethodPost, "/authors", `{"data":{"email":"a@b.c"}}`).Code)
+	t.Require().EqualValues(http.StatusOK, t.do(http.MethodPost, "/authors", `{"data":{"name":"alice","email":"a@b.c"}}`).Code)
+	t.Require().EqualValues(http.StatusOK, t.do(http.MethodPost, "/library_books", `{"data":{"title":"foo","author_id":1,"status":"draft","pages":10}}`).Code)
+	t.Require().EqualValues(http.StatusOK, t.do(http.MethodPost, "/library_books", `{"data":{"title":"bar","author_id":1,"status":"published"}}`).Code)
+
+	w := t.do(http.MethodGet, "/library_books?status=published", "")
+	t.Require().EqualValues(http.StatusOK, w.Code)
+	var page mapper.PageRes[Book]
+	t.Require().NoError(json.Unmarshal(w.Body.Bytes(), &page))
+	t.Require().Len(page.Data, 1)
+	t.Equal("bar", page.Data[0].Title)
+
+	t.Require().EqualValues(http.StatusOK, t.do(http.MethodPut, "/library_books/1", `{"data":{"status":"published"}}`).Code)
+	book, err := NewBookRepository(t.db).FindById(1)
+	t.Require().NoError(err)
+	t.Equal(Status("published"), book.Status)
+	t.Equal("foo", book.Title)
+	t.Zero(book.Pages)
+
+	author, err := NewAuthorRepository(t.db).FindById(1)
+	t.Require().NoError(err)
+	t.Equal("a@b.c", author.Email)
+	count, err := NewBookRepository(t.db).Count()
+	t.NoError(err)
+	t.Equal(2, count)
+}
+
+func (t *_testExample) Test_Fields() {
+	t.Equal("books", BookFields.Status.Table)
+	t.Equal("email_address", AuthorFields.Email.Name)
+}
+
+func TestExample(t *testing.T) {
+	suite.Run(t, &_testExample{})
+}
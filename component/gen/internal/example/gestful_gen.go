@@ -0,0 +1,194 @@
+// Code generated by gestful gen. DO NOT EDIT.
+
+package example
+
+import (
+	"time"
+
+	"github.com/go-gosh/gestful"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/query"
+	"github.com/go-gosh/gestful/component/repository"
+	"github.com/go-gosh/gestful/component/repository/support"
+	"github.com/go-gosh/gestful/component/service"
+	"gorm.io/gorm"
+)
+
+// AuthorFields the columns of Author
+var AuthorFields = query.Bind[struct {
+	ID        query.Column[uint]
+	CreatedAt query.Column[time.Time]
+	UpdatedAt query.Column[time.Time]
+	DeletedAt query.Column[gorm.DeletedAt]
+	Name      query.Column[string]
+	Email     query.Column[string]
+}, Author]()
+
+// NewAuthorMapper the mapper of Author
+func NewAuthorMapper(db *gorm.DB, opts ...mapper.Option) mapper.BaseMapper[Author] {
+	return mapper.NewBaseMapper[Author](db, opts...)
+}
+
+// NewAuthorRepository the repository of Author
+func NewAuthorRepository(db *gorm.DB) repository.CrudRepository[Author, uint] {
+	return support.GormJpaRepository[Author, uint]{DB: db}
+}
+
+// AuthorCreateRequest create request of Author
+type AuthorCreateRequest struct {
+	Data struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email"`
+	} `json:"data"`
+}
+
+func (r AuthorCreateRequest) MakeCreate() (*Author, error) {
+	entity := &Author{}
+	entity.Name = r.Data.Name
+	entity.Email = r.Data.Email
+	return entity, nil
+}
+
+// AuthorPageRequest page request of Author, the filters are compared by equality
+type AuthorPageRequest struct {
+	service.BasePageRequest
+	Name *string `json:"name" form:"name"`
+}
+
+func (r AuthorPageRequest) MakeWrapper() func(*gorm.DB) *gorm.DB {
+	conditions := make([]query.Condition, 0)
+	if r.Name != nil {
+		conditions = append(conditions, AuthorFields.Name.Eq(*r.Name))
+	}
+	return query.New[Author]().Where(conditions...).Wrapper()
+}
+
+// AuthorUpdateRequest update request of Author, the absent fields are not changed
+type AuthorUpdateRequest struct {
+	Data struct {
+		Name  *string `json:"name,omitempty"`
+		Email *string `json:"email,omitempty"`
+	} `json:"data"`
+}
+
+func (r AuthorUpdateRequest) MakeUpdate() (map[string]interface{}, error) {
+	changes := make(map[string]interface{})
+	if r.Data.Name != nil {
+		changes["name"] = *r.Data.Name
+	}
+	if r.Data.Email != nil {
+		changes["email_address"] = *r.Data.Email
+	}
+	return changes, nil
+}
+
+// AuthorResource the authors resource with the generated requests
+func AuthorResource(db *gorm.DB, opts ...mapper.Option) *gestful.ResourceBuilder[Author] {
+	return gestful.Resource[Author]("authors").
+		Mapper(NewAuthorMapper(db, opts...)).
+		Create(AuthorCreateRequest{}).
+		Page(AuthorPageRequest{}).
+		Update(AuthorUpdateRequest{})
+}
+
+// BookFields the columns of Book
+var BookFields = query.Bind[struct {
+	ID          query.Column[uint]
+	Title       query.Column[string]
+	AuthorID    query.Column[uint]
+	Status      query.Column[Status]
+	PublishedAt query.Column[time.Time]
+	Pages       query.Column[int]
+	CreatedAt   query.Column[time.Time]
+}, Book]()
+
+// NewBookMapper the mapper of Book
+func NewBookMapper(db *gorm.DB, opts ...mapper.Option) mapper.BaseMapper[Book] {
+	return mapper.NewBaseMapper[Book](db, opts...)
+}
+
+// NewBookRepository the repository of Book
+func NewBookRepository(db *gorm.DB) repository.CrudRepository[Book, uint] {
+	return support.GormJpaRepository[Book, uint]{DB: db}
+}
+
+// BookCreateRequest create request of Book
+type BookCreateRequest struct {
+	Data struct {
+		Title       string     `json:"title" binding:"required"`
+		AuthorID    uint       `json:"author_id"`
+		Status      Status     `json:"status"`
+		PublishedAt *time.Time `json:"published_at"`
+	} `json:"data"`
+}
+
+func (r BookCreateRequest) MakeCreate() (*Book, error) {
+	entity := &Book{}
+	entity.Title = r.Data.Title
+	entity.AuthorID = r.Data.AuthorID
+	entity.Status = r.Data.Status
+	entity.PublishedAt = r.Data.PublishedAt
+	return entity, nil
+}
+
+// BookPageRequest page request of Book, the filters are compared by equality
+type BookPageRequest struct {
+	service.BasePageRequest
+	AuthorID *uint   `json:"author_id" form:"author_id"`
+	Status   *Status `json:"status" form:"status"`
+}
+
+func (r BookPageRequest) MakeWrapper() func(*gorm.DB) *gorm.DB {
+	conditions := make([]query.Condition, 0)
+	if r.AuthorID != nil {
+		conditions = append(conditions, BookFields.AuthorID.Eq(*r.AuthorID))
+	}
+	if r.Status != nil {
+		conditions = append(conditions, BookFields.Status.Eq(*r.Status))
+	}
+	return query.New[Book]().Where(conditions...).Wrapper()
+}
+
+// BookUpdateRequest update request of Book, the absent fields are not changed
+type BookUpdateRequest struct {
+	Data struct {
+		Title       *string    `json:"title,omitempty"`
+		AuthorID    *uint      `json:"author_id,omitempty"`
+		Status      *Status    `json:"status,omitempty"`
+		PublishedAt *time.Time `json:"published_at,omitempty"`
+	} `json:"data"`
+}
+
+func (r BookUpdateRequest) MakeUpdate() (map[string]interface{}, error) {
+	changes := make(map[string]interface{})
+	if r.Data.Title != nil {
+		changes["title"] = *r.Data.Title
+	}
+	if r.Data.AuthorID != nil {
+		changes["author_id"] = *r.Data.AuthorID
+	}
+	if r.Data.Status != nil {
+		changes["status"] = *r.Data.Status
+	}
+	if r.Data.PublishedAt != nil {
+		changes["published_at"] = *r.Data.PublishedAt
+	}
+	return changes, nil
+}
+
+// BookResource the library_books resource with the generated requests
+func BookResource(db *gorm.DB, opts ...mapper.Option) *gestful.ResourceBuilder[Book] {
+	return gestful.Resource[Book]("library_books").
+		Mapper(NewBookMapper(db, opts...)).
+		Create(BookCreateRequest{}).
+		Page(BookPageRequest{}).
+		Update(BookUpdateRequest{})
+}
+
+// RegisterResources add the resources of the package to registry
+func RegisterResources(registry *gestful.Registry, db *gorm.DB, opts ...mapper.Option) {
+	registry.Add(
+		AuthorResource(db, opts...),
+		BookResource(db, opts...),
+	)
+}
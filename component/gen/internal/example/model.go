@@ -0,0 +1,33 @@
+// Package example models of the generator tests, gestful_gen.go is generated by gestful gen
+package example
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:generate go run github.com/go-gosh/gestful/cmd/gestful gen
+
+type Status string
+
+//gestful:resource
+type Author struct {
+	gorm.Model
+	Name  string `json:"name" binding:"required" gestful:"filter,search"`
+	Email string `json:"email" gorm:"column:email_address"`
+	Books []Book `json:"books"`
+}
+
+//gestful:resource library_books
+type Book struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `json:"title" binding:"required"`
+	AuthorID    uint       `json:"author_id" gestful:"filter"`
+	Status      Status     `json:"status" gestful:"filter"`
+	PublishedAt *time.Time `json:"published_at"`
+	Pages       int        `json:"pages" gestful:"readonly"`
+	Author      *Author    `json:"author,omitempty"`
+	secret      string
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,171 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// imports of the generated code
+var imports = []string{
+	"github.com/go-gosh/gestful",
+	"github.com/go-gosh/gestful/component/mapper",
+	"github.com/go-gosh/gestful/component/query",
+	"github.com/go-gosh/gestful/component/repository",
+	"github.com/go-gosh/gestful/component/repository/support",
+	"github.com/go-gosh/gestful/component/service",
+	"gorm.io/gorm",
+}
+
+var tmpl = template.Must(template.New("gen").Parse(`// Code generated by gestful gen. DO NOT EDIT.
+
+package {{.Name}}
+
+import (
+{{- range .Imports}}
+{{if .}}	{{.}}{{end}}
+{{- end}}
+)
+{{range .Resources}}{{$model := .Model}}
+// {{$model}}Fields the columns of {{$model}}
+var {{$model}}Fields = query.Bind[struct {
+{{- range .Fields}}
+	{{.Name}} query.Column[{{.Elem}}]
+{{- end}}
+}, {{$model}}]()
+
+// New{{$model}}Mapper the mapper of {{$model}}
+func New{{$model}}Mapper(db *gorm.DB, opts ...mapper.Option) mapper.BaseMapper[{{$model}}] {
+	return mapper.NewBaseMapper[{{$model}}](db, opts...)
+}
+
+// New{{$model}}Repository the repository of {{$model}}
+func New{{$model}}Repository(db *gorm.DB) repository.CrudRepository[{{$model}}, uint] {
+	return support.GormJpaRepository[{{$model}}, uint]{DB: db}
+}
+
+// {{$model}}CreateRequest create request of {{$model}}
+type {{$model}}CreateRequest struct {
+	Data struct {
+{{- range .Writable}}
+		{{.Name}} {{.Type}} ` + "`" + `json:"{{.JSONTag}}"{{if .Binding}} binding:"{{.Binding}}"{{end}}` + "`" + `
+{{- end}}
+	} ` + "`" + `json:"data"` + "`" + `
+}
+
+func (r {{$model}}CreateRequest) MakeCreate() (*{{$model}}, error) {
+	entity := &{{$model}}{}
+{{- range .Writable}}
+	entity.{{.Name}} = r.Data.{{.Name}}
+{{- end}}
+	return entity, nil
+}
+
+// {{$model}}PageRequest page request of {{$model}}, the filters are compared by equality
+type {{$model}}PageRequest struct {
+	service.BasePageRequest
+{{- range .Filters}}
+	{{.Name}} *{{.Elem}} ` + "`" + `json:"{{.JSON}}" form:"{{.JSON}}"` + "`" + `
+{{- end}}
+}
+
+func (r {{$model}}PageRequest) MakeWrapper() func(*gorm.DB) *gorm.DB {
+	conditions := make([]query.Condition, 0)
+{{- range .Filters}}
+	if r.{{.Name}} != nil {
+		conditions = append(conditions, {{$model}}Fields.{{.Name}}.Eq(*r.{{.Name}}))
+	}
+{{- end}}
+	return query.New[{{$model}}]().Where(conditions...).Wrapper()
+}
+
+// {{$model}}UpdateRequest update request of {{$model}}, the absent fields are not changed
+type {{$model}}UpdateRequest struct {
+	Data struct {
+{{- range .Writable}}
+		{{.Name}} *{{.Elem}} ` + "`" + `json:"{{.JSON}},omitempty"` + "`" + `
+{{- end}}
+	} ` + "`" + `json:"data"` + "`" + `
+}
+
+func (r {{$model}}UpdateRequest) MakeUpdate() (map[string]interface{}, error) {
+	changes := make(map[string]interface{})
+{{- range .Writable}}
+	if r.Data.{{.Name}} != nil {
+		changes["{{.Column}}"] = *r.Data.{{.Name}}
+	}
+{{- end}}
+	return changes, nil
+}
+
+// {{$model}}Resource the {{.Name}} resource with the generated requests
+func {{$model}}Resource(db *gorm.DB, opts ...mapper.Option) *gestful.ResourceBuilder[{{$model}}] {
+	return gestful.Resource[{{$model}}]("{{.Name}}").
+		Mapper(New{{$model}}Mapper(db, opts...)).
+		Create({{$model}}CreateRequest{}).
+		Page({{$model}}PageRequest{}).
+		Update({{$model}}UpdateRequest{})
+}
+{{end}}
+// RegisterResources add the resources of the package to registry
+func RegisterResources(registry *gestful.Registry, db *gorm.DB, opts ...mapper.Option) {
+	registry.Add(
+{{- range .Resources}}
+		{{.Model}}Resource(db, opts...),
+{{- end}}
+	)
+}
+`))
+
+// Generate the code of pkg: the columns, the mapper, the repository, the requests and the resource of every resource
+func Generate(pkg *Package) ([]byte, error) {
+	specs := make(map[string]bool)
+	for _, path := range imports {
+		specs[strconv.Quote(path)] = true
+	}
+	for _, spec := range pkg.Imports {
+		specs[spec] = true
+	}
+	data := *pkg
+	std, others := make([]string, 0), make([]string, 0, len(specs))
+	for spec := range specs {
+		path, _ := strconv.Unquote(spec[strings.Index(spec, `"`):])
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	// the empty spec separates the standard library from the others
+	data.Imports = append(append(std, ""), others...)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: format: %w", err)
+	}
+	return src, nil
+}
+
+// Run generate the code of the package in dir into the file output of dir
+func Run(dir, output string) error {
+	pkg, err := Parse(dir)
+	if err != nil {
+		return err
+	}
+	src, err := Generate(pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, output), src, 0o644)
+}
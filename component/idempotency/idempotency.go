@@ -0,0 +1,193 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// Header of the idempotency key of a request
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set to true on the replayed responses
+const ReplayedHeader = "Idempotent-Replayed"
+
+// DefaultTTL how long a key is remembered by default
+const DefaultTTL = 24 * time.Hour
+
+// DefaultMaxBodySize the largest body read to fingerprint a request by default
+const DefaultMaxBodySize = 1 << 20
+
+// MaxKeyLength the longest Idempotency-Key accepted, the longer keys are rejected with 400
+const MaxKeyLength = 128
+
+type options struct {
+	ttl         time.Duration
+	scope       func(ctx web.Context) string
+	maxBodySize int64
+}
+
+type Option func(*options)
+
+// WithTTL remember the keys for ttl
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithScope scope the keys by scope, the keys are scoped by the tenant and the principal by default
+func WithScope(scope func(ctx web.Context) string) Option {
+	return func(o *options) {
+		o.scope = scope
+	}
+}
+
+// WithMaxBodySize reject the requests with a key whose body is larger than size with 413
+func WithMaxBodySize(size int64) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
+
+// defaultScope the tenant and the principal of the request, so a key never replays the response of another user,
+// the principals are told apart by fmt.Sprint, e.g. by their String method
+func defaultScope(ctx web.Context) string {
+	id, _ := tenant.FromContext(ctx.Context())
+	principal, ok := security.PrincipalFromContext[interface{}](ctx.Context())
+	if !ok {
+		return id
+	}
+	return strconv.Quote(id) + ":" + strconv.Quote(fmt.Sprint(principal))
+}
+
+// Middleware make the POST, PUT and PATCH requests with an Idempotency-Key header idempotent:
+// a repeated request replays the stored response, a request reusing a key with another payload is rejected with 422
+// and a request whose key is in progress with 409. The responses of 5xx are not stored, so that they are retryable.
+// The expired keys are kept until purged, see RunPurge
+func Middleware(store Store, opts ...Option) web.Middleware {
+	o := &options{ttl: DefaultTTL, scope: defaultScope, maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			key := ctx.Header(Header)
+			method := ctx.Request().Method
+			if key == "" || (method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch) {
+				next(ctx)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				ctx.JSON(http.StatusBadRequest, fmt.Sprintf("idempotency key is longer than %d", MaxKeyLength))
+				return
+			}
+			fingerprint, err := fingerprintOf(ctx.Request(), o.maxBodySize)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				ctx.JSON(http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, err.Error())
+				return
+			}
+			if scope := o.scope(ctx); scope != "" {
+				// the scope is hashed to keep the stored key within the size of Record.Key
+				sum := sha256.Sum256([]byte(scope))
+				key = hex.EncodeToString(sum[:]) + ":" + key
+			}
+			record, err := store.Reserve(ctx.Context(), key, fingerprint, o.ttl)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+			if record != nil {
+				replay(ctx, record, fingerprint)
+				return
+			}
+
+			rec := &recorder{webContext: ctx}
+			defer func() {
+				if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+					_ = store.Release(ctx.Context(), key)
+					return
+				}
+				_ = store.Complete(ctx.Context(), key, rec.status, rec.body)
+			}()
+			next(rec)
+		}
+	}
+}
+
+func replay(ctx web.Context, record *Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		ctx.JSON(http.StatusUnprocessableEntity, "idempotency key is reused with another request")
+	case record.InProgress():
+		ctx.JSON(http.StatusConflict, "request of the idempotency key is in progress")
+	default:
+		ctx.SetHeader(ReplayedHeader, "true")
+		if len(record.Body) == 0 {
+			ctx.Status(record.Status)
+			return
+		}
+		ctx.JSON(record.Status, json.RawMessage(record.Body))
+	}
+}
+
+// fingerprintOf hash the method, the url and the body of r up to max bytes, the body is restored for the handler
+func fingerprintOf(r *http.Request, max int64) (string, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, max))
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// webContext embedded by recorder, its method Context would collide with the field web.Context
+type webContext = web.Context
+
+// recorder Context recording the response written by the handler
+type recorder struct {
+	webContext
+	status int
+	body   []byte
+}
+
+func (r *recorder) JSON(code int, v interface{}) {
+	if !r.webContext.Written() {
+		if b, err := json.Marshal(v); err == nil {
+			r.status, r.body = code, b
+		} else {
+			r.status = http.StatusInternalServerError
+		}
+	}
+	r.webContext.JSON(code, v)
+}
+
+func (r *recorder) Status(code int) {
+	if !r.webContext.Written() {
+		r.status = code
+	}
+	r.webContext.Status(code)
+}
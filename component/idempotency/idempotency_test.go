@@ -0,0 +1,200 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testIdempotency struct {
+	suite.Suite
+	newStore func() Store
+	db       *gorm.DB
+	store    Store
+	handler  http.Handler
+	calls    int
+	fail     bool
+}
+
+func (t *_testIdempotency) SetupTest() {
+	t.store = t.newStore()
+	if gs, ok := t.store.(GormStore); ok {
+		t.db = gs.DB
+	}
+	t.calls, t.fail = 0, false
+	mux := http.NewServeMux()
+	principal := func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			if user := ctx.Header("X-User"); user != "" {
+				ctx.SetContext(security.WithPrincipal(ctx.Context(), user))
+			}
+			next(ctx)
+		}
+	}
+	router := web.WithMiddleware(web.ServeMux(mux, ""), principal, Middleware(t.store, WithMaxBodySize(1<<10)))
+	handler := func(ctx web.Context) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		t.calls++
+		if t.fail {
+			ctx.JSON(http.StatusInternalServerError, "failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]interface{}{"name": body.Name, "call": t.calls})
+	}
+	router.Handle(http.MethodPost, "/foos", handler)
+	router.Handle(http.MethodGet, "/foos", func(ctx web.Context) {
+		t.calls++
+		ctx.JSON(http.StatusOK, t.calls)
+	})
+	t.handler = mux
+}
+
+func (t *_testIdempotency) TearDownTest() {
+	if t.db != nil {
+		db, err := t.db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(db.Close())
+	}
+}
+
+func (t *_testIdempotency) do(method, key, body string) *httptest.ResponseRecorder {
+	return t.doAs("", method, key, body)
+}
+
+func (t *_testIdempotency) doAs(user, method, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/foos", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testIdempotency) Test_Replay() {
+	first := t.do(http.MethodPost, "k1", `{"name":"foo"}`)
+	t.EqualValues(http.StatusOK, first.Code)
+	second := t.do(http.MethodPost, "k1", `{"name":"foo"}`)
+	t.EqualValues(http.StatusOK, second.Code)
+	t.Equal("true", second.Header().Get(ReplayedHeader))
+	t.JSONEq(first.Body.String(), second.Body.String())
+	t.Equal(1, t.calls)
+
+	t.do(http.MethodPost, "k2", `{"name":"foo"}`)
+	t.Equal(2, t.calls)
+}
+
+func (t *_testIdempotency) Test_Mismatch() {
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "k1", `{"name":"foo"}`).Code)
+	t.EqualValues(http.StatusUnprocessableEntity, t.do(http.MethodPost, "k1", `{"name":"bar"}`).Code)
+	t.Equal(1, t.calls)
+}
+
+func (t *_testIdempotency) Test_InProgress() {
+	fingerprint, err := fingerprintOf(httptest.NewRequest(http.MethodPost, "/foos", strings.NewReader(`{"name":"foo"}`)), DefaultMaxBodySize)
+	t.Require().NoError(err)
+	record, err := t.store.Reserve(context.TODO(), "k1", fingerprint, DefaultTTL)
+	t.Require().NoError(err)
+	t.Nil(record)
+	t.EqualValues(http.StatusConflict, t.do(http.MethodPost, "k1", `{"name":"foo"}`).Code)
+	t.Equal(0, t.calls)
+}
+
+func (t *_testIdempotency) Test_PrincipalScope() {
+	first := t.doAs("alice", http.MethodPost, "k1", `{"name":"foo"}`)
+	t.EqualValues(http.StatusOK, first.Code)
+	second := t.doAs("bob", http.MethodPost, "k1", `{"name":"foo"}`)
+	t.EqualValues(http.StatusOK, second.Code)
+	t.Empty(second.Header().Get(ReplayedHeader), "the key of another user is not replayed")
+	t.Equal(2, t.calls)
+
+	t.Equal("true", t.doAs("alice", http.MethodPost, "k1", `{"name":"foo"}`).Header().Get(ReplayedHeader))
+	t.Equal(2, t.calls)
+}
+
+func (t *_testIdempotency) Test_BodyTooLarge() {
+	body := `{"name":"` + strings.Repeat("x", 1<<10) + `"}`
+	t.EqualValues(http.StatusRequestEntityTooLarge, t.do(http.MethodPost, "k1", body).Code)
+	t.Equal(0, t.calls)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "", body).Code, "the requests without a key are not capped")
+}
+
+func (t *_testIdempotency) Test_ServerErrorRetryable() {
+	t.fail = true
+	t.EqualValues(http.StatusInternalServerError, t.do(http.MethodPost, "k1", `{"name":"foo"}`).Code)
+	t.fail = false
+	w := t.do(http.MethodPost, "k1", `{"name":"foo"}`)
+	t.EqualValues(http.StatusOK, w.Code)
+	t.Empty(w.Header().Get(ReplayedHeader))
+	t.Equal(2, t.calls)
+}
+
+func (t *_testIdempotency) Test_Skipped() {
+	t.do(http.MethodPost, "", `{"name":"foo"}`)
+	t.do(http.MethodPost, "", `{"name":"foo"}`)
+	t.do(http.MethodGet, "k1", "")
+	t.do(http.MethodGet, "k1", "")
+	t.Equal(4, t.calls)
+}
+
+func (t *_testIdempotency) Test_KeyTooLong() {
+	t.EqualValues(http.StatusBadRequest, t.do(http.MethodPost, strings.Repeat("k", MaxKeyLength+1), `{"name":"foo"}`).Code)
+	t.Equal(0, t.calls)
+	t.EqualValues(http.StatusOK, t.doAs(strings.Repeat("u", 300), http.MethodPost, strings.Repeat("k", MaxKeyLength), `{"name":"foo"}`).Code)
+}
+
+func (t *_testIdempotency) Test_Purge() {
+	ctx := context.TODO()
+	now := time.Now()
+	_, err := t.store.Reserve(ctx, "short", "f", time.Minute)
+	t.Require().NoError(err)
+	_, err = t.store.Reserve(ctx, "long", "f", time.Hour)
+	t.Require().NoError(err)
+	n, err := t.store.Purge(ctx, now.Add(2*time.Minute))
+	t.NoError(err)
+	t.EqualValues(1, n)
+	record, err := t.store.Reserve(ctx, "long", "f", time.Hour)
+	t.NoError(err)
+	t.NotNil(record, "the unexpired record is kept")
+	record, err = t.store.Reserve(ctx, "short", "f", time.Hour)
+	t.NoError(err)
+	t.Nil(record)
+}
+
+func TestMemoryStore(t *testing.T) {
+	suite.Run(t, &_testIdempotency{newStore: func() Store {
+		return NewMemoryStore()
+	}})
+}
+
+func TestGormStore(t *testing.T) {
+	suite.Run(t, &_testIdempotency{newStore: func() Store {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		if err != nil {
+			panic(err)
+		}
+		if err := AutoMigrate(db); err != nil {
+			panic(err)
+		}
+		return GormStore{DB: db}
+	}})
+}
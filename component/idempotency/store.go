@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Record the request of an idempotency key and its response, Status is 0 while the request is in progress
+type Record struct {
+	Key         string `gorm:"primaryKey;size:255;column:idempotency_key"`
+	Fingerprint string `gorm:"size:64"`
+	Status      int
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+func (Record) TableName() string {
+	return "idempotency_records"
+}
+
+// InProgress whether the request of the record has not completed yet
+func (r *Record) InProgress() bool {
+	return r.Status == 0
+}
+
+// Store pluggable storage of the idempotency records
+type Store interface {
+	// Reserve reserve key for the request of fingerprint until ttl,
+	// the unexpired record of key is returned instead when it exists, nil means reserved
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	// Complete store the response of the reserved key
+	Complete(ctx context.Context, key string, status int, body []byte) error
+	// Release release the reserved key without response, so that the request is retryable
+	Release(ctx context.Context, key string) error
+	// Purge delete the records expired at now, the number of the records deleted is returned
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunPurge purge the expired records of store every interval until ctx is done, errors are reported to onError
+func RunPurge(ctx context.Context, store Store, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := store.Purge(ctx, time.Now()); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// MemoryStore in memory Store, e.g. for a single instance or tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record), now: time.Now}
+}
+
+func (s *MemoryStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if record, ok := s.records[key]; ok && now.Before(record.ExpiresAt) {
+		copied := *record
+		return &copied, nil
+	}
+	s.records[key] = &Record{Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(ttl), CreatedAt: now}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, status int, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	record.Status, record.Body = status, body
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, record := range s.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GormStore Store in the table of Record, see AutoMigrate
+type GormStore struct {
+	DB *gorm.DB
+}
+
+// AutoMigrate create the table of Record
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Record{})
+}
+
+func (s GormStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	db := s.DB.WithContext(ctx)
+	now := time.Now()
+	if err := db.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&Record{}).Error; err != nil {
+		return nil, err
+	}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Record{Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(ttl), CreatedAt: now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil, nil
+	}
+	var record Record
+	err := db.Where("idempotency_key = ?", key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// released between the insert and the lookup, let the client retry
+		return &Record{Key: key, Fingerprint: fingerprint}, nil
+	}
+	return &record, err
+}
+
+func (s GormStore) Complete(ctx context.Context, key string, status int, body []byte) error {
+	return s.DB.WithContext(ctx).Model(&Record{}).Where("idempotency_key = ?", key).
+		Updates(map[string]interface{}{"status": status, "body": body}).Error
+}
+
+func (s GormStore) Release(ctx context.Context, key string) error {
+	return s.DB.WithContext(ctx).Where("idempotency_key = ?", key).Delete(&Record{}).Error
+}
+
+func (s GormStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.DB.WithContext(ctx).Where("expires_at <= ?", now).Delete(&Record{})
+	return res.RowsAffected, res.Error
+}
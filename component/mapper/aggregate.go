@@ -0,0 +1,260 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AggregateFunc function of a Metric
+type AggregateFunc string
+
+const (
+	AggregateCount AggregateFunc = "count"
+	AggregateSum   AggregateFunc = "sum"
+	AggregateAvg   AggregateFunc = "avg"
+	AggregateMin   AggregateFunc = "min"
+	AggregateMax   AggregateFunc = "max"
+)
+
+// Metric aggregate of a column, the column of count is empty
+type Metric struct {
+	Func   AggregateFunc
+	Column string
+}
+
+func Count() Metric {
+	return Metric{Func: AggregateCount}
+}
+
+func Sum(column string) Metric {
+	return Metric{Func: AggregateSum, Column: column}
+}
+
+func Avg(column string) Metric {
+	return Metric{Func: AggregateAvg, Column: column}
+}
+
+func Min(column string) Metric {
+	return Metric{Func: AggregateMin, Column: column}
+}
+
+func Max(column string) Metric {
+	return Metric{Func: AggregateMax, Column: column}
+}
+
+// Name the key of the metric in AggregateRow.Metrics, e.g. count and sum(amount)
+func (m Metric) Name() string {
+	if m.Column == "" {
+		return string(m.Func)
+	}
+	return fmt.Sprintf("%s(%s)", m.Func, m.Column)
+}
+
+var metricPattern = regexp.MustCompile(`^(\w+)(?:\(\s*([\w*]*)\s*\))?$`)
+
+// ParseMetric parse a metric like count, count(*) or sum(amount), the function is case insensitive but the column is not
+func ParseMetric(s string) (Metric, error) {
+	match := metricPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Metric{}, fmt.Errorf("%w: %s", ErrInvalidField, s)
+	}
+	m := Metric{Func: AggregateFunc(strings.ToLower(match[1])), Column: match[2]}
+	switch m.Func {
+	case AggregateCount:
+		if m.Column == "*" {
+			m.Column = ""
+		}
+	case AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+		if m.Column == "" || m.Column == "*" {
+			return Metric{}, fmt.Errorf("%w: %s needs a column", ErrInvalidField, s)
+		}
+	default:
+		return Metric{}, fmt.Errorf("%w: unknown aggregate %s", ErrInvalidField, match[1])
+	}
+	return m, nil
+}
+
+// Aggregation aggregate query made by GroupBy, e.g. GroupBy("status").Aggregate(Count(), Sum("amount"))
+type Aggregation struct {
+	GroupBy []string
+	Metrics []Metric
+	// Limit at most Limit groups in the order of the group by columns, 0 means all. The groups merged from the shards
+	// are ordered by the Go comparison of their values rather than by the collation of the database, so that
+	// the first groups of the shards in their collation may not be the first ones merged, e.g. of a case
+	// insensitive collation of strings
+	Limit int
+}
+
+// GroupBy group by the columns, no column aggregates all the entities in a single row
+func GroupBy(columns ...string) Aggregation {
+	return Aggregation{GroupBy: columns}
+}
+
+// Aggregate add the metrics
+func (a Aggregation) Aggregate(metrics ...Metric) Aggregation {
+	a.Metrics = append(append([]Metric(nil), a.Metrics...), metrics...)
+	return a
+}
+
+// WithLimit keep at most n groups, see Aggregation.Limit
+func (a Aggregation) WithLimit(n int) Aggregation {
+	a.Limit = n
+	return a
+}
+
+// AggregateRow a group of an Aggregation, Group is keyed by the group by columns and Metrics by Metric.Name
+type AggregateRow struct {
+	Group   map[string]interface{} `json:"group"`
+	Metrics map[string]float64     `json:"metrics"`
+}
+
+// build the aggregate query of a against the schema s
+func (a Aggregation) build(db *gorm.DB, s *schema.Schema) (*gorm.DB, error) {
+	if len(a.Metrics) == 0 {
+		return nil, fmt.Errorf("%w: no metric", ErrInvalidField)
+	}
+	selects := make([]string, 0, len(a.GroupBy)+len(a.Metrics))
+	groups := make([]string, 0, len(a.GroupBy))
+	for i, column := range a.GroupBy {
+		field := lookupField(s, column)
+		if field == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidField, column)
+		}
+		quoted := db.Statement.Quote(clause.Column{Name: field.DBName})
+		selects = append(selects, fmt.Sprintf("%s AS g%d", quoted, i))
+		groups = append(groups, field.DBName)
+	}
+	for i, metric := range a.Metrics {
+		expr := "*"
+		if metric.Column != "" {
+			field := lookupField(s, metric.Column)
+			if field == nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidField, metric.Column)
+			}
+			if metric.Func != AggregateCount && !numeric(field) {
+				return nil, fmt.Errorf("%w: %s of non numeric %s", ErrInvalidField, metric.Func, metric.Column)
+			}
+			expr = db.Statement.Quote(clause.Column{Name: field.DBName})
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s) AS m%d", strings.ToUpper(string(metric.Func)), expr, i))
+	}
+	db = db.Select(strings.Join(selects, ", "))
+	// the groups are in the order of their columns like the groups merged from the shards, an order of wrapper
+	// would come before them and pick the limited groups by another order
+	delete(db.Statement.Clauses, "ORDER BY")
+	for _, group := range groups {
+		db = db.Group(group).Order(clause.OrderByColumn{Column: clause.Column{Name: group}})
+	}
+	if a.Limit > 0 && len(groups) > 0 {
+		db = db.Limit(a.Limit)
+	}
+	return db, nil
+}
+
+func numeric(field *schema.Field) bool {
+	switch field.DataType {
+	case schema.Int, schema.Uint, schema.Float:
+		return true
+	}
+	return false
+}
+
+func (m baseMapper[T]) Aggregate(ctx context.Context, aggregation Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]AggregateRow, error) {
+	if m.fanOut(ctx) {
+		return m.aggregateShards(ctx, aggregation, wrapper)
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	var t T
+	db, err := aggregation.build(wrapper(m.conn(ctx).Model(&t)), s)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]map[string]interface{}, 0)
+	if err := db.Find(&values).Error; err != nil {
+		return nil, err
+	}
+	res := make([]AggregateRow, 0, len(values))
+	for _, value := range values {
+		row := AggregateRow{
+			Group:   make(map[string]interface{}, len(aggregation.GroupBy)),
+			Metrics: make(map[string]float64, len(aggregation.Metrics)),
+		}
+		for i, column := range aggregation.GroupBy {
+			v := value[fmt.Sprintf("g%d", i)]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			row.Group[column] = v
+		}
+		for i, metric := range aggregation.Metrics {
+			f, err := toFloat(value[fmt.Sprintf("m%d", i)])
+			if err != nil {
+				return nil, err
+			}
+			row.Metrics[metric.Name()] = f
+		}
+		res = append(res, row)
+	}
+	return res, nil
+}
+
+// metric aggregate a single metric of all the entities
+func (m baseMapper[T]) metric(ctx context.Context, metric Metric, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	rows, err := m.Aggregate(ctx, GroupBy().Aggregate(metric), wrapper)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Metrics[metric.Name()], nil
+}
+
+func (m baseMapper[T]) Sum(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return m.metric(ctx, Sum(column), wrapper)
+}
+
+func (m baseMapper[T]) Avg(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return m.metric(ctx, Avg(column), wrapper)
+}
+
+func (m baseMapper[T]) Min(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return m.metric(ctx, Min(column), wrapper)
+}
+
+func (m baseMapper[T]) Max(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return m.metric(ctx, Max(column), wrapper)
+}
+
+// toFloat the aggregate value of the driver, null is 0
+func toFloat(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case int32:
+		return float64(val), nil
+	case int:
+		return float64(val), nil
+	case uint64:
+		return float64(val), nil
+	case []byte:
+		return strconv.ParseFloat(string(val), 64)
+	case string:
+		return strconv.ParseFloat(val, 64)
+	default:
+		return 0, fmt.Errorf("unsupported aggregate value %T", v)
+	}
+}
@@ -0,0 +1,117 @@
+package mapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testOrder struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Status string `json:"status"`
+	Amount int    `json:"amount"`
+	Note   string `json:"note"`
+}
+
+type _testAggregate struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper BaseMapper[_testOrder]
+}
+
+func (t *_testAggregate) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testOrder{}))
+	t.mapper = NewBaseMapper[_testOrder](t.db)
+	for _, order := range []_testOrder{
+		{Status: "paid", Amount: 10},
+		{Status: "paid", Amount: 30},
+		{Status: "open", Amount: 5},
+	} {
+		t.Require().NoError(t.db.Create(&order).Error)
+	}
+}
+
+func (t *_testAggregate) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testAggregate) Test_ParseMetric() {
+	for s, expected := range map[string]Metric{
+		"count":            Count(),
+		"count(*)":         Count(),
+		"sum(amount)":      Sum("amount"),
+		"MAX( amount)":     Max("amount"),
+		"SUM(totalAmount)": Sum("totalAmount"),
+	} {
+		m, err := ParseMetric(s)
+		t.NoError(err, s)
+		t.Equal(expected, m, s)
+	}
+	for _, s := range []string{"sum", "sum(*)", "median(amount)", "sum(amount);drop", ""} {
+		_, err := ParseMetric(s)
+		t.ErrorIs(err, ErrInvalidField, s)
+	}
+}
+
+func (t *_testAggregate) Test_GroupBy() {
+	rows, err := t.mapper.Aggregate(context.TODO(), GroupBy("status").Aggregate(Count(), Sum("amount"), Avg("amount")), EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Equal([]AggregateRow{
+		{Group: map[string]interface{}{"status": "open"}, Metrics: map[string]float64{"count": 1, "sum(amount)": 5, "avg(amount)": 5}},
+		{Group: map[string]interface{}{"status": "paid"}, Metrics: map[string]float64{"count": 2, "sum(amount)": 40, "avg(amount)": 20}},
+	}, rows)
+}
+
+func (t *_testAggregate) Test_Limit() {
+	rows, err := t.mapper.Aggregate(context.TODO(), GroupBy("status").Aggregate(Count()).WithLimit(1), EmptyWrapperFunc)
+	t.Require().NoError(err)
+	t.Equal([]AggregateRow{{Group: map[string]interface{}{"status": "open"}, Metrics: map[string]float64{"count": 1}}}, rows)
+
+	rows, err = t.mapper.Aggregate(context.TODO(), GroupBy("status").Aggregate(Count()).WithLimit(1), func(db *gorm.DB) *gorm.DB {
+		return db.Order("status desc")
+	})
+	t.Require().NoError(err)
+	t.Equal([]AggregateRow{{Group: map[string]interface{}{"status": "open"}, Metrics: map[string]float64{"count": 1}}}, rows, "in the order of the groups")
+}
+
+func (t *_testAggregate) Test_Metrics() {
+	ctx := context.TODO()
+	paid := func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", "paid")
+	}
+	sum, err := t.mapper.Sum(ctx, "amount", paid)
+	t.NoError(err)
+	t.EqualValues(40, sum)
+	min, err := t.mapper.Min(ctx, "amount", EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(5, min)
+	max, err := t.mapper.Max(ctx, "amount", EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(30, max)
+	avg, err := t.mapper.Avg(ctx, "amount", paid)
+	t.NoError(err)
+	t.EqualValues(20, avg)
+}
+
+func (t *_testAggregate) Test_Invalid() {
+	ctx := context.TODO()
+	_, err := t.mapper.Aggregate(ctx, GroupBy("missing").Aggregate(Count()), EmptyWrapperFunc)
+	t.ErrorIs(err, ErrInvalidField)
+	_, err = t.mapper.Aggregate(ctx, GroupBy("status"), EmptyWrapperFunc)
+	t.ErrorIs(err, ErrInvalidField)
+	_, err = t.mapper.Sum(ctx, "note", EmptyWrapperFunc)
+	t.ErrorIs(err, ErrInvalidField)
+}
+
+func TestAggregate(t *testing.T) {
+	suite.Run(t, &_testAggregate{})
+}
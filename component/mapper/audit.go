@@ -0,0 +1,143 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/audit"
+	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/revision"
+	"github.com/go-gosh/gestful/component/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// WithAuditor populate CreatedBy, UpdatedBy, CreatedAt and UpdatedAt of the entity on create and update
+func WithAuditor(auditor audit.AuditorAware) Option {
+	return func(o *options) {
+		o.auditor = auditor
+	}
+}
+
+// WithClock replace time.Now as the clock of the audit fields, the audit logs and the revisions
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithAuditLog record an audit log of every create, update and delete in the transaction of the write
+func WithAuditLog(recorder audit.Recorder) Option {
+	return func(o *options) {
+		o.recorder = recorder
+	}
+}
+
+func (m baseMapper[T]) touch(ctx context.Context, entity *T) error {
+	if m.auditor == nil {
+		return nil
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return err
+	}
+	return audit.Touch(ctx, s, reflect.ValueOf(entity), m.auditor, m.now(), true)
+}
+
+func (m baseMapper[T]) touchChanges(ctx context.Context, updated map[string]interface{}) error {
+	if m.auditor == nil {
+		return nil
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return err
+	}
+	audit.TouchChanges(ctx, s, updated, m.auditor, m.now())
+	return nil
+}
+
+// byPrimaryKey wrapper of the entity by its primary key
+func (m baseMapper[T]) byPrimaryKey(ctx context.Context, entity *T) (func(*gorm.DB) *gorm.DB, error) {
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("no primary key in %s", s.Name)
+	}
+	field := s.PrioritizedPrimaryField
+	value, _ := field.ValueOf(ctx, reflect.ValueOf(entity))
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+	}, nil
+}
+
+// WithRevisions write a revision to the history table of the entity on every write, see revision.AutoMigrate
+func WithRevisions() Option {
+	return func(o *options) {
+		o.revisions = true
+	}
+}
+
+// recording whether the writes are recorded by the audit log or the revisions
+func (m baseMapper[T]) recording() bool {
+	return m.recorder != nil || m.revisions
+}
+
+// record the audit log and the revision of an entity changed from before to after
+func (m baseMapper[T]) record(ctx context.Context, op audit.Operation, before, after *T) error {
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return err
+	}
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	var id interface{}
+	if s.PrioritizedPrimaryField != nil {
+		id, _ = s.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(entity))
+	}
+	root, err := m.root(ctx)
+	if err != nil {
+		return err
+	}
+	tx := conn(ctx, root)
+	now := m.now()
+	if m.recorder != nil {
+		log, err := audit.NewLog(ctx, s.Name, fmt.Sprint(id), op, m.auditor, now, before, after)
+		if err != nil {
+			return err
+		}
+		if err := m.recorder.Record(ctx, tx, log); err != nil {
+			return err
+		}
+	}
+	if m.revisions {
+		var actor string
+		if m.auditor != nil {
+			actor, _ = m.auditor.CurrentAuditor(ctx)
+		}
+		table, err := m.historyTable(ctx, s)
+		if err != nil {
+			return err
+		}
+		return revision.Write(ctx, tx, table, domain.RevisionType(op), fmt.Sprint(id), actor, now, entity)
+	}
+	return nil
+}
+
+// historyTable the history table of the entity in the tenant of ctx, see revision.TableName
+func (m baseMapper[T]) historyTable(ctx context.Context, s *schema.Schema) (string, error) {
+	if m.tenant == nil {
+		return revision.TableName(s), nil
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", tenant.ErrTenantRequired
+	}
+	return m.tenant.Table(revision.TableName(s), id)
+}
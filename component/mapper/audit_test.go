@@ -0,0 +1,154 @@
+package mapper
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/audit"
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testAuditFoo struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	Name      string
+	CreatedBy string
+	UpdatedBy string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type _testAudit struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper BaseMapper[_testAuditFoo]
+}
+
+func (t *_testAudit) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testAuditFoo{}, &audit.Log{}))
+	t.mapper = NewBaseMapper[_testAuditFoo](t.db,
+		WithAuditor(audit.PrincipalAuditor(func(name string) string { return name })),
+		WithAuditLog(audit.TableRecorder{}))
+}
+
+func (t *_testAudit) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testAudit) Test_AuditFields() {
+	foo := _testAuditFoo{Name: "foo"}
+	t.NoError(t.mapper.Create(security.WithPrincipal(context.TODO(), "alice"), &foo))
+	t.NoError(t.mapper.UpdateById(security.WithPrincipal(context.TODO(), "bob"), foo.ID, map[string]interface{}{"name": "bar"}))
+	res, err := t.mapper.OneById(context.TODO(), foo.ID)
+	t.NoError(err)
+	t.EqualValues("alice", res.CreatedBy)
+	t.EqualValues("bob", res.UpdatedBy)
+	t.False(res.CreatedAt.IsZero())
+	t.False(res.UpdatedAt.Before(res.CreatedAt))
+}
+
+func (t *_testAudit) Test_Clock() {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := at
+	m := NewBaseMapper[_testAuditFoo](t.db,
+		WithAuditor(audit.PrincipalAuditor(func(name string) string { return name })),
+		WithAuditLog(audit.TableRecorder{}),
+		WithClock(func() time.Time { return now }))
+	foo := _testAuditFoo{Name: "foo"}
+	t.NoError(m.Create(context.TODO(), &foo))
+	now = at.Add(time.Hour)
+	t.NoError(m.UpdateById(context.TODO(), foo.ID, map[string]interface{}{"name": "bar"}))
+	res, err := m.OneById(context.TODO(), foo.ID)
+	t.NoError(err)
+	t.True(at.Equal(res.CreatedAt))
+	t.True(now.Equal(res.UpdatedAt))
+	var logs []audit.Log
+	t.NoError(t.db.Order("id").Find(&logs).Error)
+	t.Require().Len(logs, 2)
+	t.True(at.Equal(logs[0].CreatedAt))
+	t.True(now.Equal(logs[1].CreatedAt))
+}
+
+func (t *_testAudit) Test_AuditFields_Forged() {
+	foo := _testAuditFoo{Name: "foo", CreatedBy: "mallory", UpdatedBy: "mallory"}
+	t.NoError(t.mapper.Create(context.TODO(), &foo))
+	res, err := t.mapper.OneById(context.TODO(), foo.ID)
+	t.NoError(err)
+	t.Empty(res.CreatedBy, "no principal")
+	t.Empty(res.UpdatedBy)
+
+	t.NoError(t.mapper.UpdateById(security.WithPrincipal(context.TODO(), "bob"), foo.ID, map[string]interface{}{
+		"name": "bar", "created_by": "mallory", "CreatedAt": time.Time{}, "updated_by": "mallory"}))
+	res, err = t.mapper.OneById(context.TODO(), foo.ID)
+	t.NoError(err)
+	t.EqualValues("bar", res.Name)
+	t.Empty(res.CreatedBy)
+	t.EqualValues("bob", res.UpdatedBy)
+	t.False(res.CreatedAt.IsZero())
+}
+
+func (t *_testAudit) Test_AuditLog() {
+	ctx := security.WithPrincipal(context.TODO(), "alice")
+	foo := _testAuditFoo{Name: "foo"}
+	t.NoError(t.mapper.Create(ctx, &foo))
+	t.NoError(t.mapper.UpdateById(ctx, foo.ID, map[string]interface{}{"name": "bar"}))
+	t.NoError(t.mapper.DeleteById(ctx, foo.ID))
+
+	var logs []audit.Log
+	t.NoError(t.db.Order("id").Find(&logs).Error)
+	t.Len(logs, 3)
+	for i, op := range []audit.Operation{audit.OperationCreate, audit.OperationUpdate, audit.OperationDelete} {
+		t.EqualValues(op, logs[i].Operation)
+		t.EqualValues("alice", logs[i].Actor)
+		t.EqualValues("_testAuditFoo", logs[i].EntityType)
+		t.EqualValues("1", logs[i].EntityID)
+	}
+	t.Empty(logs[0].Before)
+	t.Empty(logs[2].After)
+	var diff map[string]audit.Change
+	t.NoError(json.Unmarshal([]byte(logs[1].Diff), &diff))
+	t.EqualValues(audit.Change{Before: "foo", After: "bar"}, diff["Name"])
+	t.NotContains(diff, "CreatedBy")
+}
+
+func (t *_testAudit) Test_AuditLog_Rollback() {
+	err := t.mapper.Transaction(context.TODO(), func(ctx context.Context) error {
+		t.NoError(t.mapper.Create(ctx, &_testAuditFoo{}))
+		return gorm.ErrInvalidData
+	})
+	t.ErrorIs(err, gorm.ErrInvalidData)
+	var count int64
+	t.NoError(t.db.Model(&audit.Log{}).Count(&count).Error)
+	t.EqualValues(0, count)
+}
+
+func (t *_testAudit) Test_AuditLog_OtherTransaction() {
+	other, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(other.AutoMigrate(&audit.Log{}))
+	err = t.mapper.Transaction(context.TODO(), func(ctx context.Context) error {
+		return Transaction(ctx, other, func(ctx context.Context) error {
+			return t.mapper.Create(ctx, &_testAuditFoo{Name: "foo"})
+		})
+	})
+	t.NoError(err)
+	var count int64
+	t.NoError(t.db.Model(&audit.Log{}).Count(&count).Error)
+	t.EqualValues(1, count)
+	t.NoError(other.Model(&audit.Log{}).Count(&count).Error)
+	t.EqualValues(0, count)
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, &_testAudit{})
+}
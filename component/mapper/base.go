@@ -2,7 +2,9 @@ package mapper
 
 import (
 	"context"
+	"time"
 
+	"github.com/go-gosh/gestful/component/audit"
 	"gorm.io/gorm"
 )
 
@@ -22,7 +24,17 @@ type BaseMapper[T any] interface {
 }
 
 type baseMapper[T any] struct {
-	db *gorm.DB
+	db     *gorm.DB
+	search SearchProvider
+	tenant TenantStrategy
+
+	auditor   audit.AuditorAware
+	recorder  audit.Recorder
+	revisions bool
+	now       func() time.Time
+
+	shardKey string
+	sharding ShardingStrategy
 }
 
 func WrapperFuncById(id uint) func(db *gorm.DB) *gorm.DB {
@@ -35,9 +47,25 @@ func EmptyWrapperFunc(db *gorm.DB) *gorm.DB {
 	return db
 }
 
+// ChainWrapperFunc apply wrappers in order
+func ChainWrapperFunc(wrappers ...func(*gorm.DB) *gorm.DB) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, wrapper := range wrappers {
+			db = wrapper(db)
+		}
+		return db
+	}
+}
+
 // NewBaseMapper base mapper
-func NewBaseMapper[T any](db *gorm.DB) BaseMapper[T] {
-	return &baseMapper[T]{db: db}
+func NewBaseMapper[T any](db *gorm.DB, opts ...Option) BaseMapper[T] {
+	return newBaseMapper[T](db, opts...)
+}
+
+func newBaseMapper[T any](db *gorm.DB, opts ...Option) *baseMapper[T] {
+	o := newOptions(opts...)
+	return &baseMapper[T]{db: db, search: o.search, tenant: o.tenant, auditor: o.auditor, recorder: o.recorder, revisions: o.revisions,
+		now: o.now, shardKey: o.shardKey, sharding: o.sharding}
 }
 
 func (m baseMapper[T]) OneById(ctx context.Context, id uint) (*T, error) {
@@ -53,22 +81,31 @@ func (m baseMapper[T]) UpdateById(ctx context.Context, id uint, updated map[stri
 }
 
 func (m baseMapper[T]) One(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*T, error) {
+	if m.fanOut(ctx) {
+		return m.oneShards(ctx, wrapper)
+	}
 	var res T
-	err := wrapper(m.db.WithContext(ctx)).
+	err := wrapper(m.conn(ctx)).
 		First(&res).Error
 	return &res, err
 }
 
 func (m baseMapper[T]) All(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, error) {
+	if m.fanOut(ctx) {
+		return m.allShards(ctx, wrapper)
+	}
 	res := make([]T, 0)
-	err := wrapper(m.db.WithContext(ctx)).
+	err := wrapper(m.conn(ctx)).
 		Find(&res).Error
 	return res, err
 }
 
 func (m baseMapper[T]) Paginate(ctx context.Context, pager Paginator, wrapper func(*gorm.DB) *gorm.DB) (*PageRes[T], error) {
+	if m.fanOut(ctx) {
+		return m.paginateShards(ctx, pager, wrapper)
+	}
 	res := make([]T, 0, pager.Limit+1)
-	db := wrapper(m.db.WithContext(ctx))
+	db := wrapper(m.conn(ctx))
 	if pager.StartId > 0 {
 		db = db.
 			Where("id>?", pager.StartId)
@@ -91,9 +128,63 @@ func (m baseMapper[T]) Paginate(ctx context.Context, pager Paginator, wrapper fu
 	}, nil
 }
 
+func (m baseMapper[T]) Search(q string) func(*gorm.DB) *gorm.DB {
+	if q == "" {
+		return EmptyWrapperFunc
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return func(db *gorm.DB) *gorm.DB {
+			_ = db.AddError(err)
+			return db
+		}
+	}
+	columns := SearchColumns(s)
+	if len(columns) == 0 {
+		return EmptyWrapperFunc
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return m.search.Search(db, columns, q)
+	}
+}
+
+func (m baseMapper[T]) Fields(fields ...string) (*FieldSet, error) {
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFieldSet(s, fields...)
+}
+
 func (m baseMapper[T]) Delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) error {
+	if m.fanOut(ctx) {
+		return m.writeShards(ctx, func(ctx context.Context) error {
+			return m.Delete(ctx, wrapper)
+		})
+	}
+	if !m.recording() {
+		return m.delete(ctx, wrapper)
+	}
+	return m.Transaction(ctx, func(ctx context.Context) error {
+		before, err := m.All(ctx, wrapper)
+		if err != nil {
+			return err
+		}
+		if err := m.delete(ctx, wrapper); err != nil {
+			return err
+		}
+		for i := range before {
+			if err := m.record(ctx, audit.OperationDelete, &before[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m baseMapper[T]) delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) error {
 	var t T
-	d := wrapper(m.db.WithContext(ctx)).Delete(&t)
+	d := wrapper(m.conn(ctx)).Delete(&t)
 	if d.Error != nil {
 		return d.Error
 	}
@@ -103,13 +194,86 @@ func (m baseMapper[T]) Delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.
 	return nil
 }
 
+func (m baseMapper[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.fanOut(ctx) {
+		return m.lazyTransaction(ctx, fn)
+	}
+	db, err := m.root(ctx)
+	if err != nil {
+		return err
+	}
+	return Transaction(ctx, db, fn)
+}
+
 func (m baseMapper[T]) Create(ctx context.Context, entity *T) error {
-	return m.db.WithContext(ctx).Create(entity).Error
+	if err := m.assign(ctx, entity); err != nil {
+		return err
+	}
+	if err := m.touch(ctx, entity); err != nil {
+		return err
+	}
+	ctx, err := m.route(ctx, entity)
+	if err != nil {
+		return err
+	}
+	if !m.recording() {
+		return m.conn(ctx).Create(entity).Error
+	}
+	return m.Transaction(ctx, func(ctx context.Context) error {
+		if err := m.conn(ctx).Create(entity).Error; err != nil {
+			return err
+		}
+		return m.record(ctx, audit.OperationCreate, nil, entity)
+	})
 }
 
 func (m baseMapper[T]) Update(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB, updated map[string]interface{}) error {
+	if m.fanOut(ctx) {
+		return m.writeShards(ctx, func(ctx context.Context) error {
+			changes := make(map[string]interface{}, len(updated))
+			for k, v := range updated {
+				changes[k] = v
+			}
+			return m.Update(ctx, wrapper, changes)
+		})
+	}
+	if err := m.protect(updated); err != nil {
+		return err
+	}
+	if err := m.touchChanges(ctx, updated); err != nil {
+		return err
+	}
+	if !m.recording() {
+		return m.update(ctx, wrapper, updated)
+	}
+	return m.Transaction(ctx, func(ctx context.Context) error {
+		before, err := m.All(ctx, wrapper)
+		if err != nil {
+			return err
+		}
+		if err := m.update(ctx, wrapper, updated); err != nil {
+			return err
+		}
+		for i := range before {
+			byPk, err := m.byPrimaryKey(ctx, &before[i])
+			if err != nil {
+				return err
+			}
+			after, err := m.One(ctx, byPk)
+			if err != nil {
+				return err
+			}
+			if err := m.record(ctx, audit.OperationUpdate, &before[i], after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m baseMapper[T]) update(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB, updated map[string]interface{}) error {
 	var t T
-	updates := wrapper(m.db.WithContext(ctx).Model(&t)).Updates(updated)
+	updates := wrapper(m.conn(ctx).Model(&t)).Updates(updated)
 	if updates.Error != nil {
 		return updates.Error
 	}
@@ -120,8 +284,11 @@ func (m baseMapper[T]) Update(ctx context.Context, wrapper func(*gorm.DB) *gorm.
 }
 
 func (m baseMapper[T]) Count(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (int, error) {
+	if m.fanOut(ctx) {
+		return m.countShards(ctx, wrapper)
+	}
 	var c int64
 	var t T
-	err := wrapper(m.db.WithContext(ctx).Model(&t)).Count(&c).Error
+	err := wrapper(m.conn(ctx).Model(&t)).Count(&c).Error
 	return int(c), err
 }
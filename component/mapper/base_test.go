@@ -197,6 +197,72 @@ func (t *_testMapper) Test_Paginate_SecondPageWhenMoreData() {
 	}
 }
 
+func (t *_testMapper) Test_Transaction_Rollback() {
+	ctx := context.TODO()
+	err := t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		t.NoError(t.mapper.Create(ctx, &_testFoo{}))
+		return gorm.ErrInvalidData
+	})
+	t.ErrorIs(err, gorm.ErrInvalidData)
+	res, err := t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(0, res)
+}
+
+func (t *_testMapper) Test_Transaction_Commit() {
+	ctx := context.TODO()
+	err := t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		return t.mapper.Create(ctx, &_testFoo{})
+	})
+	t.NoError(err)
+	res, err := t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, res)
+}
+
+func (t *_testMapper) Test_Transaction_OtherDatabase() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	db = db.Debug()
+	defer func() {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}()
+	t.Require().NoError(db.AutoMigrate(&_testFoo{}))
+	other := NewBaseMapper[_testFoo](db)
+
+	ctx := context.TODO()
+	err = t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		t.NoError(other.Create(ctx, &_testFoo{}), "the mapper of another db does not join the transaction")
+		return other.Transaction(ctx, func(inner context.Context) error {
+			t.NoError(t.mapper.Create(inner, &_testFoo{}))
+			t.NoError(other.Create(inner, &_testFoo{}))
+			return nil
+		})
+	})
+	t.NoError(err)
+	res, err := other.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(2, res)
+	res, err = t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, res)
+
+	err = t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		t.NoError(t.mapper.Create(ctx, &_testFoo{}))
+		t.NoError(other.Create(ctx, &_testFoo{}))
+		return gorm.ErrInvalidData
+	})
+	t.ErrorIs(err, gorm.ErrInvalidData)
+	res, err = t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, res, "the transaction of the mapper is rolled back")
+	res, err = other.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(3, res, "the write of the other db is not")
+}
+
 func (t *_testMapper) addData(num int) []_testFoo {
 	res := make([]_testFoo, 0, num)
 	for i := 0; i < num; i++ {
@@ -0,0 +1,93 @@
+package mapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRolledBack the item of a batch is rolled back by the failure of another item
+var ErrRolledBack = errors.New("rolled back")
+
+// BatchMode how a batch handles the failure of an item
+type BatchMode int
+
+const (
+	// BatchAtomic all or nothing, the first failure rolls back the whole batch
+	BatchAtomic BatchMode = iota
+	// BatchPartial only the failed items are rolled back, the others are committed
+	BatchPartial
+)
+
+// BatchResult result of the item Index of a batch, Err is nil when it succeeded
+type BatchResult struct {
+	Index int
+	Err   error
+}
+
+// BatchUpdate update of the entity ID in a batch
+type BatchUpdate struct {
+	ID      uint
+	Updated map[string]interface{}
+}
+
+// Batch run fn for the n items of a batch in a single transaction, each item runs in a savepoint in BatchPartial mode.
+// In BatchAtomic mode the error of the failed item is returned and the other items are ErrRolledBack
+func Batch(ctx context.Context, transaction func(ctx context.Context, fn func(ctx context.Context) error) error,
+	n int, mode BatchMode, fn func(ctx context.Context, i int) error) ([]BatchResult, error) {
+	res := make([]BatchResult, n)
+	err := transaction(ctx, func(ctx context.Context) error {
+		for i := range res {
+			res[i] = BatchResult{Index: i}
+			if mode == BatchAtomic {
+				if err := fn(ctx, i); err != nil {
+					res[i].Err = err
+					return err
+				}
+				continue
+			}
+			tx, ok := TxFromContext(ctx)
+			if !ok {
+				return ErrShardKeyRequired
+			}
+			savepoint := fmt.Sprintf("batch_%d", i)
+			if err := tx.WithContext(ctx).SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			if err := fn(ctx, i); err != nil {
+				res[i].Err = err
+				if err := tx.WithContext(ctx).RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return res, nil
+	}
+	for i := range res {
+		if res[i].Err == nil {
+			res[i] = BatchResult{Index: i, Err: ErrRolledBack}
+		}
+	}
+	return res, err
+}
+
+func (m baseMapper[T]) CreateBatch(ctx context.Context, entities []*T, mode BatchMode) ([]BatchResult, error) {
+	return Batch(ctx, m.Transaction, len(entities), mode, func(ctx context.Context, i int) error {
+		return m.Create(ctx, entities[i])
+	})
+}
+
+func (m baseMapper[T]) UpdateBatch(ctx context.Context, updates []BatchUpdate, mode BatchMode) ([]BatchResult, error) {
+	return Batch(ctx, m.Transaction, len(updates), mode, func(ctx context.Context, i int) error {
+		return m.UpdateById(ctx, updates[i].ID, updates[i].Updated)
+	})
+}
+
+func (m baseMapper[T]) DeleteBatch(ctx context.Context, ids []uint, mode BatchMode) ([]BatchResult, error) {
+	return Batch(ctx, m.Transaction, len(ids), mode, func(ctx context.Context, i int) error {
+		return m.DeleteById(ctx, ids[i])
+	})
+}
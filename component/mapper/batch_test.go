@@ -0,0 +1,50 @@
+package mapper
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+func (t *_testMapper) Test_CreateBatch_Atomic() {
+	ctx := context.TODO()
+	t.addData(1)
+	res, err := t.mapper.CreateBatch(ctx, []*_testFoo{{ID: 2}, {ID: 1}, {ID: 3}}, BatchAtomic)
+	t.Error(err)
+	t.Require().Len(res, 3)
+	t.ErrorIs(res[0].Err, ErrRolledBack)
+	t.Error(res[1].Err)
+	t.False(errors.Is(res[1].Err, ErrRolledBack))
+	t.ErrorIs(res[2].Err, ErrRolledBack)
+	count, err := t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal(1, count)
+}
+
+func (t *_testMapper) Test_CreateBatch_Partial() {
+	ctx := context.TODO()
+	t.addData(1)
+	res, err := t.mapper.CreateBatch(ctx, []*_testFoo{{ID: 2}, {ID: 1}, {ID: 3}}, BatchPartial)
+	t.NoError(err)
+	t.Require().Len(res, 3)
+	t.NoError(res[0].Err)
+	t.Error(res[1].Err)
+	t.NoError(res[2].Err)
+	count, err := t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal(3, count)
+}
+
+func (t *_testMapper) Test_DeleteBatch_Partial() {
+	ctx := context.TODO()
+	t.addData(3)
+	res, err := t.mapper.DeleteBatch(ctx, []uint{1, 10, 3}, BatchPartial)
+	t.NoError(err)
+	t.NoError(res[0].Err)
+	t.ErrorIs(res[1].Err, gorm.ErrRecordNotFound)
+	t.NoError(res[2].Err)
+	all, err := t.mapper.All(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal([]_testFoo{{ID: 2}}, all)
+}
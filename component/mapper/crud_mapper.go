@@ -22,16 +22,16 @@ type CRUDMapper[Model any] interface {
 	IMapper[Model, CRUDPaginator, CRUDPageResult[Model]]
 }
 
-func NewCRUDMapper[Model any](db *gorm.DB) CRUDMapper[Model] {
+func NewCRUDMapper[Model any](db *gorm.DB, opts ...Option) CRUDMapper[Model] {
 	return &crudMapper[Model]{
 		db:     db,
-		mapper: NewBaseMapper[Model](db),
+		mapper: newBaseMapper[Model](db, opts...),
 	}
 }
 
 type crudMapper[Model any] struct {
 	db     *gorm.DB
-	mapper BaseMapper[Model]
+	mapper *baseMapper[Model]
 }
 
 func (c *crudMapper[Model]) One(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*Model, error) {
@@ -79,9 +79,11 @@ func (c *crudMapper[Model]) Paginate(ctx context.Context, pager CRUDPaginator, w
 	if total == 0 {
 		return &res, nil
 	}
-	db := wrapper(c.db.WithContext(ctx))
+	// the page is loaded by All, so that it is merged from every shard like Count without a shard key
 	offset := int((pager.Page - 1) * pager.PageSize)
-	err = db.Offset(offset).Limit(int(pager.PageSize)).Find(&data).Error
+	data, err = c.All(ctx, func(db *gorm.DB) *gorm.DB {
+		return wrapper(db).Offset(offset).Limit(int(pager.PageSize))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +101,34 @@ func (c *crudMapper[Model]) Count(ctx context.Context, wrapper func(*gorm.DB) *g
 	return c.mapper.Count(ctx, wrapper)
 }
 
+func (c *crudMapper[Model]) Search(q string) func(*gorm.DB) *gorm.DB {
+	return SearchWrapperFunc(c.mapper, q)
+}
+
+func (c *crudMapper[Model]) Fields(fields ...string) (*FieldSet, error) {
+	return c.mapper.Fields(fields...)
+}
+
+func (c *crudMapper[Model]) Aggregate(ctx context.Context, aggregation Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]AggregateRow, error) {
+	return c.mapper.Aggregate(ctx, aggregation, wrapper)
+}
+
+func (c *crudMapper[Model]) Sum(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.mapper.Sum(ctx, column, wrapper)
+}
+
+func (c *crudMapper[Model]) Avg(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.mapper.Avg(ctx, column, wrapper)
+}
+
+func (c *crudMapper[Model]) Min(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.mapper.Min(ctx, column, wrapper)
+}
+
+func (c *crudMapper[Model]) Max(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return c.mapper.Max(ctx, column, wrapper)
+}
+
 func (c *crudMapper[Model]) Delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) error {
 
 	return c.mapper.Delete(ctx, wrapper)
@@ -108,6 +138,10 @@ func (c *crudMapper[Model]) DeleteById(ctx context.Context, id uint) error {
 	return c.mapper.DeleteById(ctx, id)
 }
 
+func (c *crudMapper[Model]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.mapper.Transaction(ctx, fn)
+}
+
 func (c *crudMapper[Model]) Create(ctx context.Context, entity *Model) error {
 	return c.mapper.Create(ctx, entity)
 }
@@ -120,3 +154,15 @@ func (c *crudMapper[Model]) Update(ctx context.Context, wrapper func(*gorm.DB) *
 func (c *crudMapper[Model]) UpdateById(ctx context.Context, id uint, updated map[string]interface{}) error {
 	return c.mapper.UpdateById(ctx, id, updated)
 }
+
+func (c *crudMapper[Model]) CreateBatch(ctx context.Context, entities []*Model, mode BatchMode) ([]BatchResult, error) {
+	return c.mapper.CreateBatch(ctx, entities, mode)
+}
+
+func (c *crudMapper[Model]) UpdateBatch(ctx context.Context, updates []BatchUpdate, mode BatchMode) ([]BatchResult, error) {
+	return c.mapper.UpdateBatch(ctx, updates, mode)
+}
+
+func (c *crudMapper[Model]) DeleteBatch(ctx context.Context, ids []uint, mode BatchMode) ([]BatchResult, error) {
+	return c.mapper.DeleteBatch(ctx, ids, mode)
+}
@@ -3,6 +3,8 @@ package mapper
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -149,6 +151,36 @@ func (t *_testCRUDMapper) Test_Paginate_LastPageMoreData() {
 	}
 }
 
+func (t *_testCRUDMapper) Test_Paginate_Sharding() {
+	dir := t.T().TempDir()
+	dbs := make([]*gorm.DB, 2)
+	for i := range dbs {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, fmt.Sprintf("shard%d.db", i))), &gorm.Config{})
+		t.Require().NoError(err)
+		dbs[i] = db.Debug()
+		t.Require().NoError(dbs[i].AutoMigrate(&_testShardFoo{}))
+		defer func() {
+			sqlDB, err := db.DB()
+			t.Require().NoError(err)
+			t.Require().NoError(sqlDB.Close())
+		}()
+	}
+	mapper := NewCRUDMapper[_testShardFoo](dbs[0], WithSharding("user_id", RangeSharding{Bounds: []int64{10}, DBs: dbs}))
+	for i := 1; i <= 5; i++ {
+		t.Require().NoError(mapper.Create(context.TODO(), &_testShardFoo{ID: uint(i), UserID: uint(i * 4)}))
+	}
+
+	res, err := mapper.Paginate(context.TODO(), CRUDPaginator{Page: 2, PageSize: 2}, func(db *gorm.DB) *gorm.DB {
+		return db.Order("id desc")
+	})
+	t.Require().NoError(err)
+	t.EqualValues(5, res.Total)
+	t.EqualValues(3, res.TotalPage)
+	t.Len(res.Data, 2)
+	t.EqualValues(3, res.Data[0].ID)
+	t.EqualValues(2, res.Data[1].ID)
+}
+
 func (t *_testCRUDMapper) addData(num int) []_testFoo {
 	res := make([]_testFoo, 0, num)
 	for i := 0; i < num; i++ {
@@ -0,0 +1,207 @@
+package mapper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidField the requested field is not in the schema
+var ErrInvalidField = errors.New("invalid field")
+
+// FieldSet sparse fieldset of a model, made by IQueryMapper.Fields
+type FieldSet struct {
+	schema    *schema.Schema
+	columns   []string
+	names     map[string]bool
+	relations map[string]*FieldSet
+	jsonNames map[string]string
+}
+
+// ParseFieldSet parse fields like "id,name,owner.email" against the schema
+func ParseFieldSet(s *schema.Schema, fields ...string) (*FieldSet, error) {
+	f := newFieldSet(s)
+	for _, path := range fields {
+		if err := f.add(path, path); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func newFieldSet(s *schema.Schema) *FieldSet {
+	f := &FieldSet{
+		schema:    s,
+		names:     make(map[string]bool),
+		relations: make(map[string]*FieldSet),
+		jsonNames: make(map[string]string),
+	}
+	for _, field := range s.PrimaryFields {
+		f.addColumn(field.DBName)
+	}
+	return f
+}
+
+func (f *FieldSet) add(path, full string) error {
+	name, rest, nested := strings.Cut(strings.TrimSpace(path), ".")
+	if !nested {
+		field := lookupField(f.schema, name)
+		if field == nil {
+			return fmt.Errorf("%w: %s", ErrInvalidField, full)
+		}
+		f.addColumn(field.DBName)
+		f.names[jsonName(field)] = true
+		return nil
+	}
+
+	rel := lookupRelation(f.schema, name)
+	if rel == nil {
+		return fmt.Errorf("%w: %s", ErrInvalidField, full)
+	}
+	child, ok := f.relations[rel.Name]
+	if !ok {
+		child = newFieldSet(rel.FieldSchema)
+		for _, ref := range rel.References {
+			for _, key := range []*schema.Field{ref.PrimaryKey, ref.ForeignKey} {
+				if key == nil {
+					continue
+				}
+				switch key.Schema {
+				case f.schema:
+					f.addColumn(key.DBName)
+				case rel.FieldSchema:
+					child.addColumn(key.DBName)
+				}
+			}
+		}
+		f.relations[rel.Name] = child
+		f.jsonNames[rel.Name] = jsonName(rel.Field)
+	}
+	return child.add(rest, full)
+}
+
+func (f *FieldSet) addColumn(column string) {
+	for _, c := range f.columns {
+		if c == column {
+			return
+		}
+	}
+	f.columns = append(f.columns, column)
+}
+
+// Wrapper select the columns and preload the selected relations
+func (f *FieldSet) Wrapper() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Select(f.columns)
+		for name, child := range f.relations {
+			db = db.Preload(name, child.Wrapper())
+		}
+		return db
+	}
+}
+
+// Relations preload the selected relations without selecting the columns of the model,
+// for the reads which need the whole row, e.g. to authorize it, and shape the response afterwards
+func (f *FieldSet) Relations() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for name, child := range f.relations {
+			db = db.Preload(name, child.Wrapper())
+		}
+		return db
+	}
+}
+
+// Shape keep only the selected fields in the json of v, v is an entity or a slice of entity
+func (f *FieldSet) Shape(v interface{}) (interface{}, error) {
+	raw, err := toJSONValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return f.filter(raw), nil
+}
+
+// ShapeField like Shape, but only shape the value of key in the json object of v, e.g. the data of PageRes
+func (f *FieldSet) ShapeField(v interface{}, key string) (interface{}, error) {
+	raw, err := toJSONValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := raw.(map[string]interface{}); ok {
+		if data, ok := obj[key]; ok {
+			obj[key] = f.filter(data)
+		}
+	}
+	return raw, nil
+}
+
+func (f *FieldSet) filter(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		for i := range val {
+			val[i] = f.filter(val[i])
+		}
+		return val
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(f.names)+len(f.relations))
+		for name := range f.names {
+			if item, ok := val[name]; ok {
+				res[name] = item
+			}
+		}
+		for rel, child := range f.relations {
+			name := f.jsonNames[rel]
+			if item, ok := val[name]; ok {
+				res[name] = child.filter(item)
+			}
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	err = json.Unmarshal(b, &raw)
+	return raw, err
+}
+
+func jsonName(field *schema.Field) string {
+	name, _, _ := strings.Cut(field.StructField.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func lookupField(s *schema.Schema, name string) *schema.Field {
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.StructField.Tag.Get("json") == "-" {
+			continue
+		}
+		if jsonName(field) == name || field.DBName == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func lookupRelation(s *schema.Schema, name string) *schema.Relationship {
+	for _, rel := range s.Relationships.Relations {
+		if rel.Field.StructField.Tag.Get("json") == "-" {
+			continue
+		}
+		if jsonName(rel.Field) == name || strings.EqualFold(rel.Name, name) {
+			return rel
+		}
+	}
+	return nil
+}
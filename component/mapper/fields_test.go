@@ -0,0 +1,105 @@
+package mapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testOwner struct {
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type _testPet struct {
+	ID      uint        `gorm:"primaryKey" json:"id"`
+	Name    string      `json:"name"`
+	Kind    string      `json:"kind"`
+	OwnerID uint        `json:"owner_id"`
+	Owner   *_testOwner `json:"owner"`
+}
+
+type _testFields struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper BaseMapper[_testPet]
+}
+
+func (t *_testFields) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testOwner{}, &_testPet{}))
+	t.mapper = NewBaseMapper[_testPet](t.db)
+	owner := _testOwner{Name: "alice", Email: "alice@example.com"}
+	t.Require().NoError(t.db.Create(&owner).Error)
+	t.Require().NoError(t.db.Create(&_testPet{Name: "tom", Kind: "cat", OwnerID: owner.ID}).Error)
+}
+
+func (t *_testFields) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testFields) Test_Fields_Invalid() {
+	_, err := t.mapper.Fields("id", "password")
+	t.ErrorIs(err, ErrInvalidField)
+	_, err = t.mapper.Fields("owner.password")
+	t.ErrorIs(err, ErrInvalidField)
+	_, err = t.mapper.Fields("name.email")
+	t.ErrorIs(err, ErrInvalidField)
+}
+
+func (t *_testFields) Test_Fields_SelectColumns() {
+	fs, err := t.mapper.Fields("name")
+	t.Require().NoError(err)
+	res, err := t.mapper.All(context.TODO(), fs.Wrapper())
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("tom", res[0].Name)
+	t.EqualValues("", res[0].Kind)
+	t.NotZero(res[0].ID)
+
+	shaped, err := fs.Shape(res)
+	t.NoError(err)
+	t.EqualValues([]interface{}{map[string]interface{}{"name": "tom"}}, shaped)
+}
+
+func (t *_testFields) Test_Fields_Relation() {
+	fs, err := t.mapper.Fields("name", "owner.email")
+	t.Require().NoError(err)
+	res, err := t.mapper.One(context.TODO(), fs.Wrapper())
+	t.Require().NoError(err)
+	t.Require().NotNil(res.Owner)
+	t.EqualValues("alice@example.com", res.Owner.Email)
+	t.EqualValues("", res.Owner.Name)
+
+	shaped, err := fs.Shape(res)
+	t.NoError(err)
+	t.EqualValues(map[string]interface{}{
+		"name":  "tom",
+		"owner": map[string]interface{}{"email": "alice@example.com"},
+	}, shaped)
+}
+
+func (t *_testFields) Test_Fields_ShapePage() {
+	fs, err := t.mapper.Fields("kind")
+	t.Require().NoError(err)
+	res, err := t.mapper.Paginate(context.TODO(), Paginator{Limit: 10}, fs.Wrapper())
+	t.Require().NoError(err)
+	shaped, err := fs.ShapeField(res, "data")
+	t.NoError(err)
+	page := shaped.(map[string]interface{})
+	t.EqualValues(false, page["more"])
+	t.EqualValues([]interface{}{map[string]interface{}{"kind": "cat"}}, page["data"])
+}
+
+func TestFields(t *testing.T) {
+	suite.Run(t, &_testFields{})
+}
@@ -17,6 +17,14 @@ type IQueryMapper[T, U, V any] interface {
 	All(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, error)
 	Paginate(ctx context.Context, pager U, wrapper func(*gorm.DB) *gorm.DB) (*V, error)
 	Count(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (int, error)
+	// Fields validate the fields against the schema of T and make the sparse fieldset
+	Fields(fields ...string) (*FieldSet, error)
+	// Aggregate group the entities and aggregate the metrics, the columns are validated against the schema of T
+	Aggregate(ctx context.Context, aggregation Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]AggregateRow, error)
+	Sum(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error)
+	Avg(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error)
+	Min(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error)
+	Max(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error)
 }
 
 type ICommandMapper[T any] interface {
@@ -25,4 +33,12 @@ type ICommandMapper[T any] interface {
 	Create(ctx context.Context, entity *T) error
 	Update(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB, updated map[string]interface{}) error
 	UpdateById(ctx context.Context, id uint, updated map[string]interface{}) error
+	// Transaction run fn in a transaction, the mappers called with the ctx of fn join it
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// CreateBatch create the entities in a single transaction, see Batch
+	CreateBatch(ctx context.Context, entities []*T, mode BatchMode) ([]BatchResult, error)
+	// UpdateBatch update the entities in a single transaction, see Batch
+	UpdateBatch(ctx context.Context, updates []BatchUpdate, mode BatchMode) ([]BatchResult, error)
+	// DeleteBatch delete the entities of ids in a single transaction, see Batch
+	DeleteBatch(ctx context.Context, ids []uint, mode BatchMode) ([]BatchResult, error)
 }
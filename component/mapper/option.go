@@ -0,0 +1,41 @@
+package mapper
+
+import (
+	"time"
+
+	"github.com/go-gosh/gestful/component/audit"
+)
+
+// Option configure mapper
+type Option func(*options)
+
+type options struct {
+	search SearchProvider
+	tenant TenantStrategy
+
+	auditor   audit.AuditorAware
+	recorder  audit.Recorder
+	revisions bool
+	now       func() time.Time
+
+	shardKey string
+	sharding ShardingStrategy
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		search: LikeSearchProvider{},
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSearchProvider replace the default LikeSearchProvider
+func WithSearchProvider(provider SearchProvider) Option {
+	return func(o *options) {
+		o.search = provider
+	}
+}
@@ -0,0 +1,29 @@
+package mapper
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TagName struct tag read by gestful, e.g. `gestful:"search"`
+const TagName = "gestful"
+
+func parseSchema[T any](db *gorm.DB) (*schema.Schema, error) {
+	var t T
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&t); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+func hasTagOption(field *schema.Field, option string) bool {
+	for _, v := range strings.Split(field.Tag.Get(TagName), ",") {
+		if strings.TrimSpace(v) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package mapper
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrSearchUnsupported the mapper searched is not an ISearchMapper
+var ErrSearchUnsupported = errors.New("search unsupported")
+
+// ISearchMapper search the entities, the mappers of NewBaseMapper implement it,
+// it is apart from IQueryMapper so that the implementations of IQueryMapper need not search
+type ISearchMapper interface {
+	// Search make a wrapper filtering the searchable columns by q
+	Search(q string) func(*gorm.DB) *gorm.DB
+}
+
+// SearchWrapperFunc the wrapper searching q by m when it is an ISearchMapper,
+// the queries fail with ErrSearchUnsupported when it is not
+func SearchWrapperFunc(m interface{}, q string) func(*gorm.DB) *gorm.DB {
+	if q == "" {
+		return EmptyWrapperFunc
+	}
+	if searcher, ok := m.(ISearchMapper); ok {
+		return searcher.Search(q)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		_ = db.AddError(ErrSearchUnsupported)
+		return db
+	}
+}
+
+// SearchProvider make the search condition for q on columns
+type SearchProvider interface {
+	Search(db *gorm.DB, columns []string, q string) *gorm.DB
+}
+
+// SearchColumns columns tagged with `gestful:"search"`
+func SearchColumns(s *schema.Schema) []string {
+	columns := make([]string, 0)
+	for _, field := range s.Fields {
+		if field.DBName == "" || !hasTagOption(field, "search") {
+			continue
+		}
+		columns = append(columns, field.DBName)
+	}
+	return columns
+}
+
+// LikeSearchProvider match any column with LIKE '%q%', the wildcards of q are escaped by !
+// which, unlike \, is not an escape of the string literals of MySQL
+type LikeSearchProvider struct{}
+
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+func (LikeSearchProvider) Search(db *gorm.DB, columns []string, q string) *gorm.DB {
+	pattern := "%" + likeEscaper.Replace(q) + "%"
+	conditions := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		conditions = append(conditions, db.Statement.Quote(column)+` LIKE ? ESCAPE '!'`)
+		args = append(args, pattern)
+	}
+	return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// FTS5SearchProvider match against a sqlite FTS5 virtual table whose rowid is the primary key of the model,
+// the columns are used as the FTS5 column filter
+type FTS5SearchProvider struct {
+	// Table name of the FTS5 virtual table
+	Table string
+	// Key primary key column of the model, default id
+	Key string
+}
+
+func (p FTS5SearchProvider) Search(db *gorm.DB, columns []string, q string) *gorm.DB {
+	key := p.Key
+	if key == "" {
+		key = "id"
+	}
+	table := db.Statement.Quote(p.Table)
+	match := `"` + strings.ReplaceAll(q, `"`, `""`) + `"`
+	if len(columns) > 0 {
+		match = "{" + strings.Join(columns, " ") + "} : " + match
+	}
+	return db.Where(db.Statement.Quote(key)+" IN (SELECT rowid FROM "+table+" WHERE "+table+" MATCH ?)", match)
+}
+
+// TSVectorSearchProvider match with postgres full text search
+type TSVectorSearchProvider struct {
+	// Config text search configuration, default simple
+	Config string
+}
+
+func (p TSVectorSearchProvider) Search(db *gorm.DB, columns []string, q string) *gorm.DB {
+	config := p.Config
+	if config == "" {
+		config = "simple"
+	}
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, db.Statement.Quote(column))
+	}
+	return db.Where("to_tsvector(?::regconfig, concat_ws(' ', "+strings.Join(quoted, ", ")+")) @@ plainto_tsquery(?::regconfig, ?)",
+		config, config, q)
+}
@@ -0,0 +1,156 @@
+package mapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testSearchFoo struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gestful:"search"`
+	Email string `gestful:"search"`
+	Note  string
+}
+
+type _testSearch struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper BaseMapper[_testSearchFoo]
+}
+
+func (t *_testSearch) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testSearchFoo{}))
+	t.mapper = NewBaseMapper[_testSearchFoo](t.db)
+	for _, foo := range []_testSearchFoo{
+		{Name: "alice", Email: "alice@example.com", Note: "bob"},
+		{Name: "bob", Email: "bob@example.com"},
+		{Name: "carol", Email: "carol_100%@example.com"},
+	} {
+		t.Require().NoError(t.db.Create(&foo).Error)
+	}
+}
+
+func (t *_testSearch) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testSearch) Test_SearchColumns() {
+	s, err := parseSchema[_testSearchFoo](t.db)
+	t.Require().NoError(err)
+	t.EqualValues([]string{"name", "email"}, SearchColumns(s))
+}
+
+func (t *_testSearch) Test_Search_Empty() {
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, ""))
+	t.NoError(err)
+	t.Len(res, 3)
+}
+
+func (t *_testSearch) Test_Search_OnlyTaggedColumns() {
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, "bob"))
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("bob", res[0].Name)
+}
+
+func (t *_testSearch) Test_Search_AnyColumn() {
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, "example"))
+	t.NoError(err)
+	t.Len(res, 3)
+}
+
+func (t *_testSearch) Test_Search_EscapeWildcard() {
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, "_100%"))
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("carol", res[0].Name)
+	res, err = t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, "%"))
+	t.NoError(err)
+	t.Len(res, 1)
+}
+
+func (t *_testSearch) Test_Paginate_WithSearch() {
+	res, err := t.mapper.Paginate(context.TODO(), Paginator{Limit: 1}, SearchWrapperFunc(t.mapper, "example"))
+	t.NoError(err)
+	t.EqualValues(true, res.More)
+	t.Len(res.Data, 1)
+}
+
+func (t *_testSearch) Test_Search_NoSearchColumns() {
+	m := NewBaseMapper[_testFoo](t.db)
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	t.Require().NoError(t.db.Create(&_testFoo{}).Error)
+	res, err := m.All(context.TODO(), SearchWrapperFunc(m, "anything"))
+	t.NoError(err)
+	t.Len(res, 1)
+}
+
+func (t *_testSearch) Test_Search_EscapeCharacter() {
+	t.Require().NoError(t.db.Create(&_testSearchFoo{Name: "dave!", Email: "dave@example.com"}).Error)
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(t.mapper, "e!"))
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("dave!", res[0].Name)
+	stmt := t.dryRun(LikeSearchProvider{}, []string{"name"}, "x!_%")
+	t.Contains(stmt.SQL.String(), "(`name` LIKE ? ESCAPE '!')")
+	t.Equal([]interface{}{"%x!!!_!%%"}, stmt.Vars)
+}
+
+func (t *_testSearch) Test_Search_Unsupported() {
+	var m IQueryMapper[_testSearchFoo, Paginator, PageRes[_testSearchFoo]] = struct {
+		IQueryMapper[_testSearchFoo, Paginator, PageRes[_testSearchFoo]]
+	}{t.mapper}
+	_, err := t.mapper.All(context.TODO(), SearchWrapperFunc(m, "bob"))
+	t.ErrorIs(err, ErrSearchUnsupported)
+	res, err := t.mapper.All(context.TODO(), SearchWrapperFunc(m, ""))
+	t.NoError(err)
+	t.Len(res, 3)
+}
+
+func (t *_testSearch) Test_FTS5() {
+	if err := t.db.Exec("CREATE VIRTUAL TABLE foo_fts USING fts5(name, email)").Error; err != nil {
+		t.T().Skipf("sqlite is built without fts5, e.g. go test -tags sqlite_fts5: %v", err)
+	}
+	t.Require().NoError(t.db.Exec("INSERT INTO foo_fts (rowid, name, email) SELECT id, name, email FROM _test_search_foos").Error)
+	m := NewBaseMapper[_testSearchFoo](t.db, WithSearchProvider(FTS5SearchProvider{Table: "foo_fts"}))
+	res, err := m.All(context.TODO(), SearchWrapperFunc(m, "bob"))
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("bob", res[0].Name)
+	res, err = m.All(context.TODO(), SearchWrapperFunc(m, `bob"`))
+	t.NoError(err, "the quotes of q are escaped")
+	t.Len(res, 1)
+}
+
+// dryRun the statement of the search of provider
+func (t *_testSearch) dryRun(provider SearchProvider, columns []string, q string) *gorm.Statement {
+	db := t.db.Session(&gorm.Session{DryRun: true}).Model(&_testSearchFoo{})
+	return provider.Search(db, columns, q).Find(&[]_testSearchFoo{}).Statement
+}
+
+func (t *_testSearch) Test_FTS5_SQL() {
+	stmt := t.dryRun(FTS5SearchProvider{Table: "foo_fts"}, []string{"name", "email"}, `a"b`)
+	t.Contains(stmt.SQL.String(), "`id` IN (SELECT rowid FROM `foo_fts` WHERE `foo_fts` MATCH ?)")
+	t.Equal([]interface{}{`{name email} : "a""b"`}, stmt.Vars)
+}
+
+func (t *_testSearch) Test_TSVector_SQL() {
+	stmt := t.dryRun(TSVectorSearchProvider{Config: "english"}, []string{"name", "email"}, "bob")
+	t.Contains(stmt.SQL.String(), "to_tsvector(?::regconfig, concat_ws(' ', `name`, `email`)) @@ plainto_tsquery(?::regconfig, ?)")
+	t.Equal([]interface{}{"english", "english", "bob"}, stmt.Vars)
+	t.Equal([]interface{}{"simple", "simple", "bob"}, t.dryRun(TSVectorSearchProvider{}, []string{"name"}, "bob").Vars)
+}
+
+func TestSearch(t *testing.T) {
+	suite.Run(t, &_testSearch{})
+}
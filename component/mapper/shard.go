@@ -0,0 +1,791 @@
+package mapper
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrShardKeyRequired the operation of a sharded mapper runs on a single shard and ctx has no shard key,
+	// see WithShardKey
+	ErrShardKeyRequired = errors.New("shard key required")
+	// ErrCrossShard the operation spans several shards, e.g. an entity created out of the shard of ctx,
+	// or its results can't be merged, e.g. an order by expression
+	ErrCrossShard = errors.New("cross shard operation")
+)
+
+// ShardingStrategy split the entities of a mapper over the databases of the shards by their shard key
+type ShardingStrategy interface {
+	// Shards the databases of the shards
+	Shards() []*gorm.DB
+	// Shard the index of the shard of the shard key
+	Shard(key interface{}) (int, error)
+}
+
+// HashSharding shard by the FNV-1a hash of the shard key
+type HashSharding struct {
+	DBs []*gorm.DB
+}
+
+func (h HashSharding) Shards() []*gorm.DB {
+	return h.DBs
+}
+
+func (h HashSharding) Shard(key interface{}) (int, error) {
+	if len(h.DBs) == 0 {
+		return 0, errors.New("no shard")
+	}
+	f := fnv.New32a()
+	_, _ = fmt.Fprint(f, reflect.Indirect(reflect.ValueOf(key)))
+	return int(f.Sum32() % uint32(len(h.DBs))), nil
+}
+
+// RangeSharding shard by ranges of an integer shard key, the shard i holds the keys lower than Bounds[i]
+// and the last shard the others, so there is a shard more than the ascending Bounds
+type RangeSharding struct {
+	Bounds []int64
+	DBs    []*gorm.DB
+}
+
+func (r RangeSharding) Shards() []*gorm.DB {
+	return r.DBs
+}
+
+func (r RangeSharding) Shard(key interface{}) (int, error) {
+	if len(r.DBs) != len(r.Bounds)+1 {
+		return 0, fmt.Errorf("%d bounds need %d shards, got %d", len(r.Bounds), len(r.Bounds)+1, len(r.DBs))
+	}
+	k, err := toInt64(key)
+	if err != nil {
+		return 0, err
+	}
+	return sort.Search(len(r.Bounds), func(i int) bool { return k < r.Bounds[i] }), nil
+}
+
+func toInt64(key interface{}) (int64, error) {
+	v := reflect.Indirect(reflect.ValueOf(key))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.String:
+		return strconv.ParseInt(v.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("shard key %v is not an integer", key)
+}
+
+// WithSharding split the entities over the shards of strategy by the column key.
+// Create routes the entity by its key, the other operations run on the shard of the key of WithShardKey,
+// or fan out to every shard without it: One, All, Paginate, Count and Aggregate merge the results of the shards
+// in the order and the limit of the query, Update and Delete are not atomic across the shards.
+// Transaction without the shard key begins its transaction on the shard of the first entity created or loaded in it,
+// the writes before are not in the transaction and the partial batches need the shard key.
+// The primary keys are to be unique across the shards
+func WithSharding(key string, strategy ShardingStrategy) Option {
+	return func(o *options) {
+		o.shardKey = key
+		o.sharding = strategy
+	}
+}
+
+type shardKey struct{}
+
+// shardRoute the shard of a ctx, the shard key of WithShardKey or the index of a resolved shard
+type shardRoute struct {
+	key      interface{}
+	index    int
+	resolved bool
+}
+
+// WithShardKey route the operations of the sharded mappers with ctx to the shard of key
+func WithShardKey(ctx context.Context, key interface{}) context.Context {
+	return context.WithValue(ctx, shardKey{}, shardRoute{key: key})
+}
+
+// ShardRoute the shard route of ctx, e.g. to key the caches of the reads routed to a shard,
+// false when the reads of ctx fan out to every shard
+func ShardRoute(ctx context.Context) (string, bool) {
+	route, ok := ctx.Value(shardKey{}).(shardRoute)
+	if !ok {
+		return "", false
+	}
+	if route.resolved {
+		return fmt.Sprintf("shard:%d", route.index), true
+	}
+	return fmt.Sprintf("key:%v", route.key), true
+}
+
+func withShard(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, shardKey{}, shardRoute{index: index, resolved: true})
+}
+
+type lazyTxKey struct{}
+
+// lazyTx the transaction of a sharded mapper whose ctx has no shard key,
+// it is begun on the shard of the first entity created or loaded by one in it
+type lazyTx struct {
+	begin func(shard int) (*gorm.DB, error)
+	shard int
+	tx    *gorm.DB
+}
+
+// pin begin the transaction on shard, the transaction already begun is to be on shard
+func (l *lazyTx) pin(shard int) error {
+	if l.tx != nil {
+		if l.shard != shard {
+			return fmt.Errorf("%w: the transaction is on shard %d, not %d", ErrCrossShard, l.shard, shard)
+		}
+		return nil
+	}
+	tx, err := l.begin(shard)
+	if err != nil {
+		return err
+	}
+	l.shard, l.tx = shard, tx
+	return nil
+}
+
+// lazyTransaction run fn in a transaction begun on the shard of the first entity fn creates or loads,
+// e.g. the entity to update loaded by its id, fn joins the lazy transaction already carried in ctx
+func (m baseMapper[T]) lazyTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if lazy, ok := ctx.Value(lazyTxKey{}).(*lazyTx); ok {
+		return fn(withLazyTx(ctx, lazy))
+	}
+	lazy := &lazyTx{begin: func(shard int) (*gorm.DB, error) {
+		db, err := m.root(withShard(ctx, shard))
+		if err != nil {
+			return nil, err
+		}
+		tx := db.WithContext(ctx).Begin()
+		return tx, tx.Error
+	}}
+	ctx, hooks := withAfterCommit(ctx)
+	panicked := true
+	defer func() {
+		if lazy.tx != nil {
+			if panicked || err != nil {
+				lazy.tx.Rollback()
+				return
+			}
+			if err = lazy.tx.Commit().Error; err != nil {
+				return
+			}
+		}
+		if !panicked && err == nil {
+			hooks.run()
+		}
+	}()
+	err = fn(withLazyTx(ctx, lazy))
+	panicked = false
+	return err
+}
+
+// shard the index of the shard of ctx, false when ctx has no shard
+func (m baseMapper[T]) shard(ctx context.Context) (int, bool, error) {
+	route, ok := ctx.Value(shardKey{}).(shardRoute)
+	if !ok {
+		if lazy, ok := ctx.Value(lazyTxKey{}).(*lazyTx); ok && lazy.tx != nil {
+			return lazy.shard, true, nil
+		}
+		return 0, false, nil
+	}
+	if route.resolved {
+		return route.index, true, nil
+	}
+	i, err := m.sharding.Shard(route.key)
+	return i, err == nil, err
+}
+
+// database the database of the shard of ctx, the database of the mapper when it is not sharded
+func (m baseMapper[T]) database(ctx context.Context) (*gorm.DB, error) {
+	if m.sharding == nil {
+		return m.db, nil
+	}
+	i, ok, err := m.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrShardKeyRequired
+	}
+	shards := m.sharding.Shards()
+	if i < 0 || i >= len(shards) {
+		return nil, fmt.Errorf("shard %d out of %d shards", i, len(shards))
+	}
+	return shards[i], nil
+}
+
+// fanOut the operation with ctx runs on every shard, i.e. ctx has neither a shard nor a transaction of a shard
+func (m baseMapper[T]) fanOut(ctx context.Context) bool {
+	if m.sharding == nil {
+		return false
+	}
+	if _, ok := ctx.Value(shardKey{}).(shardRoute); ok {
+		return false
+	}
+	for _, db := range m.sharding.Shards() {
+		if _, ok := txOf(ctx, db); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fan run fn concurrently with the ctx of every shard, the error of the first failed shard is returned
+func (m baseMapper[T]) fan(ctx context.Context, fn func(ctx context.Context, shard int) error) error {
+	n := len(m.sharding.Shards())
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(withShard(ctx, i), i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// route the ctx of the shard of the entity to create, the shard of ctx is to be the shard of the entity
+func (m baseMapper[T]) route(ctx context.Context, entity *T) (context.Context, error) {
+	if m.sharding == nil {
+		return ctx, nil
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	field := s.LookUpField(m.shardKey)
+	if field == nil {
+		return nil, fmt.Errorf("shard key %s not found in %s", m.shardKey, s.Name)
+	}
+	key, _ := field.ValueOf(ctx, reflect.ValueOf(entity))
+	i, err := m.sharding.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	current, ok, err := m.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if lazy, ok := ctx.Value(lazyTxKey{}).(*lazyTx); ok {
+			return ctx, lazy.pin(i)
+		}
+		return withShard(ctx, i), nil
+	}
+	if current != i {
+		return nil, fmt.Errorf("%w: %s %v is not in shard %d", ErrCrossShard, m.shardKey, key, current)
+	}
+	return ctx, nil
+}
+
+// order a column of the order of a query
+type order struct {
+	field *schema.Field
+	desc  bool
+}
+
+// plan the order, the limit and the offset of the query of wrapper, the results of the shards are merged by them
+type plan[T any] struct {
+	orders []order
+	limit  int
+	offset int
+}
+
+func (m baseMapper[T]) plan(wrapper func(*gorm.DB) *gorm.DB) (*plan[T], error) {
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	var t T
+	db := wrapper(m.db.Session(&gorm.Session{NewDB: true}).Model(&t))
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	p := &plan[T]{}
+	if c, ok := db.Statement.Clauses["ORDER BY"]; ok {
+		orderBy, _ := c.Expression.(clause.OrderBy)
+		if orderBy.Expression != nil {
+			return nil, fmt.Errorf("%w: order by expression", ErrCrossShard)
+		}
+		for _, column := range orderBy.Columns {
+			columns := []clause.OrderByColumn{column}
+			if column.Column.Raw {
+				columns = parseOrder(column.Column.Name)
+			}
+			for _, column := range columns {
+				field := s.LookUpField(column.Column.Name)
+				if field == nil {
+					return nil, fmt.Errorf("%w: order by %s", ErrCrossShard, column.Column.Name)
+				}
+				p.orders = append(p.orders, order{field: field, desc: column.Desc})
+			}
+		}
+	}
+	if c, ok := db.Statement.Clauses["LIMIT"]; ok {
+		limit, _ := c.Expression.(clause.Limit)
+		p.limit, p.offset = limit.Limit, limit.Offset
+	}
+	return p, nil
+}
+
+// parseOrder parse a raw order like "name desc, id"
+func parseOrder(raw string) []clause.OrderByColumn {
+	res := make([]clause.OrderByColumn, 0)
+	for _, part := range strings.Split(raw, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 {
+			continue
+		}
+		name := words[0]
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		res = append(res, clause.OrderByColumn{
+			Column: clause.Column{Name: strings.Trim(name, "`\"[]")},
+			Desc:   len(words) > 1 && strings.EqualFold(words[1], "desc"),
+		})
+	}
+	return res
+}
+
+// shardWrapper the query of a shard, it returns the rows up to the limit of wrapper from the first one
+func (p *plan[T]) shardWrapper(wrapper func(*gorm.DB) *gorm.DB) func(*gorm.DB) *gorm.DB {
+	if p.limit <= 0 && p.offset <= 0 {
+		return wrapper
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		db = wrapper(db).Offset(-1)
+		if p.limit > 0 {
+			db = db.Limit(p.offset + p.limit)
+		}
+		return db
+	}
+}
+
+// sort the rows merged from the shards by the order
+func (p *plan[T]) sort(ctx context.Context, rows []T) {
+	if len(p.orders) == 0 {
+		return
+	}
+	sort.Stable(&merged[T]{ctx: ctx, plan: p, rows: rows})
+}
+
+// less the row a is before the row b in the order
+func (p *plan[T]) less(ctx context.Context, a, b *T) bool {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for _, o := range p.orders {
+		c := compare(o.field.ReflectValueOf(ctx, va).Interface(), o.field.ReflectValueOf(ctx, vb).Interface())
+		if c != 0 {
+			return (c < 0) != o.desc
+		}
+	}
+	return false
+}
+
+// merged the rows merged from the shards sorted by a plan, shards is the shard of every row, optional
+type merged[T any] struct {
+	ctx    context.Context
+	plan   *plan[T]
+	rows   []T
+	shards []int
+}
+
+func (m *merged[T]) Len() int {
+	return len(m.rows)
+}
+
+func (m *merged[T]) Less(i, j int) bool {
+	return m.plan.less(m.ctx, &m.rows[i], &m.rows[j])
+}
+
+func (m *merged[T]) Swap(i, j int) {
+	m.rows[i], m.rows[j] = m.rows[j], m.rows[i]
+	if m.shards != nil {
+		m.shards[i], m.shards[j] = m.shards[j], m.shards[i]
+	}
+}
+
+// slice apply the limit and the offset to the merged rows
+func (p *plan[T]) slice(rows []T) []T {
+	start, end := p.window(len(rows))
+	return rows[start:end]
+}
+
+// window the bounds of the limit and the offset in the n merged rows
+func (p *plan[T]) window(n int) (int, int) {
+	start := p.offset
+	if start < 0 {
+		start = 0
+	}
+	if start > n {
+		start = n
+	}
+	end := n
+	if p.limit > 0 && start+p.limit < n {
+		end = start + p.limit
+	}
+	return start, end
+}
+
+// compare the values of a column, null is the lowest
+func compare(a, b interface{}) int {
+	va, vb := value(a), value(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return boolInt(va.IsValid()) - boolInt(vb.IsValid())
+	}
+	if ta, ok := va.Interface().(time.Time); ok {
+		if tb, ok := vb.Interface().(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	switch {
+	case isInt(va) && isInt(vb):
+		return cmp(va.Int(), vb.Int())
+	case isUint(va) && isUint(vb):
+		return cmp(va.Uint(), vb.Uint())
+	}
+	if fa, ok := toNumber(va); ok {
+		if fb, ok := toNumber(vb); ok {
+			return cmp(fa, fb)
+		}
+	}
+	if va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool {
+		return boolInt(va.Bool()) - boolInt(vb.Bool())
+	}
+	return strings.Compare(fmt.Sprint(va.Interface()), fmt.Sprint(vb.Interface()))
+}
+
+// value the dereferenced value of v, the driver value of a driver.Valuer, invalid for null
+func value(v interface{}) reflect.Value {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return reflect.Value{}
+		}
+		v, _ = valuer.Value()
+	}
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func toNumber(v reflect.Value) (float64, bool) {
+	switch {
+	case isInt(v):
+		return float64(v.Int()), true
+	case isUint(v):
+		return float64(v.Uint()), true
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func cmp[N int64 | uint64 | float64](a, b N) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// oneShards the first row of the shards, the lazy transaction of ctx is begun on its shard
+func (m baseMapper[T]) oneShards(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*T, error) {
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return nil, err
+	}
+	rows, shards, err := m.mergeShards(ctx, func(db *gorm.DB) *gorm.DB {
+		db = wrapper(db)
+		if s.PrioritizedPrimaryField != nil {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: s.PrioritizedPrimaryField.DBName}})
+		}
+		return db.Limit(1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if lazy, ok := ctx.Value(lazyTxKey{}).(*lazyTx); ok {
+		if err := lazy.pin(shards[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &rows[0], nil
+}
+
+func (m baseMapper[T]) allShards(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, error) {
+	rows, _, err := m.mergeShards(ctx, wrapper)
+	return rows, err
+}
+
+// mergeShards the rows of the shards merged by the order and the limit of wrapper, with the shard of every row
+func (m baseMapper[T]) mergeShards(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, []int, error) {
+	p, err := m.plan(wrapper)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([][]T, len(m.sharding.Shards()))
+	err = m.fan(ctx, func(ctx context.Context, shard int) error {
+		var err error
+		results[shard], err = m.All(ctx, p.shardWrapper(wrapper))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	res := &merged[T]{ctx: ctx, plan: p, rows: make([]T, 0), shards: make([]int, 0)}
+	for shard, rows := range results {
+		res.rows = append(res.rows, rows...)
+		for range rows {
+			res.shards = append(res.shards, shard)
+		}
+	}
+	sort.Stable(res)
+	start, end := p.window(len(res.rows))
+	return res.rows[start:end], res.shards[start:end], nil
+}
+
+func (m baseMapper[T]) paginateShards(ctx context.Context, pager Paginator, wrapper func(*gorm.DB) *gorm.DB) (*PageRes[T], error) {
+	p, err := m.plan(func(db *gorm.DB) *gorm.DB {
+		return wrapper(db).Order("id asc")
+	})
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]*PageRes[T], len(m.sharding.Shards()))
+	err = m.fan(ctx, func(ctx context.Context, shard int) error {
+		var err error
+		pages[shard], err = m.Paginate(ctx, pager, wrapper)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]T, 0, pager.Limit+1)
+	more := false
+	for _, page := range pages {
+		res = append(res, page.Data...)
+		more = more || page.More
+	}
+	p.sort(ctx, res)
+	if len(res) > pager.Limit {
+		res = res[:pager.Limit]
+		more = true
+	}
+	return &PageRes[T]{
+		Paginator: pager,
+		More:      more,
+		Data:      res,
+	}, nil
+}
+
+func (m baseMapper[T]) countShards(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (int, error) {
+	counts := make([]int, len(m.sharding.Shards()))
+	err := m.fan(ctx, func(ctx context.Context, shard int) error {
+		var err error
+		counts[shard], err = m.Count(ctx, wrapper)
+		return err
+	})
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return total, err
+}
+
+// writeShards run the write fn on every shard, gorm.ErrRecordNotFound when no shard found a row
+func (m baseMapper[T]) writeShards(ctx context.Context, fn func(ctx context.Context) error) error {
+	var found atomic.Bool
+	err := m.fan(ctx, func(ctx context.Context, _ int) error {
+		err := fn(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err == nil {
+			found.Store(true)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !found.Load() {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// aggregateShards merge the groups of the shards, avg is weighted by the count of the column of every shard
+// and min and max skip the shards whose column is null in every row of the group.
+// A group within the limit is within the limit of every shard having it, as the shards order the groups alike
+func (m baseMapper[T]) aggregateShards(ctx context.Context, aggregation Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]AggregateRow, error) {
+	shardAggregation := aggregation.Aggregate(Count())
+	for _, metric := range aggregation.Metrics {
+		switch metric.Func {
+		case AggregateAvg, AggregateMin, AggregateMax:
+			shardAggregation = shardAggregation.Aggregate(Metric{Func: AggregateCount, Column: metric.Column})
+		}
+	}
+	results := make([][]AggregateRow, len(m.sharding.Shards()))
+	err := m.fan(ctx, func(ctx context.Context, shard int) error {
+		var err error
+		results[shard], err = m.Aggregate(ctx, shardAggregation, wrapper)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*AggregateRow)
+	keys := make([][]interface{}, 0)
+	for _, rows := range results {
+		for _, row := range rows {
+			if row.Metrics[Count().Name()] == 0 {
+				// the row of a shard without entity
+				continue
+			}
+			values := make([]interface{}, len(aggregation.GroupBy))
+			for i, column := range aggregation.GroupBy {
+				values[i] = row.Group[column]
+			}
+			key := fmt.Sprintf("%#v", values)
+			merged, ok := groups[key]
+			if !ok {
+				row := row
+				groups[key] = &row
+				keys = append(keys, values)
+				continue
+			}
+			mergeMetrics(merged.Metrics, row.Metrics, shardAggregation.Metrics)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		for k := range keys[i] {
+			if c := compare(keys[i][k], keys[j][k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	if len(keys) == 0 && len(aggregation.GroupBy) == 0 {
+		row := AggregateRow{Group: map[string]interface{}{}, Metrics: make(map[string]float64, len(aggregation.Metrics))}
+		for _, metric := range aggregation.Metrics {
+			row.Metrics[metric.Name()] = 0
+		}
+		return []AggregateRow{row}, nil
+	}
+	names := make(map[string]bool, len(aggregation.Metrics))
+	for _, metric := range aggregation.Metrics {
+		names[metric.Name()] = true
+	}
+	res := make([]AggregateRow, 0, len(keys))
+	for _, values := range keys {
+		row := groups[fmt.Sprintf("%#v", values)]
+		for name := range row.Metrics {
+			if !names[name] {
+				delete(row.Metrics, name)
+			}
+		}
+		res = append(res, *row)
+		if len(res) == aggregation.Limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+// mergeMetrics merge the metrics of a shard into the merged metrics, the counts of the columns are merged
+// after the averages, the minimums and the maximums, which skip the null values by them
+func mergeMetrics(merged, shard map[string]float64, metrics []Metric) {
+	done := make(map[string]bool, len(metrics))
+	for _, metric := range metrics {
+		name := metric.Name()
+		if done[name] {
+			continue
+		}
+		count := Metric{Func: AggregateCount, Column: metric.Column}.Name()
+		switch {
+		case metric.Func != AggregateAvg && metric.Func != AggregateMin && metric.Func != AggregateMax:
+			continue
+		case shard[count] == 0:
+		case merged[count] == 0:
+			merged[name] = shard[name]
+		case metric.Func == AggregateAvg:
+			merged[name] = (merged[name]*merged[count] + shard[name]*shard[count]) / (merged[count] + shard[count])
+		case metric.Func == AggregateMin:
+			merged[name] = math.Min(merged[name], shard[name])
+		case metric.Func == AggregateMax:
+			merged[name] = math.Max(merged[name], shard[name])
+		}
+		done[name] = true
+	}
+	for _, metric := range metrics {
+		name := metric.Name()
+		if done[name] {
+			continue
+		}
+		done[name] = true
+		switch metric.Func {
+		case AggregateCount, AggregateSum:
+			merged[name] += shard[name]
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testShardFoo struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Amount int    `json:"amount"`
+}
+
+type _testShard struct {
+	suite.Suite
+	dbs    []*gorm.DB
+	mapper BaseMapper[_testShardFoo]
+}
+
+func (t *_testShard) SetupTest() {
+	dir := t.T().TempDir()
+	t.dbs = make([]*gorm.DB, 3)
+	for i := range t.dbs {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, fmt.Sprintf("shard%d.db", i))), &gorm.Config{})
+		t.Require().NoError(err)
+		t.dbs[i] = db.Debug()
+		t.Require().NoError(t.dbs[i].AutoMigrate(&_testShardFoo{}))
+	}
+	// users below 10 in shard 0, below 20 in shard 1, the others in shard 2
+	t.mapper = NewBaseMapper[_testShardFoo](t.dbs[0], WithSharding("user_id", RangeSharding{Bounds: []int64{10, 20}, DBs: t.dbs}))
+	for _, foo := range []_testShardFoo{
+		{ID: 1, UserID: 1, Name: "a", Amount: 10},
+		{ID: 2, UserID: 15, Name: "b", Amount: 20},
+		{ID: 3, UserID: 25, Name: "c", Amount: 30},
+		{ID: 4, UserID: 2, Name: "d", Amount: 40},
+		{ID: 5, UserID: 16, Name: "a", Amount: 50},
+	} {
+		foo := foo
+		t.Require().NoError(t.mapper.Create(context.TODO(), &foo))
+	}
+}
+
+func (t *_testShard) TearDownTest() {
+	for _, db := range t.dbs {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}
+}
+
+func (t *_testShard) shardIds(i int) []uint {
+	ids := make([]uint, 0)
+	t.Require().NoError(t.dbs[i].Model(&_testShardFoo{}).Order("id").Pluck("id", &ids).Error)
+	return ids
+}
+
+func (t *_testShard) Test_CreateRoutesByShardKey() {
+	t.Equal([]uint{1, 4}, t.shardIds(0))
+	t.Equal([]uint{2, 5}, t.shardIds(1))
+	t.Equal([]uint{3}, t.shardIds(2))
+
+	ctx := WithShardKey(context.TODO(), 1)
+	t.ErrorIs(t.mapper.Create(ctx, &_testShardFoo{ID: 6, UserID: 30}), ErrCrossShard)
+	t.NoError(t.mapper.Create(ctx, &_testShardFoo{ID: 6, UserID: 3}))
+	t.Equal([]uint{1, 4, 6}, t.shardIds(0))
+}
+
+func (t *_testShard) Test_SingleShard() {
+	ctx := WithShardKey(context.TODO(), 15)
+	res, err := t.mapper.All(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(res, 2)
+	_, err = t.mapper.OneById(ctx, 1)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+	t.NoError(t.mapper.UpdateById(ctx, 2, map[string]interface{}{"name": "bb"}))
+	t.ErrorIs(t.mapper.DeleteById(ctx, 3), gorm.ErrRecordNotFound)
+
+	err = t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		return t.mapper.DeleteById(ctx, 5)
+	})
+	t.NoError(err)
+	t.Equal([]uint{2}, t.shardIds(1))
+}
+
+func (t *_testShard) Test_LazyTransaction() {
+	ctx := context.TODO()
+	t.NoError(t.mapper.Transaction(ctx, func(context.Context) error { return nil }))
+	t.NoError(t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		foo, err := t.mapper.OneById(ctx, 2)
+		if err != nil {
+			return err
+		}
+		return t.mapper.UpdateById(ctx, foo.ID, map[string]interface{}{"name": "bb"})
+	}))
+	foo, err := t.mapper.OneById(ctx, 2)
+	t.NoError(err)
+	t.EqualValues("bb", foo.Name)
+
+	err = t.mapper.Transaction(ctx, func(ctx context.Context) error {
+		if err := t.mapper.Create(ctx, &_testShardFoo{ID: 6, UserID: 3}); err != nil {
+			return err
+		}
+		return t.mapper.Create(ctx, &_testShardFoo{ID: 7, UserID: 30})
+	})
+	t.ErrorIs(err, ErrCrossShard)
+	t.Equal([]uint{1, 4}, t.shardIds(0), "the transaction of the shard is rolled back")
+
+	_, err = t.mapper.CreateBatch(ctx, []*_testShardFoo{{ID: 6, UserID: 3}}, BatchPartial)
+	t.ErrorIs(err, ErrShardKeyRequired)
+	_, err = t.mapper.CreateBatch(ctx, []*_testShardFoo{{ID: 6, UserID: 3}, {ID: 7, UserID: 4}}, BatchAtomic)
+	t.NoError(err)
+	t.Equal([]uint{1, 4, 6, 7}, t.shardIds(0))
+}
+
+func (t *_testShard) Test_FanOutReads() {
+	ctx := context.TODO()
+	foo, err := t.mapper.OneById(ctx, 3)
+	t.NoError(err)
+	t.EqualValues(25, foo.UserID)
+	_, err = t.mapper.OneById(ctx, 7)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	res, err := t.mapper.All(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Order("name desc, id asc").Offset(1).Limit(3)
+	})
+	t.NoError(err)
+	ids := make([]uint, 0)
+	for _, foo := range res {
+		ids = append(ids, foo.ID)
+	}
+	t.Equal([]uint{3, 2, 1}, ids)
+
+	c, err := t.mapper.Count(ctx, func(db *gorm.DB) *gorm.DB { return db.Where("amount > ?", 15) })
+	t.NoError(err)
+	t.Equal(4, c)
+
+	_, err = t.mapper.All(ctx, func(db *gorm.DB) *gorm.DB { return db.Order("length(name)") })
+	t.ErrorIs(err, ErrCrossShard)
+}
+
+func (t *_testShard) Test_FanOutPaginate() {
+	ctx := context.TODO()
+	page, err := t.mapper.Paginate(ctx, Paginator{Limit: 2}, EmptyWrapperFunc)
+	t.NoError(err)
+	t.True(page.More)
+	t.Len(page.Data, 2)
+	t.EqualValues(1, page.Data[0].ID)
+	t.EqualValues(2, page.Data[1].ID)
+
+	page, err = t.mapper.Paginate(ctx, Paginator{StartId: 2, Limit: 3}, EmptyWrapperFunc)
+	t.NoError(err)
+	t.False(page.More)
+	t.Len(page.Data, 3)
+	t.EqualValues(3, page.Data[0].ID)
+	t.EqualValues(5, page.Data[2].ID)
+}
+
+func (t *_testShard) Test_FanOutWrites() {
+	ctx := context.TODO()
+	t.NoError(t.mapper.Update(ctx, func(db *gorm.DB) *gorm.DB { return db.Where("name = ?", "a") }, map[string]interface{}{"amount": 1}))
+	sum, err := t.mapper.Sum(ctx, "amount", EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(92, sum)
+	t.ErrorIs(t.mapper.UpdateById(ctx, 7, map[string]interface{}{"amount": 1}), gorm.ErrRecordNotFound)
+
+	t.NoError(t.mapper.DeleteById(ctx, 3))
+	t.Empty(t.shardIds(2))
+	t.ErrorIs(t.mapper.DeleteById(ctx, 3), gorm.ErrRecordNotFound)
+}
+
+func (t *_testShard) Test_FanOutAggregate() {
+	ctx := context.TODO()
+	rows, err := t.mapper.Aggregate(ctx, GroupBy("name").Aggregate(Count(), Sum("amount"), Avg("amount"), Min("amount"), Max("amount")), EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(rows, 4)
+	t.Equal("a", rows[0].Group["name"])
+	t.Equal(map[string]float64{"count": 2, "sum(amount)": 60, "avg(amount)": 30, "min(amount)": 10, "max(amount)": 50}, rows[0].Metrics)
+	t.Equal("d", rows[3].Group["name"])
+
+	limited, err := t.mapper.Aggregate(ctx, GroupBy("name").Aggregate(Count(), Sum("amount")).WithLimit(2), EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(limited, 2)
+	for i := range limited {
+		t.Equal(rows[i].Group, limited[i].Group)
+		t.Equal(rows[i].Metrics["sum(amount)"], limited[i].Metrics["sum(amount)"], "the groups of every shard are merged")
+	}
+
+	avg, err := t.mapper.Avg(ctx, "amount", EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(30, avg)
+	min, err := t.mapper.Min(ctx, "amount", func(db *gorm.DB) *gorm.DB { return db.Where("amount > ?", 15) })
+	t.NoError(err)
+	t.EqualValues(20, min)
+}
+
+func (t *_testShard) Test_FanOutAggregate_Null() {
+	ctx := context.TODO()
+	// every amount of shard 0 and shard 2 is null
+	for _, i := range []int{0, 2} {
+		t.Require().NoError(t.dbs[i].Exec("UPDATE _test_shard_foos SET amount = NULL").Error)
+	}
+	rows, err := t.mapper.Aggregate(ctx, GroupBy().Aggregate(Min("amount"), Max("amount"), Avg("amount")), EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(rows, 1)
+	t.Equal(map[string]float64{"min(amount)": 20, "max(amount)": 50, "avg(amount)": 35}, rows[0].Metrics)
+
+	rows, err = t.mapper.Aggregate(ctx, GroupBy("name").Aggregate(Min("amount"), Max("amount")), EmptyWrapperFunc)
+	t.NoError(err)
+	t.Equal("a", rows[0].Group["name"])
+	t.Equal(map[string]float64{"min(amount)": 50, "max(amount)": 50}, rows[0].Metrics)
+}
+
+func (t *_testShard) Test_HashSharding() {
+	h := HashSharding{DBs: t.dbs}
+	a, err := h.Shard(uint(42))
+	t.NoError(err)
+	b, err := h.Shard(42)
+	t.NoError(err)
+	t.Equal(a, b)
+	t.GreaterOrEqual(a, 0)
+	t.Less(a, 3)
+
+	_, err = RangeSharding{Bounds: []int64{10}, DBs: t.dbs}.Shard(1)
+	t.Error(err)
+	_, err = RangeSharding{Bounds: []int64{10, 20}, DBs: t.dbs}.Shard("x")
+	t.Error(err)
+}
+
+func TestShard(t *testing.T) {
+	suite.Run(t, &_testShard{})
+}
@@ -0,0 +1,245 @@
+package mapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/go-gosh/gestful/component/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidTenant the tenant id can not name a schema, see SchemaTenant
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+// TenantStrategy isolate the tenants of a mapper, the tenant id is read by tenant.FromContext
+type TenantStrategy interface {
+	// Database the database of tenant
+	Database(db *gorm.DB, tenant string) (*gorm.DB, error)
+	// Scope isolate the statement of tenant on the table of s
+	Scope(db *gorm.DB, s *schema.Schema, tenant string) *gorm.DB
+	// Table the name of table in the database of tenant, e.g. the history table of an entity
+	Table(table, tenant string) (string, error)
+	// Assign bind the entity to create to tenant
+	Assign(ctx context.Context, s *schema.Schema, entity interface{}, tenant string) error
+	// Protect drop the changes moving the entity out of its tenant
+	Protect(s *schema.Schema, updated map[string]interface{})
+}
+
+// ColumnTenant shared table with a tenant column, e.g. tenant_id
+type ColumnTenant struct {
+	Column string
+}
+
+func (c ColumnTenant) Database(db *gorm.DB, _ string) (*gorm.DB, error) {
+	return db, nil
+}
+
+func (c ColumnTenant) Scope(db *gorm.DB, s *schema.Schema, tenant string) *gorm.DB {
+	return db.Where(clause.Eq{Column: clause.Column{Table: s.Table, Name: c.Column}, Value: tenant})
+}
+
+func (c ColumnTenant) Table(table, _ string) (string, error) {
+	return table, nil
+}
+
+func (c ColumnTenant) Assign(ctx context.Context, s *schema.Schema, entity interface{}, tenant string) error {
+	field := s.LookUpField(c.Column)
+	if field == nil {
+		return fmt.Errorf("tenant column %s not found in %s", c.Column, s.Name)
+	}
+	return field.Set(ctx, reflect.ValueOf(entity), tenant)
+}
+
+func (c ColumnTenant) Protect(s *schema.Schema, updated map[string]interface{}) {
+	delete(updated, c.Column)
+	if field := s.LookUpField(c.Column); field != nil {
+		delete(updated, field.Name)
+	}
+}
+
+// schemaName the tenant ids allowed in a schema name, the id is read from the client and can not be quoted portably
+var schemaName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// SchemaTenant schema per tenant, the table is qualified with the schema of tenant,
+// the tenant ids out of [A-Za-z0-9_] fail with ErrInvalidTenant
+type SchemaTenant struct {
+	// Format of the schema name, e.g. tenant_%s
+	Format string
+}
+
+func (t SchemaTenant) Database(db *gorm.DB, _ string) (*gorm.DB, error) {
+	return db, nil
+}
+
+func (t SchemaTenant) Scope(db *gorm.DB, s *schema.Schema, tenant string) *gorm.DB {
+	table, err := t.Table(s.Table, tenant)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	// the insert names the table too, as the sqlite dialect drops the schema of Table on insert
+	return db.Table(table).Clauses(clause.Insert{Table: clause.Table{Name: table}})
+}
+
+func (t SchemaTenant) Table(table, tenant string) (string, error) {
+	if !schemaName.MatchString(tenant) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, tenant)
+	}
+	return fmt.Sprintf(t.Format, tenant) + "." + table, nil
+}
+
+func (t SchemaTenant) Assign(context.Context, *schema.Schema, interface{}, string) error {
+	return nil
+}
+
+func (t SchemaTenant) Protect(*schema.Schema, map[string]interface{}) {}
+
+// DatabaseTenant database per tenant
+type DatabaseTenant struct {
+	// Resolve the database of tenant
+	Resolve func(tenant string) (*gorm.DB, error)
+}
+
+func (t DatabaseTenant) Database(_ *gorm.DB, tenant string) (*gorm.DB, error) {
+	return t.Resolve(tenant)
+}
+
+func (t DatabaseTenant) Scope(db *gorm.DB, _ *schema.Schema, _ string) *gorm.DB {
+	return db
+}
+
+func (t DatabaseTenant) Table(table, _ string) (string, error) {
+	return table, nil
+}
+
+func (t DatabaseTenant) Assign(context.Context, *schema.Schema, interface{}, string) error {
+	return nil
+}
+
+func (t DatabaseTenant) Protect(*schema.Schema, map[string]interface{}) {}
+
+// tenantSetting the setting of the connections isolated by a tenant strategy, see TenantScope
+const tenantSetting = "gestful:tenant"
+
+type tenantScope struct {
+	strategy TenantStrategy
+	tenant   string
+}
+
+// TenantScope the table of model and the conditions of its rows in the tenant of db, when db is the connection
+// of a mapper with a tenant strategy, e.g. to join the other models to a query or to query them in a subquery.
+// Otherwise, it is the table of model without condition
+func TenantScope(db *gorm.DB, model interface{}) (string, []clause.Expression, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", nil, err
+	}
+	v, ok := db.Get(tenantSetting)
+	if !ok {
+		return stmt.Schema.Table, nil, nil
+	}
+	scope := v.(tenantScope)
+	table, err := scope.strategy.Table(stmt.Schema.Table, scope.tenant)
+	if err != nil {
+		return "", nil, err
+	}
+	scoped := scope.strategy.Scope(db.Session(&gorm.Session{NewDB: true}).Model(model), stmt.Schema, scope.tenant)
+	if scoped.Error != nil {
+		return "", nil, scoped.Error
+	}
+	conditions := make([]clause.Expression, 0)
+	if c, ok := scoped.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conditions = append(conditions, where.Exprs...)
+		}
+	}
+	return table, conditions, nil
+}
+
+// WithTenantStrategy isolate every query, create and update by the tenant in the context,
+// the operations without tenant fail with tenant.ErrTenantRequired
+func WithTenantStrategy(strategy TenantStrategy) Option {
+	return func(o *options) {
+		o.tenant = strategy
+	}
+}
+
+// root the database of the shard and the tenant in ctx
+func (m baseMapper[T]) root(ctx context.Context) (*gorm.DB, error) {
+	db, err := m.database(ctx)
+	if err != nil || m.tenant == nil {
+		return db, err
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrTenantRequired
+	}
+	return m.tenant.Database(db, id)
+}
+
+// conn the connection of ctx routed to its shard and isolated by the tenant strategy,
+// the errors are added to the connection
+func (m baseMapper[T]) conn(ctx context.Context) *gorm.DB {
+	base, err := m.database(ctx)
+	if err != nil {
+		db := conn(ctx, m.db)
+		_ = db.AddError(err)
+		return db
+	}
+	if m.tenant == nil {
+		return conn(ctx, base)
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		db := conn(ctx, base)
+		_ = db.AddError(tenant.ErrTenantRequired)
+		return db
+	}
+	root, err := m.tenant.Database(base, id)
+	if err != nil {
+		db := conn(ctx, base)
+		_ = db.AddError(err)
+		return db
+	}
+	db := conn(ctx, root)
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	return m.tenant.Scope(db, s, id).Set(tenantSetting, tenantScope{strategy: m.tenant, tenant: id})
+}
+
+// assign bind the entity to create to the tenant in ctx
+func (m baseMapper[T]) assign(ctx context.Context, entity *T) error {
+	if m.tenant == nil {
+		return nil
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrTenantRequired
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return err
+	}
+	return m.tenant.Assign(ctx, s, entity, id)
+}
+
+// protect drop the changes moving the entity out of its tenant
+func (m baseMapper[T]) protect(updated map[string]interface{}) error {
+	if m.tenant == nil {
+		return nil
+	}
+	s, err := parseSchema[T](m.db)
+	if err != nil {
+		return err
+	}
+	m.tenant.Protect(s, updated)
+	return nil
+}
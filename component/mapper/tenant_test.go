@@ -0,0 +1,152 @@
+package mapper
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testTenantFoo struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID string
+	Name     string
+}
+
+type _testTenant struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper BaseMapper[_testTenantFoo]
+}
+
+func (t *_testTenant) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testTenantFoo{}))
+	t.mapper = NewBaseMapper[_testTenantFoo](t.db, WithTenantStrategy(ColumnTenant{Column: "tenant_id"}))
+	t.Require().NoError(t.mapper.Create(tenant.WithTenant(context.TODO(), "a"), &_testTenantFoo{Name: "a1"}))
+	t.Require().NoError(t.mapper.Create(tenant.WithTenant(context.TODO(), "b"), &_testTenantFoo{Name: "b1", TenantID: "a"}))
+}
+
+func (t *_testTenant) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testTenant) Test_RequireTenant() {
+	ctx := context.TODO()
+	_, err := t.mapper.All(ctx, EmptyWrapperFunc)
+	t.ErrorIs(err, tenant.ErrTenantRequired)
+	t.ErrorIs(t.mapper.Create(ctx, &_testTenantFoo{}), tenant.ErrTenantRequired)
+	t.ErrorIs(t.mapper.DeleteById(ctx, 1), tenant.ErrTenantRequired)
+	t.ErrorIs(t.mapper.Transaction(ctx, func(context.Context) error { return nil }), tenant.ErrTenantRequired)
+}
+
+func (t *_testTenant) Test_AssignOnCreate() {
+	var foo _testTenantFoo
+	t.NoError(t.db.First(&foo, 2).Error)
+	t.EqualValues("b", foo.TenantID)
+}
+
+func (t *_testTenant) Test_IsolateReads() {
+	ctx := tenant.WithTenant(context.TODO(), "a")
+	res, err := t.mapper.All(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("a1", res[0].Name)
+	_, err = t.mapper.OneById(ctx, 2)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+	count, err := t.mapper.Count(ctx, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, count)
+	page, err := NewCRUDMapper[_testTenantFoo](t.db, WithTenantStrategy(ColumnTenant{Column: "tenant_id"})).
+		Paginate(ctx, CRUDPaginator{Page: 1, PageSize: 10}, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(page.Data, 1)
+}
+
+func (t *_testTenant) Test_IsolateWrites() {
+	ctx := tenant.WithTenant(context.TODO(), "a")
+	t.ErrorIs(t.mapper.UpdateById(ctx, 2, map[string]interface{}{"name": "x"}), gorm.ErrRecordNotFound)
+	t.ErrorIs(t.mapper.DeleteById(ctx, 2), gorm.ErrRecordNotFound)
+	t.NoError(t.mapper.UpdateById(ctx, 1, map[string]interface{}{"name": "x", "tenant_id": "b"}))
+	var foo _testTenantFoo
+	t.NoError(t.db.First(&foo, 1).Error)
+	t.EqualValues("a", foo.TenantID)
+	t.EqualValues("x", foo.Name)
+}
+
+func (t *_testTenant) Test_DatabaseTenant() {
+	dbs := map[string]*gorm.DB{}
+	for _, name := range []string{"a", "b"} {
+		db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+		t.Require().NoError(err)
+		t.Require().NoError(db.AutoMigrate(&_testTenantFoo{}))
+		dbs[name] = db
+	}
+	m := NewBaseMapper[_testTenantFoo](t.db, WithTenantStrategy(DatabaseTenant{Resolve: func(tenant string) (*gorm.DB, error) {
+		return dbs[tenant], nil
+	}}))
+	ctxA := tenant.WithTenant(context.TODO(), "a")
+	ctxB := tenant.WithTenant(context.TODO(), "b")
+	t.NoError(m.Transaction(ctxA, func(ctx context.Context) error {
+		return m.Create(ctx, &_testTenantFoo{Name: "a"})
+	}))
+	count, err := m.Count(ctxA, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, count)
+	count, err = m.Count(ctxB, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(0, count)
+}
+
+func (t *_testTenant) Test_SchemaTenant() {
+	dir := t.T().TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "main.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	db = db.Debug()
+	sqlDB, err := db.DB()
+	t.Require().NoError(err)
+	defer sqlDB.Close()
+	// the attached databases are the schemas of the connection
+	sqlDB.SetMaxOpenConns(1)
+	for _, name := range []string{"a", "b"} {
+		t.Require().NoError(db.Exec("ATTACH DATABASE ? AS tenant_"+name, filepath.Join(dir, name+".db")).Error)
+		t.Require().NoError(db.Exec("CREATE TABLE tenant_" + name + "._test_tenant_foos (id integer primary key, tenant_id text, name text)").Error)
+	}
+	m := NewBaseMapper[_testTenantFoo](db, WithTenantStrategy(SchemaTenant{Format: "tenant_%s"}))
+	ctxA := tenant.WithTenant(context.TODO(), "a")
+	t.NoError(m.Create(ctxA, &_testTenantFoo{Name: "a1"}))
+	count, err := m.Count(ctxA, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, count)
+	ctxB := tenant.WithTenant(context.TODO(), "b")
+	count, err = m.Count(ctxB, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(0, count)
+	t.NoError(m.Create(ctxB, &_testTenantFoo{Name: "b1"}))
+	res, err := m.All(ctxB, EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(res, 1)
+	t.EqualValues("b1", res[0].Name)
+
+	hostile := tenant.WithTenant(context.TODO(), "a._test_tenant_foos; DROP TABLE tenant_a._test_tenant_foos; --")
+	_, err = m.All(hostile, EmptyWrapperFunc)
+	t.ErrorIs(err, ErrInvalidTenant)
+	t.ErrorIs(m.Create(hostile, &_testTenantFoo{Name: "x"}), ErrInvalidTenant)
+	t.ErrorIs(m.DeleteById(hostile, 1), ErrInvalidTenant)
+	count, err = m.Count(ctxA, EmptyWrapperFunc)
+	t.NoError(err)
+	t.EqualValues(1, count)
+}
+
+func TestTenant(t *testing.T) {
+	suite.Run(t, &_testTenant{})
+}
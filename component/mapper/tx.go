@@ -0,0 +1,132 @@
+package mapper
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// txs the transactions carried in a ctx, the last one carried first,
+// lazy is the transaction of a sharded mapper, it is nil until begun
+type txs struct {
+	tx     *gorm.DB
+	lazy   *lazyTx
+	parent *txs
+}
+
+// current the transaction of the node, nil for a lazy transaction not begun yet
+func (t *txs) current() *gorm.DB {
+	if t.lazy != nil {
+		return t.lazy.tx
+	}
+	return t.tx
+}
+
+// WithTx carry the transaction tx in ctx, the mappers of the database tx is begun on run inside tx when called with the ctx,
+// the mappers of the other databases do not join it
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	parent, _ := ctx.Value(txKey{}).(*txs)
+	return context.WithValue(ctx, txKey{}, &txs{tx: tx, parent: parent})
+}
+
+// withLazyTx carry the lazy transaction of a sharded mapper in ctx
+func withLazyTx(ctx context.Context, lazy *lazyTx) context.Context {
+	parent, _ := ctx.Value(txKey{}).(*txs)
+	ctx = context.WithValue(ctx, lazyTxKey{}, lazy)
+	return context.WithValue(ctx, txKey{}, &txs{lazy: lazy, parent: parent})
+}
+
+// TxFromContext the transaction carried last in ctx, i.e. the transaction of the innermost Transaction of a mapper,
+// false when it is the transaction of a sharded mapper not begun yet
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	if carried, ok := ctx.Value(txKey{}).(*txs); ok {
+		tx := carried.current()
+		return tx, tx != nil
+	}
+	return nil, false
+}
+
+// InTransaction whether ctx carries a transaction, including the transaction of a sharded mapper not begun yet
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txs)
+	return ok
+}
+
+// txOf the transaction carried in ctx which is begun on the database of db
+func txOf(ctx context.Context, db *gorm.DB) (*gorm.DB, bool) {
+	carried, _ := ctx.Value(txKey{}).(*txs)
+	for ; carried != nil; carried = carried.parent {
+		if tx := carried.current(); tx != nil && pool(tx) == pool(db) {
+			return tx, true
+		}
+	}
+	return nil, false
+}
+
+// pool the connection pool of the database of db, it is shared by the sessions and the transactions of db
+func pool(db *gorm.DB) gorm.ConnPool {
+	if prepared, ok := db.Config.ConnPool.(*gorm.PreparedStmtDB); ok {
+		return prepared.ConnPool
+	}
+	return db.Config.ConnPool
+}
+
+type afterCommitKey struct{}
+
+// afterCommit the functions to run after the transaction commits
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func withAfterCommit(ctx context.Context) (context.Context, *afterCommit) {
+	hooks := &afterCommit{}
+	return context.WithValue(ctx, afterCommitKey{}, hooks), hooks
+}
+
+func (h *afterCommit) run() {
+	for _, fn := range h.fns {
+		fn()
+	}
+}
+
+// AfterCommit run fn after the transaction begun by Transaction in ctx commits, it is dropped on rollback.
+// fn runs at once when ctx has no such transaction
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommit)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// Transaction run fn in a transaction of db, fn joins the transaction of db already carried in ctx,
+// the transactions of the other databases in ctx are not joined but kept for their mappers.
+// The transaction of db is the one carried last in the ctx of fn, see TxFromContext
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if tx, ok := txOf(ctx, db); ok {
+		return fn(WithTx(ctx, tx))
+	}
+	ctx, hooks := withAfterCommit(ctx)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(WithTx(ctx, tx))
+	})
+	if err == nil {
+		hooks.run()
+	}
+	return err
+}
+
+// conn the transaction of db in ctx or db
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := txOf(ctx, db); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ErrUsage invalid arguments of Command
+var ErrUsage = errors.New("usage: [-dry-run] up [version] | down [steps] | status")
+
+// Command run the command of args, e.g. of a migrate subcommand of the application, and write its output to w:
+//
+//	up [version]   apply the pending migrations, up to version when given
+//	down [steps]   roll back the last steps applied migrations, 1 by default
+//	status         list the migrations and their state
+//
+// The -dry-run flag writes the SQL of up and down instead of executing it, see Migrator.DryRun
+func Command(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "write the SQL instead of executing it")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 || fs.NArg() > 2 {
+		return ErrUsage
+	}
+	if *dryRun {
+		m = m.DryRun(w)
+	}
+	var n int64
+	if fs.NArg() == 2 {
+		var err error
+		if n, err = strconv.ParseInt(fs.Arg(1), 10, 64); err != nil || n < 0 {
+			return ErrUsage
+		}
+	}
+	switch fs.Arg(0) {
+	case "up":
+		done, err := m.UpTo(ctx, n)
+		if !*dryRun {
+			report(w, "applied", done)
+		}
+		return err
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		done, err := m.Down(ctx, int(n))
+		if !*dryRun {
+			report(w, "rolled back", done)
+		}
+		return err
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			at := "pending"
+			if state.Applied {
+				at = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", state.Version, state.Name, at)
+		}
+		return tw.Flush()
+	}
+	return ErrUsage
+}
+
+func report(w io.Writer, action string, migrations []Migration) {
+	if len(migrations) == 0 {
+		fmt.Fprintf(w, "nothing %s\n", action)
+	}
+	for _, migration := range migrations {
+		fmt.Fprintf(w, "%s %d %s\n", action, migration.Version, migration.Name)
+	}
+}
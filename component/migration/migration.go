@@ -0,0 +1,323 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLocked the migrations are run by another process
+	ErrLocked = errors.New("migrations are locked")
+	// ErrIrreversible the migration to roll back has no Down
+	ErrIrreversible = errors.New("irreversible migration")
+	// ErrUnknownVersion the applied version is not registered
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrDuplicateVersion the version is registered twice
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+)
+
+// DefaultLockTimeout a lock older than it is stale and taken over, e.g. the process holding it crashed
+const DefaultLockTimeout = 10 * time.Minute
+
+// Migration versioned migration, Up and Down run in a transaction with the write of the history
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	// Down roll back Up, optional
+	Down func(tx *gorm.DB) error
+}
+
+// History applied migration
+type History struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (History) TableName() string {
+	return "schema_migrations"
+}
+
+// Lock the row held while migrating, its primary key makes the concurrent runs fail
+type Lock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (Lock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// State of a registered migration
+type State struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Option func(*Migrator)
+
+// WithLockTimeout the age of a stale lock, DefaultLockTimeout by default,
+// the lock held by a run is refreshed every third of it. A timeout <= 0 never takes a lock over,
+// the lock of a crashed run is then to be deleted by hand
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// Migrator run the registered migrations in the version order
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+	dryRun      io.Writer
+	now         func() time.Time
+}
+
+func New(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{db: db, lockTimeout: DefaultLockTimeout, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add register the migrations, it panics when a version is already registered
+func (m *Migrator) Add(migrations ...Migration) *Migrator {
+	for _, migration := range migrations {
+		if _, ok := m.find(migration.Version); ok {
+			panic(fmt.Sprintf("migration: %d: %s", migration.Version, ErrDuplicateVersion))
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	return m
+}
+
+// DryRun a Migrator which writes the SQL of the migrations to w instead of executing them,
+// it neither locks nor writes the history.
+// The Go migrations are run by a gorm session in dry run mode, so they are not to read the results of their queries
+func (m *Migrator) DryRun(w io.Writer) *Migrator {
+	dry := *m
+	dry.dryRun = w
+	return &dry
+}
+
+// Migrations the registered migrations in the version order
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// Status the state of every registered migration
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]State, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		h, ok := applied[migration.Version]
+		res = append(res, State{Migration: migration, Applied: ok, AppliedAt: h.AppliedAt})
+	}
+	return res, nil
+}
+
+// Up apply the pending migrations, see UpTo
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo apply the pending migrations whose version is not greater than version in the version order, 0 means all,
+// it stops at the first failure and returns the applied ones
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if version > 0 && migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, migration, "up", migration.Up, func(tx *gorm.DB) error {
+				return tx.Create(&History{Version: migration.Version, Name: migration.Name, AppliedAt: m.now()}).Error
+			}); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down roll back the last steps applied migrations in the reverse version order,
+// it stops at the first failure and returns the rolled back ones
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		for i := 0; i < steps && i < len(versions); i++ {
+			migration, ok := m.find(versions[i])
+			if !ok {
+				return fmt.Errorf("migration: %d: %w", versions[i], ErrUnknownVersion)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration: %d %s: %w", migration.Version, migration.Name, ErrIrreversible)
+			}
+			if err := m.run(ctx, migration, "down", migration.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&History{}, migration.Version).Error
+			}); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// run fn of the migration and record the history in a transaction
+func (m *Migrator) run(ctx context.Context, migration Migration, direction string, fn, record func(tx *gorm.DB) error) error {
+	if m.dryRun != nil {
+		if _, err := fmt.Fprintf(m.dryRun, "-- %d %s %s\n", migration.Version, migration.Name, direction); err != nil {
+			return err
+		}
+		return fn(m.db.Session(&gorm.Session{DryRun: true, Logger: &sqlWriter{w: m.dryRun}, NewDB: true, Context: ctx}))
+	}
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("migration: %d %s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	return nil
+}
+
+// applied the applied migrations by version
+func (m *Migrator) applied(ctx context.Context) (map[int64]History, error) {
+	db := m.db.WithContext(ctx)
+	res := make(map[int64]History)
+	if !db.Migrator().HasTable(&History{}) {
+		if m.dryRun != nil {
+			return res, nil
+		}
+		if err := db.AutoMigrate(&History{}); err != nil {
+			return nil, err
+		}
+	}
+	histories := make([]History, 0)
+	if err := db.Find(&histories).Error; err != nil {
+		return nil, err
+	}
+	for _, h := range histories {
+		res[h.Version] = h
+	}
+	return res, nil
+}
+
+// locked run fn holding the lock, the dry run does not lock.
+// The lock is refreshed while fn runs, so a long migration is not taken for a stale one,
+// and it is released even if ctx is cancelled
+func (m *Migrator) locked(ctx context.Context, fn func() error) (err error) {
+	if m.dryRun != nil {
+		return fn()
+	}
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&Lock{}); err != nil {
+		return err
+	}
+	if m.lockTimeout > 0 {
+		if err := db.Where("id = ? AND locked_at < ?", 1, m.now().Add(-m.lockTimeout)).Delete(&Lock{}).Error; err != nil {
+			return err
+		}
+	}
+	lock := Lock{ID: 1, Owner: owner(), LockedAt: m.now()}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		var holder Lock
+		if err := db.First(&holder, 1).Error; err != nil {
+			return ErrLocked
+		}
+		return fmt.Errorf("%w by %s since %s", ErrLocked, holder.Owner, holder.LockedAt.Format(time.RFC3339))
+	}
+	release := context.WithoutCancel(ctx)
+	stop := m.heartbeat(release, lock.Owner)
+	defer func() {
+		stop()
+		if e := m.db.WithContext(release).Where("owner = ?", lock.Owner).Delete(&Lock{}, 1).Error; e != nil {
+			err = errors.Join(err, fmt.Errorf("migration: release the lock: %w", e))
+		}
+	}()
+	return fn()
+}
+
+// heartbeat refresh locked_at of the lock of owner every third of the lock timeout until stop is called,
+// a failed refresh is retried by the next one
+func (m *Migrator) heartbeat(ctx context.Context, owner string) (stop func()) {
+	if m.lockTimeout <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.lockTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.db.WithContext(ctx).Model(&Lock{}).Where("id = ? AND owner = ?", 1, owner).Update("locked_at", m.now())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func owner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
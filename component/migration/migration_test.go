@@ -0,0 +1,254 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testUser struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+var migrations = fstest.MapFS{
+	"migrations/0002_add_posts.up.sql": {Data: []byte(`-- posts of the users
+CREATE TABLE posts (
+	id integer PRIMARY KEY,
+	title text
+);
+CREATE INDEX idx_posts_title ON posts(title);
+`)},
+	"migrations/0002_add_posts.down.sql": {Data: []byte("DROP TABLE posts;\n")},
+	"migrations/0003_seed.up.sql":        {Data: []byte("INSERT INTO posts (title) VALUES ('a;b');\n")},
+	"migrations/README.md":               {Data: []byte("not a migration")},
+}
+
+type _testMigration struct {
+	suite.Suite
+	db       *gorm.DB
+	migrator *Migrator
+}
+
+func (t *_testMigration) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.migrator = New(t.db).Add(CreateTables(1, "create_users", &_testUser{}))
+	t.Require().NoError(t.migrator.AddFS(migrations, "migrations"))
+}
+
+func (t *_testMigration) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func versions(migrations []Migration) []int64 {
+	res := make([]int64, 0, len(migrations))
+	for _, m := range migrations {
+		res = append(res, m.Version)
+	}
+	return res
+}
+
+func (t *_testMigration) Test_Statements() {
+	t.Equal([]string{"CREATE TABLE a (\n\tid int\n)", "INSERT INTO a VALUES (1)", "SELECT 1"},
+		Statements("-- comment\nCREATE TABLE a (\n\tid int\n);\n\nINSERT INTO a VALUES (1);\nSELECT 1"))
+
+	trigger := "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n\tUPDATE a SET id = 2;\n\t-- once\n\tUPDATE a SET id = 3;\nEND"
+	t.Equal([]string{"SELECT 1", trigger, "SELECT 2"},
+		Statements("SELECT 1;\n"+StatementBegin+"\n"+trigger+";\n"+StatementEnd+"\nSELECT 2;\n"))
+}
+
+func (t *_testMigration) Test_UpDown() {
+	ctx := context.TODO()
+	done, err := t.migrator.UpTo(ctx, 2)
+	t.Require().NoError(err)
+	t.Equal([]int64{1, 2}, versions(done))
+	t.True(t.db.Migrator().HasTable("posts"))
+	t.True(t.db.Migrator().HasIndex("posts", "idx_posts_title"))
+
+	done, err = t.migrator.Up(ctx)
+	t.Require().NoError(err)
+	t.Equal([]int64{3}, versions(done))
+	var title string
+	t.NoError(t.db.Raw("SELECT title FROM posts").Scan(&title).Error)
+	t.Equal("a;b", title)
+	done, err = t.migrator.Up(ctx)
+	t.NoError(err)
+	t.Empty(done)
+
+	states, err := t.migrator.Status(ctx)
+	t.Require().NoError(err)
+	t.Len(states, 3)
+	for _, state := range states {
+		t.True(state.Applied)
+		t.NotZero(state.AppliedAt)
+	}
+
+	_, err = t.migrator.Down(ctx, 1)
+	t.ErrorIs(err, ErrIrreversible)
+	t.Require().NoError(t.db.Delete(&History{}, 3).Error)
+	done, err = t.migrator.Down(ctx, 5)
+	t.Require().NoError(err)
+	t.Equal([]int64{2, 1}, versions(done))
+	t.False(t.db.Migrator().HasTable("posts"))
+	t.False(t.db.Migrator().HasTable(&_testUser{}))
+	var count int64
+	t.NoError(t.db.Model(&History{}).Count(&count).Error)
+	t.Zero(count)
+}
+
+func (t *_testMigration) Test_Failure() {
+	ctx := context.TODO()
+	t.migrator.Add(Migration{Version: 4, Name: "fail", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO posts (title) VALUES ('c')").Error; err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+	done, err := t.migrator.Up(ctx)
+	t.ErrorContains(err, "4 fail up: boom")
+	t.Equal([]int64{1, 2, 3}, versions(done))
+	var count int64
+	t.NoError(t.db.Table("posts").Count(&count).Error)
+	t.EqualValues(1, count)
+	states, err := t.migrator.Status(ctx)
+	t.Require().NoError(err)
+	t.False(states[3].Applied)
+	t.Panics(func() {
+		t.migrator.Add(Migration{Version: 4})
+	})
+}
+
+func (t *_testMigration) Test_Lock() {
+	ctx := context.TODO()
+	t.Require().NoError(t.db.AutoMigrate(&Lock{}))
+	t.Require().NoError(t.db.Create(&Lock{ID: 1, Owner: "other", LockedAt: time.Now()}).Error)
+	_, err := t.migrator.Up(ctx)
+	t.ErrorIs(err, ErrLocked)
+	t.ErrorContains(err, "other")
+	t.False(t.db.Migrator().HasTable(&_testUser{}))
+
+	t.Require().NoError(t.db.Model(&Lock{}).Where("id = 1").Update("locked_at", time.Now().Add(-time.Hour)).Error)
+	done, err := t.migrator.Up(ctx)
+	t.NoError(err)
+	t.Len(done, 3)
+	var count int64
+	t.NoError(t.db.Model(&Lock{}).Count(&count).Error)
+	t.Zero(count)
+}
+
+func (t *_testMigration) Test_Lock_NoTimeout() {
+	ctx := context.TODO()
+	t.Require().NoError(t.db.AutoMigrate(&Lock{}))
+	t.Require().NoError(t.db.Create(&Lock{ID: 1, Owner: "other", LockedAt: time.Now().Add(-time.Hour)}).Error)
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		migrator := New(t.db, WithLockTimeout(timeout)).Add(Migration{Version: 1, Name: "noop", Up: func(tx *gorm.DB) error {
+			return nil
+		}})
+		_, err := migrator.Up(ctx)
+		t.ErrorIs(err, ErrLocked, "the lock is never taken over with the timeout %s", timeout)
+	}
+	var count int64
+	t.NoError(t.db.Model(&Lock{}).Count(&count).Error)
+	t.EqualValues(1, count)
+}
+
+func (t *_testMigration) Test_LockReleasedOnCancel() {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	t.migrator.Add(Migration{Version: 4, Name: "cancel", Up: func(tx *gorm.DB) error {
+		cancel()
+		return ctx.Err()
+	}})
+	_, err := t.migrator.Up(ctx)
+	t.ErrorIs(err, context.Canceled)
+	var count int64
+	t.NoError(t.db.Model(&Lock{}).Count(&count).Error)
+	t.Zero(count)
+}
+
+func (t *_testMigration) Test_Heartbeat() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.T().TempDir(), "heartbeat.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	defer func() {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}()
+	timeout := 60 * time.Millisecond
+	running, finish := make(chan struct{}), make(chan struct{})
+	migrator := New(db, WithLockTimeout(timeout)).Add(Migration{Version: 1, Name: "long", Up: func(tx *gorm.DB) error {
+		close(running)
+		<-finish
+		return nil
+	}})
+	result := make(chan error)
+	go func() {
+		_, err := migrator.Up(context.TODO())
+		result <- err
+	}()
+	<-running
+	var lock Lock
+	t.Require().NoError(db.First(&lock, 1).Error)
+	t.Eventually(func() bool {
+		var current Lock
+		return db.First(&current, 1).Error == nil && current.LockedAt.After(lock.LockedAt)
+	}, time.Second, timeout/6, "the lock is refreshed")
+	t.Eventually(func() bool {
+		_, err := New(db, WithLockTimeout(timeout)).Up(context.TODO())
+		return time.Since(lock.LockedAt) > 2*timeout && errors.Is(err, ErrLocked)
+	}, time.Second, timeout/6, "the refreshed lock is not taken over")
+	close(finish)
+	t.NoError(<-result)
+	var count int64
+	t.NoError(db.Model(&Lock{}).Count(&count).Error)
+	t.Zero(count)
+}
+
+func (t *_testMigration) Test_DryRun() {
+	ctx := context.TODO()
+	var buf bytes.Buffer
+	done, err := t.migrator.DryRun(&buf).Up(ctx)
+	t.Require().NoError(err)
+	t.Len(done, 3)
+	t.Contains(buf.String(), "-- 1 create_users up\nCREATE TABLE `_test_users`")
+	t.Contains(buf.String(), "-- 2 add_posts up\nCREATE TABLE posts (")
+	t.Contains(buf.String(), "INSERT INTO posts (title) VALUES ('a;b');\n")
+	t.False(t.db.Migrator().HasTable(&_testUser{}))
+	t.False(t.db.Migrator().HasTable(&History{}))
+}
+
+func (t *_testMigration) Test_Command() {
+	ctx := context.TODO()
+	var buf bytes.Buffer
+	t.ErrorIs(Command(ctx, t.migrator, nil, &buf), ErrUsage)
+	t.ErrorIs(Command(ctx, t.migrator, []string{"sideways"}, &buf), ErrUsage)
+	t.NoError(Command(ctx, t.migrator, []string{"up", "1"}, &buf))
+	t.Equal("applied 1 create_users\n", buf.String())
+	buf.Reset()
+	t.NoError(Command(ctx, t.migrator, []string{"status"}, &buf))
+	t.Contains(buf.String(), "2        add_posts     pending")
+	buf.Reset()
+	t.NoError(Command(ctx, t.migrator, []string{"-dry-run", "down"}, &buf))
+	t.Contains(buf.String(), "-- 1 create_users down\n")
+	t.Contains(buf.String(), "DROP TABLE IF EXISTS `_test_users`;\n")
+	buf.Reset()
+	t.NoError(Command(ctx, t.migrator, []string{"down"}, &buf))
+	t.Equal("rolled back 1 create_users\n", buf.String())
+}
+
+func TestMigration(t *testing.T) {
+	suite.Run(t, &_testMigration{})
+}
@@ -0,0 +1,176 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// SQL migration of the SQL scripts, down is optional, see Statements
+func SQL(version int64, name, up, down string) Migration {
+	migration := Migration{Version: version, Name: name, Up: execFunc(up)}
+	if strings.TrimSpace(down) != "" {
+		migration.Down = execFunc(down)
+	}
+	return migration
+}
+
+func execFunc(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range Statements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StatementBegin and StatementEnd the comment lines wrapping a statement which is kept whole by Statements
+const (
+	StatementBegin = "-- +migrate StatementBegin"
+	StatementEnd   = "-- +migrate StatementEnd"
+)
+
+// Statements split the script into statements, a statement ends by a line ending with ;
+// and the lines starting with -- are comments.
+// The script is not parsed, so a statement with ; at the end of its inner lines, e.g. CREATE TRIGGER ... BEGIN ...; END;
+// or the body of a function, is to be wrapped between the lines StatementBegin and StatementEnd
+func Statements(script string) []string {
+	res := make([]string, 0)
+	var stmt strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			res = append(res, strings.TrimSuffix(s, ";"))
+		}
+		stmt.Reset()
+	}
+	whole := false
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == StatementBegin:
+			flush()
+			whole = true
+			continue
+		case trimmed == StatementEnd:
+			flush()
+			whole = false
+			continue
+		case whole:
+		case strings.HasPrefix(trimmed, "--"):
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if !whole && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return res
+}
+
+// FromFS the SQL migrations of the files <version>_<name>.up.sql and <version>_<name>.down.sql in dir of fsys,
+// e.g. of an embed.FS
+func FromFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	scripts := make(map[int64]map[string]string)
+	names := make(map[int64]string)
+	versions := make([]int64, 0)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration: %s: %w", entry.Name(), err)
+		}
+		if name, ok := names[version]; ok && name != match[2] {
+			return nil, fmt.Errorf("migration: %d: %w: %s and %s", version, ErrDuplicateVersion, name, match[2])
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := scripts[version]; !ok {
+			scripts[version] = make(map[string]string)
+			versions = append(versions, version)
+		}
+		names[version] = match[2]
+		scripts[version][match[3]] = string(b)
+	}
+	res := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		if _, ok := scripts[version]["up"]; !ok {
+			return nil, fmt.Errorf("migration: %d %s has no up script", version, names[version])
+		}
+		res = append(res, SQL(version, names[version], scripts[version]["up"], scripts[version]["down"]))
+	}
+	return res, nil
+}
+
+// AddFS register the SQL migrations of dir of fsys, see FromFS
+func (m *Migrator) AddFS(fsys fs.FS, dir string) error {
+	migrations, err := FromFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+	m.Add(migrations...)
+	return nil
+}
+
+// CreateTables migration creating the tables of the models, e.g. the models of the mappers,
+// Down drops them in the reverse order
+func CreateTables(version int64, name string, models ...interface{}) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(models...)
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(models) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(models[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// sqlWriter logger writing the SQL of the dry run
+type sqlWriter struct {
+	w io.Writer
+}
+
+func (s *sqlWriter) LogMode(logger.LogLevel) logger.Interface {
+	return s
+}
+
+func (s *sqlWriter) Info(context.Context, string, ...interface{}) {}
+
+func (s *sqlWriter) Warn(context.Context, string, ...interface{}) {}
+
+func (s *sqlWriter) Error(context.Context, string, ...interface{}) {}
+
+func (s *sqlWriter) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	fmt.Fprintf(s.w, "%s;\n", sql)
+}
@@ -0,0 +1,91 @@
+package observe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// Handler observe the handler of the operation of the resource, the requests answered 5xx are failed,
+// the request id is carried in the context like Middleware does
+func (o *Observer) Handler(resource, operation string) web.Middleware {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			requestID(ctx)
+			sc := &statusContext{ctx: ctx}
+			_ = o.observe(ctx.Context(), LayerHTTP, resource, operation, func(c context.Context) (int, error) {
+				sc.SetContext(c)
+				next(sc)
+				if sc.status() >= http.StatusInternalServerError {
+					return -1, fmt.Errorf("%s %s: %d %s", ctx.Request().Method, ctx.Request().URL.Path, sc.status(), http.StatusText(sc.status()))
+				}
+				return -1, nil
+			})
+		}
+	}
+}
+
+// statusContext record the status code of the response
+type statusContext struct {
+	ctx  web.Context
+	code int
+}
+
+func (s *statusContext) Context() context.Context {
+	return s.ctx.Context()
+}
+
+func (s *statusContext) SetContext(ctx context.Context) {
+	s.ctx.SetContext(ctx)
+}
+
+func (s *statusContext) Request() *http.Request {
+	return s.ctx.Request()
+}
+
+func (s *statusContext) Param(name string) string {
+	return s.ctx.Param(name)
+}
+
+func (s *statusContext) Query(name string) string {
+	return s.ctx.Query(name)
+}
+
+func (s *statusContext) Header(name string) string {
+	return s.ctx.Header(name)
+}
+
+func (s *statusContext) SetHeader(name, value string) {
+	s.ctx.SetHeader(name, value)
+}
+
+func (s *statusContext) BindJSON(v interface{}) error {
+	return s.ctx.BindJSON(v)
+}
+
+func (s *statusContext) BindQuery(v interface{}) error {
+	return s.ctx.BindQuery(v)
+}
+
+func (s *statusContext) JSON(code int, v interface{}) {
+	s.code = code
+	s.ctx.JSON(code, v)
+}
+
+func (s *statusContext) Status(code int) {
+	s.code = code
+	s.ctx.Status(code)
+}
+
+func (s *statusContext) Written() bool {
+	return s.ctx.Written()
+}
+
+func (s *statusContext) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
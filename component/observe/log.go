@@ -0,0 +1,92 @@
+package observe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// RequestIDHeader header of the request id, it is kept when the client sends it and echoed in the response
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// WithRequestID carry the request id in ctx
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext the request id carried in ctx
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// Middleware carry the request id of the X-Request-Id header, or a new one, in the context of every request
+func Middleware() web.Middleware {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			requestID(ctx)
+			next(ctx)
+		}
+	}
+}
+
+// requestID the request id of ctx, it is carried in ctx and set to the response on the first call
+func requestID(ctx web.Context) string {
+	if id, ok := RequestIDFromContext(ctx.Context()); ok {
+		return id
+	}
+	id := ctx.Header(RequestIDHeader)
+	if id == "" {
+		b := make([]byte, 8)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	ctx.SetContext(WithRequestID(ctx.Context(), id))
+	ctx.SetHeader(RequestIDHeader, id)
+	return id
+}
+
+// LogHandler tag the records of h with the request id of their context, e.g.
+//
+//	slog.SetDefault(slog.New(observe.LogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+func LogHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(requestIDHandler); ok {
+		return h
+	}
+	return requestIDHandler{Handler: h}
+}
+
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// discardHandler handler dropping the records, the default one
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool { return false }
+
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler { return d }
+
+func (d discardHandler) WithGroup(string) slog.Handler { return d }
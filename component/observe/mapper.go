@@ -0,0 +1,169 @@
+package observe
+
+import (
+	"context"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"gorm.io/gorm"
+)
+
+// NewMapper observing decorator of m, its operations are observed as the layer mapper of resource
+func NewMapper[T any](m mapper.BaseMapper[T], resource string, o *Observer) mapper.BaseMapper[T] {
+	return &observedMapper[T]{mapper: m, resource: resource, observer: o}
+}
+
+type observedMapper[T any] struct {
+	mapper   mapper.BaseMapper[T]
+	resource string
+	observer *Observer
+}
+
+// call observe the operation fn of layer, rows counts the rows of its result, nil when they are unknown
+func call[R any](ctx context.Context, o *Observer, layer, resource, operation string, fn func(ctx context.Context) (R, error), rows func(R) int) (R, error) {
+	var res R
+	err := o.observe(ctx, layer, resource, operation, func(ctx context.Context) (int, error) {
+		var err error
+		res, err = fn(ctx)
+		if err != nil || rows == nil {
+			return -1, err
+		}
+		return rows(res), nil
+	})
+	return res, err
+}
+
+func (m *observedMapper[T]) exec(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	return m.observer.observe(ctx, LayerMapper, m.resource, operation, func(ctx context.Context) (int, error) {
+		return -1, fn(ctx)
+	})
+}
+
+func one[T any](*T) int {
+	return 1
+}
+
+func length[T any](res []T) int {
+	return len(res)
+}
+
+func (m *observedMapper[T]) One(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*T, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "one", func(ctx context.Context) (*T, error) {
+		return m.mapper.One(ctx, wrapper)
+	}, one[T])
+}
+
+func (m *observedMapper[T]) OneById(ctx context.Context, id uint) (*T, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "one_by_id", func(ctx context.Context) (*T, error) {
+		return m.mapper.OneById(ctx, id)
+	}, one[T])
+}
+
+func (m *observedMapper[T]) All(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) ([]T, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "all", func(ctx context.Context) ([]T, error) {
+		return m.mapper.All(ctx, wrapper)
+	}, length[T])
+}
+
+func (m *observedMapper[T]) Paginate(ctx context.Context, pager mapper.Paginator, wrapper func(*gorm.DB) *gorm.DB) (*mapper.PageRes[T], error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "paginate", func(ctx context.Context) (*mapper.PageRes[T], error) {
+		return m.mapper.Paginate(ctx, pager, wrapper)
+	}, func(res *mapper.PageRes[T]) int { return len(res.Data) })
+}
+
+func (m *observedMapper[T]) Count(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (int, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "count", func(ctx context.Context) (int, error) {
+		return m.mapper.Count(ctx, wrapper)
+	}, nil)
+}
+
+func (m *observedMapper[T]) Aggregate(ctx context.Context, aggregation mapper.Aggregation, wrapper func(*gorm.DB) *gorm.DB) ([]mapper.AggregateRow, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "aggregate", func(ctx context.Context) ([]mapper.AggregateRow, error) {
+		return m.mapper.Aggregate(ctx, aggregation, wrapper)
+	}, length[mapper.AggregateRow])
+}
+
+func (m *observedMapper[T]) Sum(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "sum", func(ctx context.Context) (float64, error) {
+		return m.mapper.Sum(ctx, column, wrapper)
+	}, nil)
+}
+
+func (m *observedMapper[T]) Avg(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "avg", func(ctx context.Context) (float64, error) {
+		return m.mapper.Avg(ctx, column, wrapper)
+	}, nil)
+}
+
+func (m *observedMapper[T]) Min(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "min", func(ctx context.Context) (float64, error) {
+		return m.mapper.Min(ctx, column, wrapper)
+	}, nil)
+}
+
+func (m *observedMapper[T]) Max(ctx context.Context, column string, wrapper func(*gorm.DB) *gorm.DB) (float64, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "max", func(ctx context.Context) (float64, error) {
+		return m.mapper.Max(ctx, column, wrapper)
+	}, nil)
+}
+
+func (m *observedMapper[T]) Search(q string) func(*gorm.DB) *gorm.DB {
+	return mapper.SearchWrapperFunc(m.mapper, q)
+}
+
+func (m *observedMapper[T]) Fields(fields ...string) (*mapper.FieldSet, error) {
+	return m.mapper.Fields(fields...)
+}
+
+func (m *observedMapper[T]) Delete(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) error {
+	return m.exec(ctx, "delete", func(ctx context.Context) error {
+		return m.mapper.Delete(ctx, wrapper)
+	})
+}
+
+func (m *observedMapper[T]) DeleteById(ctx context.Context, id uint) error {
+	return m.exec(ctx, "delete_by_id", func(ctx context.Context) error {
+		return m.mapper.DeleteById(ctx, id)
+	})
+}
+
+func (m *observedMapper[T]) Create(ctx context.Context, entity *T) error {
+	return m.exec(ctx, "create", func(ctx context.Context) error {
+		return m.mapper.Create(ctx, entity)
+	})
+}
+
+func (m *observedMapper[T]) Update(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB, updated map[string]interface{}) error {
+	return m.exec(ctx, "update", func(ctx context.Context) error {
+		return m.mapper.Update(ctx, wrapper, updated)
+	})
+}
+
+func (m *observedMapper[T]) UpdateById(ctx context.Context, id uint, updated map[string]interface{}) error {
+	return m.exec(ctx, "update_by_id", func(ctx context.Context) error {
+		return m.mapper.UpdateById(ctx, id, updated)
+	})
+}
+
+func (m *observedMapper[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.exec(ctx, "transaction", func(ctx context.Context) error {
+		return m.mapper.Transaction(ctx, fn)
+	})
+}
+
+func (m *observedMapper[T]) CreateBatch(ctx context.Context, entities []*T, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "create_batch", func(ctx context.Context) ([]mapper.BatchResult, error) {
+		return m.mapper.CreateBatch(ctx, entities, mode)
+	}, length[mapper.BatchResult])
+}
+
+func (m *observedMapper[T]) UpdateBatch(ctx context.Context, updates []mapper.BatchUpdate, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "update_batch", func(ctx context.Context) ([]mapper.BatchResult, error) {
+		return m.mapper.UpdateBatch(ctx, updates, mode)
+	}, length[mapper.BatchResult])
+}
+
+func (m *observedMapper[T]) DeleteBatch(ctx context.Context, ids []uint, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return call(ctx, m.observer, LayerMapper, m.resource, "delete_batch", func(ctx context.Context) ([]mapper.BatchResult, error) {
+		return m.mapper.DeleteBatch(ctx, ids, mode)
+	}, length[mapper.BatchResult])
+}
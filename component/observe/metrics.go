@@ -0,0 +1,133 @@
+package observe
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// names of the metrics
+const (
+	MetricDuration = "gestful_operation_duration_seconds"
+	MetricErrors   = "gestful_operation_errors_total"
+)
+
+// DefaultBuckets the upper bounds in seconds of the latency histogram, the Prometheus default ones
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics latency histogram and error counter of the operations by layer, resource and operation,
+// it is an http.Handler serving them in the Prometheus text format, e.g. at /metrics
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[series]*histogram
+}
+
+type series struct {
+	layer     string
+	resource  string
+	operation string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+	errors uint64
+}
+
+// NewMetrics metrics of the latency histogram of the ascending buckets, DefaultBuckets without them
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Metrics{buckets: buckets, series: make(map[series]*histogram)}
+}
+
+// Observe record an operation which took elapsed and failed or not
+func (m *Metrics) Observe(layer, resource, operation string, elapsed time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := series{layer: layer, resource: resource, operation: operation}
+	h, ok := m.series[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.series[key] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+	if failed {
+		h.errors++
+	}
+}
+
+// Count the number of the operations recorded and the failed ones
+func (m *Metrics) Count(layer, resource, operation string) (count, errors uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.series[series{layer: layer, resource: resource, operation: operation}]; ok {
+		return h.count, h.errors
+	}
+	return 0, 0
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+// Write write the metrics in the Prometheus text format
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]series, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		if a.resource != b.resource {
+			return a.resource < b.resource
+		}
+		return a.operation < b.operation
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Latency of the operations.\n# TYPE %s histogram\n", MetricDuration, MetricDuration)
+	for _, key := range keys {
+		h := m.series[key]
+		labels := key.labels()
+		for i, bound := range m.buckets {
+			fmt.Fprintf(&b, "%s_bucket{%s,le=%q} %d\n", MetricDuration, labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", MetricDuration, labels, h.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", MetricDuration, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", MetricDuration, labels, h.count)
+	}
+	fmt.Fprintf(&b, "# HELP %s Failed operations.\n# TYPE %s counter\n", MetricErrors, MetricErrors)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s{%s} %d\n", MetricErrors, key.labels(), m.series[key].errors)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (s series) labels() string {
+	return fmt.Sprintf(`layer="%s",resource="%s",operation="%s"`,
+		labelEscaper.Replace(s.layer), labelEscaper.Replace(s.resource), labelEscaper.Replace(s.operation))
+}
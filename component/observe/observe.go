@@ -0,0 +1,109 @@
+package observe
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// layers of the observed operations
+const (
+	LayerMapper     = "mapper"
+	LayerRepository = "repository"
+	LayerHTTP       = "http"
+)
+
+// attribute keys of the spans and the logs
+const (
+	AttrLayer     = "gestful.layer"
+	AttrResource  = "gestful.resource"
+	AttrOperation = "gestful.operation"
+	AttrRows      = "gestful.rows"
+)
+
+// Observer trace, measure and log the operations of the mappers, the repositories and the handlers of the resources,
+// every part is a no-op until it is configured
+type Observer struct {
+	tracer  Tracer
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+type Option func(*Observer)
+
+// WithTracer trace the operations by tracer, e.g. an adapter of an OpenTelemetry tracer
+func WithTracer(tracer Tracer) Option {
+	return func(o *Observer) {
+		o.tracer = tracer
+	}
+}
+
+// WithMetrics record the latency and the errors of the operations in metrics
+func WithMetrics(metrics *Metrics) Option {
+	return func(o *Observer) {
+		o.metrics = metrics
+	}
+}
+
+// WithLogger log the operations by logger, the records are tagged with the request id of their context,
+// the operations are logged at debug level and their failures at error level
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Observer) {
+		o.logger = slog.New(LogHandler(logger.Handler()))
+	}
+}
+
+func New(opts ...Option) *Observer {
+	o := &Observer{tracer: NopTracer{}, logger: slog.New(discardHandler{})}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Metrics the metrics of the observer, nil without WithMetrics
+func (o *Observer) Metrics() *Metrics {
+	return o.metrics
+}
+
+// observe run fn of the operation in a span, record its metrics and log it, fn returns its row count or -1
+func (o *Observer) observe(ctx context.Context, layer, resource, operation string, fn func(ctx context.Context) (int, error)) error {
+	ctx = context.WithValue(ctx, resourceKey{}, resource)
+	ctx, span := o.tracer.Start(ctx, resource+"."+operation,
+		String(AttrLayer, layer), String(AttrResource, resource), String(AttrOperation, operation))
+	defer span.End()
+	start := time.Now()
+	rows, err := fn(ctx)
+	elapsed := time.Since(start)
+	if rows >= 0 {
+		span.SetAttributes(Int(AttrRows, rows))
+	}
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	if failed {
+		span.RecordError(err)
+	}
+	if o.metrics != nil {
+		o.metrics.Observe(layer, resource, operation, elapsed, failed)
+	}
+	attrs := []slog.Attr{
+		slog.String("layer", layer),
+		slog.String("resource", resource),
+		slog.String("operation", operation),
+		slog.Duration("elapsed", elapsed),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int("rows", rows))
+	}
+	level := slog.LevelDebug
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if failed {
+		level = slog.LevelError
+	}
+	o.logger.LogAttrs(ctx, level, "gestful operation", attrs...)
+	return err
+}
@@ -0,0 +1,219 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/repository/support"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testFoo struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+// _testSpan span recorded by _testTracer
+type _testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *_testSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *_testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *_testSpan) End() {
+	s.ended = true
+}
+
+type _testTracer struct {
+	mu    sync.Mutex
+	spans []*_testSpan
+}
+
+func (t *_testTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &_testSpan{name: name, attrs: make(map[string]interface{})}
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+type _testObserve struct {
+	suite.Suite
+	db       *gorm.DB
+	tracer   *_testTracer
+	logs     *bytes.Buffer
+	observer *Observer
+	mapper   mapper.BaseMapper[_testFoo]
+}
+
+func (t *_testObserve) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	t.tracer = &_testTracer{}
+	t.logs = &bytes.Buffer{}
+	t.observer = New(
+		WithTracer(t.tracer),
+		WithMetrics(NewMetrics()),
+		WithLogger(slog.New(slog.NewJSONHandler(t.logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	t.mapper = NewMapper(mapper.NewBaseMapper[_testFoo](t.db), "foos", t.observer)
+}
+
+func (t *_testObserve) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testObserve) records() []map[string]interface{} {
+	res := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(t.logs.String()), "\n") {
+		record := make(map[string]interface{})
+		t.Require().NoError(json.Unmarshal([]byte(line), &record))
+		res = append(res, record)
+	}
+	return res
+}
+
+func (t *_testObserve) Test_Mapper() {
+	ctx := WithRequestID(context.TODO(), "req-1")
+	t.NoError(t.mapper.Create(ctx, &_testFoo{Name: "a"}))
+	t.NoError(t.mapper.Create(ctx, &_testFoo{Name: "b"}))
+	res, err := t.mapper.All(ctx, mapper.EmptyWrapperFunc)
+	t.NoError(err)
+	t.Len(res, 2)
+	_, err = t.mapper.OneById(ctx, 3)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+	_, err = t.mapper.Sum(ctx, "missing", mapper.EmptyWrapperFunc)
+	t.Error(err)
+
+	t.Len(t.tracer.spans, 5)
+	all := t.tracer.spans[2]
+	t.Equal("foos.all", all.name)
+	t.Equal(map[string]interface{}{AttrLayer: LayerMapper, AttrResource: "foos", AttrOperation: "all", AttrRows: 2}, all.attrs)
+	t.True(all.ended)
+	t.NoError(t.tracer.spans[3].err, "not found is not a failure")
+	t.Error(t.tracer.spans[4].err)
+
+	count, errs := t.observer.Metrics().Count(LayerMapper, "foos", "create")
+	t.EqualValues(2, count)
+	t.EqualValues(0, errs)
+	count, errs = t.observer.Metrics().Count(LayerMapper, "foos", "sum")
+	t.EqualValues(1, count)
+	t.EqualValues(1, errs)
+
+	records := t.records()
+	t.Len(records, 5)
+	t.Equal("req-1", records[2]["request_id"])
+	t.Equal("all", records[2]["operation"])
+	t.EqualValues(2, records[2]["rows"])
+	t.Equal("DEBUG", records[2]["level"])
+	t.Equal("ERROR", records[4]["level"])
+}
+
+func (t *_testObserve) Test_Repository() {
+	repo := NewRepository[_testFoo, uint](support.GormJpaRepository[_testFoo, uint]{DB: t.db}, "foos", t.observer)
+	_, err := repo.SaveAll(&_testFoo{Name: "a"}, &_testFoo{Name: "b"})
+	t.NoError(err)
+	res, err := repo.FindAll()
+	t.NoError(err)
+	t.Len(res, 2)
+	t.Equal("foos.find_all", t.tracer.spans[1].name)
+	t.Equal(LayerRepository, t.tracer.spans[1].attrs[AttrLayer])
+	t.Equal(2, t.tracer.spans[1].attrs[AttrRows])
+	count, _ := t.observer.Metrics().Count(LayerRepository, "foos", "save_all")
+	t.EqualValues(1, count)
+}
+
+func (t *_testObserve) Test_Handler() {
+	mux := http.NewServeMux()
+	router := web.WithMiddleware(web.ServeMux(mux, ""), Middleware())
+	router.Handle(http.MethodGet, "/ok", t.observer.Handler("foos", "list")(func(ctx web.Context) {
+		id, _ := RequestIDFromContext(ctx.Context())
+		ctx.JSON(http.StatusOK, id)
+	}))
+	router.Handle(http.MethodGet, "/fail", t.observer.Handler("foos", "get")(func(ctx web.Context) {
+		ctx.Status(http.StatusServiceUnavailable)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	t.Equal(`"req-2"`, strings.TrimSpace(w.Body.String()))
+	t.Equal("req-2", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	t.Equal(http.StatusServiceUnavailable, w.Code)
+	t.Len(w.Header().Get(RequestIDHeader), 16)
+
+	count, errs := t.observer.Metrics().Count(LayerHTTP, "foos", "list")
+	t.EqualValues(1, count)
+	t.EqualValues(0, errs)
+	_, errs = t.observer.Metrics().Count(LayerHTTP, "foos", "get")
+	t.EqualValues(1, errs)
+	t.Equal("req-2", t.records()[0]["request_id"])
+}
+
+func (t *_testObserve) Test_MetricsHandler() {
+	metrics := NewMetrics(0.1, 1)
+	metrics.Observe(LayerMapper, "foos", "all", 50*time.Millisecond, false)
+	metrics.Observe(LayerMapper, "foos", "all", 500*time.Millisecond, true)
+	metrics.Observe(LayerHTTP, `a"b`, "list", 2*time.Second, false)
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	t.Contains(w.Header().Get("Content-Type"), "text/plain")
+	for _, line := range []string{
+		`gestful_operation_duration_seconds_bucket{layer="mapper",resource="foos",operation="all",le="0.1"} 1`,
+		`gestful_operation_duration_seconds_bucket{layer="mapper",resource="foos",operation="all",le="1"} 2`,
+		`gestful_operation_duration_seconds_bucket{layer="mapper",resource="foos",operation="all",le="+Inf"} 2`,
+		`gestful_operation_duration_seconds_count{layer="mapper",resource="foos",operation="all"} 2`,
+		`gestful_operation_errors_total{layer="mapper",resource="foos",operation="all"} 1`,
+		`gestful_operation_duration_seconds_bucket{layer="http",resource="a\"b",operation="list",le="1"} 0`,
+	} {
+		t.Contains(body, line+"\n")
+	}
+	t.Less(strings.Index(body, `layer="http"`), strings.Index(body, `layer="mapper"`))
+}
+
+func (t *_testObserve) Test_Nop() {
+	m := NewMapper(mapper.NewBaseMapper[_testFoo](t.db), "foos", New())
+	t.NoError(m.Create(context.TODO(), &_testFoo{Name: "a"}))
+	_, err := m.OneById(context.TODO(), 2)
+	t.True(errors.Is(err, gorm.ErrRecordNotFound))
+	t.Nil(New().Metrics())
+}
+
+func TestObserve(t *testing.T) {
+	suite.Run(t, &_testObserve{})
+}
@@ -0,0 +1,91 @@
+package observe
+
+import (
+	"context"
+
+	"github.com/go-gosh/gestful/component/repository"
+)
+
+// NewRepository observing decorator of repo, its operations are observed as the layer repository of resource,
+// they have no context so their spans are roots
+func NewRepository[T, ID any](repo repository.CrudRepository[T, ID], resource string, o *Observer) repository.CrudRepository[T, ID] {
+	return &observedRepository[T, ID]{repo: repo, resource: resource, observer: o}
+}
+
+type observedRepository[T, ID any] struct {
+	repo     repository.CrudRepository[T, ID]
+	resource string
+	observer *Observer
+}
+
+func (r *observedRepository[T, ID]) exec(operation string, fn func() error) error {
+	return r.observer.observe(context.Background(), LayerRepository, r.resource, operation, func(context.Context) (int, error) {
+		return -1, fn()
+	})
+}
+
+func (r *observedRepository[T, ID]) Save(entity *T) (*T, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "save", func(context.Context) (*T, error) {
+		return r.repo.Save(entity)
+	}, one[T])
+}
+
+func (r *observedRepository[T, ID]) SaveAll(entity ...*T) ([]*T, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "save_all", func(context.Context) ([]*T, error) {
+		return r.repo.SaveAll(entity...)
+	}, length[*T])
+}
+
+func (r *observedRepository[T, ID]) FindById(id ID) (*T, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "find_by_id", func(context.Context) (*T, error) {
+		return r.repo.FindById(id)
+	}, one[T])
+}
+
+func (r *observedRepository[T, ID]) ExistsById(id ID) (bool, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "exists_by_id", func(context.Context) (bool, error) {
+		return r.repo.ExistsById(id)
+	}, nil)
+}
+
+func (r *observedRepository[T, ID]) FindAll() ([]T, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "find_all", func(context.Context) ([]T, error) {
+		return r.repo.FindAll()
+	}, length[T])
+}
+
+func (r *observedRepository[T, ID]) FindAllById(id ...ID) ([]T, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "find_all_by_id", func(context.Context) ([]T, error) {
+		return r.repo.FindAllById(id...)
+	}, length[T])
+}
+
+func (r *observedRepository[T, ID]) Count() (int, error) {
+	return call(context.Background(), r.observer, LayerRepository, r.resource, "count", func(context.Context) (int, error) {
+		return r.repo.Count()
+	}, nil)
+}
+
+func (r *observedRepository[T, ID]) DeleteById(id ID) error {
+	return r.exec("delete_by_id", func() error {
+		return r.repo.DeleteById(id)
+	})
+}
+
+func (r *observedRepository[T, ID]) Delete(entity T) error {
+	return r.exec("delete", func() error {
+		return r.repo.Delete(entity)
+	})
+}
+
+func (r *observedRepository[T, ID]) DeleteAllById(id ...ID) error {
+	return r.exec("delete_all_by_id", func() error {
+		return r.repo.DeleteAllById(id...)
+	})
+}
+
+func (r *observedRepository[T, ID]) DeleteAll(entity ...T) error {
+	return r.exec("delete_all", func() error {
+		return r.repo.DeleteAll(entity...)
+	})
+}
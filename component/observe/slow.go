@@ -0,0 +1,656 @@
+package observe
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// SlowQueriesPluginName name of the SlowQueries plugin
+	SlowQueriesPluginName = "gestful:slow_queries"
+	// SlowQueriesPath default path of SlowQueries.Handle
+	SlowQueriesPath = "/_slow_queries"
+	// DefaultMaxFingerprints default number of the fingerprints of the report
+	DefaultMaxFingerprints = 100
+	// DefaultExplainInterval default interval between the explains of the queries of a fingerprint
+	DefaultExplainInterval = time.Minute
+	// Redacted the bind parameter of a field tagged `gestful:"redact"`
+	Redacted = "[REDACTED]"
+
+	startKey = "gestful:slow_queries:start"
+)
+
+// SlowQuery the report of the slow queries of a fingerprint, i.e. of the same SQL with other bind parameters
+type SlowQuery struct {
+	Fingerprint string        `json:"fingerprint"`
+	SQL         string        `json:"sql"`
+	Resource    string        `json:"resource"`
+	Caller      string        `json:"caller"`
+	Count       int           `json:"count"`
+	Total       time.Duration `json:"total"`
+	Max         time.Duration `json:"max"`
+	Last        time.Time     `json:"last"`
+	// Params the redacted bind parameters of the last query
+	Params []interface{} `json:"params"`
+	// Plan the query plan of the last explained query, see WithExplain
+	Plan string `json:"plan,omitempty"`
+}
+
+type SlowQueryOption func(*SlowQueries)
+
+// WithExplain capture the query plan of the slow reads, by EXPLAIN QUERY PLAN on sqlite and EXPLAIN on the others.
+// The explain runs on the connection of the query before it returns, so the queries of a fingerprint are explained
+// once by DefaultExplainInterval, see WithExplainInterval
+func WithExplain() SlowQueryOption {
+	return func(s *SlowQueries) {
+		s.explain = true
+	}
+}
+
+// WithExplainInterval the interval between the explains of the queries of a fingerprint, a zero interval explains
+// every slow read
+func WithExplainInterval(interval time.Duration) SlowQueryOption {
+	return func(s *SlowQueries) {
+		s.interval = interval
+	}
+}
+
+// WithSlowQueryLogger log the slow queries by logger at warn level, tagged with the request id of their context
+func WithSlowQueryLogger(logger *slog.Logger) SlowQueryOption {
+	return func(s *SlowQueries) {
+		s.logger = slog.New(LogHandler(logger.Handler()))
+	}
+}
+
+// WithMaxFingerprints the number of the fingerprints of the report, DefaultMaxFingerprints by default,
+// the fingerprint of the least total time is evicted by a new one
+func WithMaxFingerprints(n int) SlowQueryOption {
+	return func(s *SlowQueries) {
+		s.max = n
+	}
+}
+
+// SlowQueries gorm plugin reporting the statements taking at least the threshold, a zero threshold reports them all, e.g.
+//
+//	slow := observe.NewSlowQueries(200*time.Millisecond, observe.WithExplain(), observe.WithSlowQueryLogger(logger))
+//	err := db.Use(slow)
+//	slow.Handle(router, observe.SlowQueriesPath)
+//
+// The resource of a query is the resource of its mapper decorated by NewMapper, its table otherwise,
+// and the bind parameters of the fields tagged `gestful:"redact"` are Redacted.
+// The failed statements are not reported, nor are Row and Rows, e.g. Raw(...).Scan,
+// as their rows are still open on the connection after the callbacks, which time only the first row
+type SlowQueries struct {
+	threshold time.Duration
+	explain   bool
+	interval  time.Duration
+	logger    *slog.Logger
+	max       int
+
+	mu        sync.Mutex
+	queries   map[string]*SlowQuery
+	explained map[string]time.Time
+}
+
+func NewSlowQueries(threshold time.Duration, opts ...SlowQueryOption) *SlowQueries {
+	s := &SlowQueries{
+		threshold: threshold,
+		logger:    slog.New(discardHandler{}),
+		interval:  DefaultExplainInterval,
+		max:       DefaultMaxFingerprints,
+		queries:   make(map[string]*SlowQuery),
+		explained: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *SlowQueries) Name() string {
+	return SlowQueriesPluginName
+}
+
+func (s *SlowQueries) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	start, read, write := SlowQueriesPluginName+":start", SlowQueriesPluginName+":read", SlowQueriesPluginName+":write"
+	if err := callbacks.Query().Before("gorm:query").Register(start, begin); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register(read, s.read); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register(start, begin); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("gorm:raw").Register(write, s.write); err != nil {
+		return err
+	}
+	if err := callbacks.Create().Before("gorm:create").Register(start, begin); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register(write, s.write); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register(start, begin); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register(write, s.write); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register(start, begin); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register(write, s.write)
+}
+
+func begin(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// read check a read, it is explained
+func (s *SlowQueries) read(db *gorm.DB) {
+	s.check(db, true)
+}
+
+// write check a write, the raw reads are explained
+func (s *SlowQueries) write(db *gorm.DB) {
+	s.check(db, isSelect(db.Statement.SQL.String()))
+}
+
+func isSelect(sql string) bool {
+	sql = strings.ToUpper(strings.TrimSpace(sql))
+	return strings.HasPrefix(sql, "SELECT") || strings.HasPrefix(sql, "WITH")
+}
+
+// check record the statement of db when it is slow, the failed statements are skipped
+func (s *SlowQueries) check(db *gorm.DB, explain bool) {
+	v, ok := db.InstanceGet(startKey)
+	if !ok || db.Statement.SQL.Len() == 0 || db.DryRun || db.Error != nil {
+		return
+	}
+	elapsed := time.Since(v.(time.Time))
+	if elapsed < s.threshold {
+		return
+	}
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sql := db.Statement.SQL.String()
+	q := SlowQuery{
+		SQL:      Normalize(sql),
+		Resource: resource(ctx, db.Statement),
+		Caller:   caller(),
+		Last:     time.Now(),
+		Params:   redact(ctx, db.Statement),
+	}
+	q.Fingerprint = fingerprint(q.SQL)
+	if s.explain && explain && s.due(q.Fingerprint, q.Last) {
+		q.Plan = s.plan(ctx, db, sql)
+	}
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Any("params", q.Params),
+		slog.String("resource", q.Resource),
+		slog.String("caller", q.Caller),
+		slog.Duration("elapsed", elapsed),
+		slog.Int64("rows", db.RowsAffected),
+		slog.String("fingerprint", q.Fingerprint),
+	}
+	if q.Plan != "" {
+		attrs = append(attrs, slog.String("plan", q.Plan))
+	}
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+	s.add(q, elapsed)
+}
+
+// due whether the query of fingerprint is to be explained at now, it is then not before the interval
+func (s *SlowQueries) due(fingerprint string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.explained[fingerprint]; ok && now.Sub(last) < s.interval {
+		return false
+	}
+	s.explained[fingerprint] = now
+	return true
+}
+
+// add aggregate the slow query q into the report
+func (s *SlowQueries) add(q SlowQuery, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agg, ok := s.queries[q.Fingerprint]
+	if !ok {
+		if len(s.queries) >= s.max {
+			s.evict()
+		}
+		agg = &q
+		s.queries[q.Fingerprint] = agg
+	}
+	agg.Count++
+	agg.Total += elapsed
+	if elapsed > agg.Max {
+		agg.Max = elapsed
+	}
+	agg.Last, agg.Caller, agg.Params = q.Last, q.Caller, q.Params
+	if q.Plan != "" {
+		agg.Plan = q.Plan
+	}
+}
+
+// evict the fingerprint of the least total time
+func (s *SlowQueries) evict() {
+	var least *SlowQuery
+	for _, q := range s.queries {
+		if least == nil || q.Total < least.Total {
+			least = q
+		}
+	}
+	if least != nil {
+		delete(s.queries, least.Fingerprint)
+		delete(s.explained, least.Fingerprint)
+	}
+}
+
+// Report the slow queries by fingerprint in the descending order of their total time
+func (s *SlowQueries) Report() []SlowQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]SlowQuery, 0, len(s.queries))
+	for _, q := range s.queries {
+		res = append(res, *q)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Total != res[j].Total {
+			return res[i].Total > res[j].Total
+		}
+		return res[i].Fingerprint < res[j].Fingerprint
+	})
+	return res
+}
+
+// Reset clear the report
+func (s *SlowQueries) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = make(map[string]*SlowQuery)
+	s.explained = make(map[string]time.Time)
+}
+
+// Handle serve the report at path, e.g. SlowQueriesPath, DELETE clears it
+func (s *SlowQueries) Handle(router web.Router, path string) {
+	router.Handle(http.MethodGet, path, func(ctx web.Context) {
+		ctx.JSON(http.StatusOK, s.Report())
+	})
+	router.Handle(http.MethodDelete, path, func(ctx web.Context) {
+		s.Reset()
+		ctx.Status(http.StatusNoContent)
+	})
+}
+
+// plan the query plan of sql, the failure of the explain is the plan
+func (s *SlowQueries) plan(ctx context.Context, db *gorm.DB, sql string) string {
+	explain := "EXPLAIN "
+	if db.Dialector.Name() == "sqlite" {
+		explain = "EXPLAIN QUERY PLAN "
+	}
+	rows, err := db.Statement.ConnPool.QueryContext(ctx, explain+sql, db.Statement.Vars...)
+	if err != nil {
+		return "explain: " + err.Error()
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "explain: " + err.Error()
+	}
+	detail := -1
+	for i, column := range columns {
+		if column == "detail" {
+			detail = i
+		}
+	}
+	lines := make([]string, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "explain: " + err.Error()
+		}
+		if detail >= 0 {
+			lines = append(lines, text(values[detail]))
+			continue
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = text(v)
+		}
+		lines = append(lines, strings.Join(parts, " "))
+	}
+	if err := rows.Err(); err != nil {
+		return "explain: " + err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func text(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholder   = regexp.MustCompile(`\$\d+|@p\d+`)
+	placeholders  = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+	spaces        = regexp.MustCompile(`\s+`)
+)
+
+// Normalize the SQL of the fingerprint of sql, its literals and its placeholders are replaced by ? and
+// the lists of placeholders, e.g. of IN, are collapsed
+func Normalize(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = placeholder.ReplaceAllString(sql, "?")
+	sql = numberLiteral.ReplaceAllString(sql, "?")
+	sql = placeholders.ReplaceAllString(sql, "?, ...")
+	return strings.TrimSpace(spaces.ReplaceAllString(sql, " "))
+}
+
+func fingerprint(normalized string) string {
+	sum := sha1.Sum([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+type resourceKey struct{}
+
+// resource the resource of the observed mapper of ctx, the table of stmt otherwise
+func resource(ctx context.Context, stmt *gorm.Statement) string {
+	if r, ok := ctx.Value(resourceKey{}).(string); ok {
+		return r
+	}
+	return stmt.Table
+}
+
+// skipped the packages of the frames which are not the caller of a query
+var skipped = []string{"gorm.io/", "github.com/go-gosh/gestful/component/", "runtime.", "reflect.", "sync."}
+
+// caller the first frame out of gorm and the components of gestful, the tests are callers
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		skip := false
+		for _, prefix := range skipped {
+			if strings.HasPrefix(frame.Function, prefix) && !strings.HasSuffix(frame.File, "_test.go") {
+				skip = true
+				break
+			}
+		}
+		if !skip && frame.File != "" {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// redact the bind parameters of stmt, the values of the fields tagged `gestful:"redact"` are Redacted.
+// The parameters are redacted by their positions in the clauses of stmt, see positions, and by their values
+// otherwise, e.g. of a raw statement
+func redact(ctx context.Context, stmt *gorm.Statement) []interface{} {
+	params := make([]interface{}, len(stmt.Vars))
+	copy(params, stmt.Vars)
+	if stmt.Schema == nil {
+		return params
+	}
+	fields := make([]*schema.Field, 0)
+	for _, field := range stmt.Schema.Fields {
+		if hasRedact(field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return params
+	}
+	if secrets, ok := positions(ctx, stmt, fields); ok {
+		for i, secret := range secrets {
+			if secret {
+				params[i] = Redacted
+			}
+		}
+		return params
+	}
+	secrets := make([]interface{}, 0)
+	for _, field := range fields {
+		secrets = append(secrets, fieldValues(ctx, field, stmt.ReflectValue)...)
+		secrets = append(secrets, fieldValues(ctx, field, reflect.ValueOf(stmt.Dest))...)
+		if updated, ok := stmt.Dest.(map[string]interface{}); ok {
+			for _, key := range []string{field.DBName, field.Name} {
+				if v, ok := updated[key]; ok {
+					secrets = append(secrets, v)
+				}
+			}
+		}
+	}
+	for i, param := range params {
+		for _, secret := range secrets {
+			if reflect.DeepEqual(param, secret) {
+				params[i] = Redacted
+				break
+			}
+		}
+	}
+	return params
+}
+
+func hasRedact(field *schema.Field) bool {
+	for _, option := range strings.Split(field.Tag.Get(mapper.TagName), ",") {
+		if strings.TrimSpace(option) == "redact" {
+			return true
+		}
+	}
+	return false
+}
+
+// secret the bind parameter of a field to redact, marked by mark
+type secret struct {
+	value interface{}
+}
+
+func (s secret) Value() (driver.Value, error) {
+	return Redacted, nil
+}
+
+// positions whether the bind parameters of stmt are the ones of fields, by the build of the clauses of stmt
+// with their values of fields marked, false when the clauses do not give the bind parameters of stmt,
+// e.g. of a raw statement
+func positions(ctx context.Context, stmt *gorm.Statement, fields []*schema.Field) ([]bool, bool) {
+	if stmt.DB == nil || len(stmt.BuildClauses) == 0 || len(stmt.Clauses) == 0 {
+		return nil, false
+	}
+	marked := &gorm.Statement{
+		DB:        stmt.DB,
+		Context:   ctx,
+		Schema:    stmt.Schema,
+		Table:     stmt.Table,
+		TableExpr: stmt.TableExpr,
+		Clauses:   make(map[string]clause.Clause, len(stmt.Clauses)),
+	}
+	for name, c := range stmt.Clauses {
+		c.Expression = mark(c.Expression, fields)
+		marked.Clauses[name] = c
+	}
+	marked.Build(stmt.BuildClauses...)
+	if len(marked.Vars) != len(stmt.Vars) {
+		return nil, false
+	}
+	res := make([]bool, len(marked.Vars))
+	for i, v := range marked.Vars {
+		_, res[i] = v.(secret)
+	}
+	return res, true
+}
+
+// mark the values of fields in expr as secret
+func mark(expr clause.Expression, fields []*schema.Field) clause.Expression {
+	switch e := expr.(type) {
+	case clause.Where:
+		return clause.Where{Exprs: markAll(e.Exprs, fields)}
+	case clause.AndConditions:
+		return clause.AndConditions{Exprs: markAll(e.Exprs, fields)}
+	case clause.OrConditions:
+		return clause.OrConditions{Exprs: markAll(e.Exprs, fields)}
+	case clause.NotConditions:
+		return clause.NotConditions{Exprs: markAll(e.Exprs, fields)}
+	case clause.Eq:
+		if isRedacted(e.Column, fields) {
+			e.Value = secretOf(e.Value)
+		}
+		return e
+	case clause.Neq:
+		if isRedacted(e.Column, fields) {
+			e.Value = secretOf(e.Value)
+		}
+		return e
+	case clause.IN:
+		if isRedacted(e.Column, fields) {
+			e.Values = secretsOf(e.Values)
+		}
+		return e
+	case clause.Expr:
+		for _, field := range fields {
+			if strings.Contains(e.SQL, field.DBName) {
+				e.Vars = secretsOf(e.Vars)
+				break
+			}
+		}
+		return e
+	case clause.Set:
+		set := make(clause.Set, len(e))
+		for i, assignment := range e {
+			if isRedacted(assignment.Column, fields) {
+				assignment.Value = secretOf(assignment.Value)
+			}
+			set[i] = assignment
+		}
+		return set
+	case clause.Values:
+		values := make([][]interface{}, len(e.Values))
+		for i, row := range e.Values {
+			values[i] = make([]interface{}, len(row))
+			copy(values[i], row)
+			for j, column := range e.Columns {
+				if j < len(row) && isRedacted(column, fields) {
+					values[i][j] = secretOf(row[j])
+				}
+			}
+		}
+		return clause.Values{Columns: e.Columns, Values: values}
+	}
+	return expr
+}
+
+func markAll(exprs []clause.Expression, fields []*schema.Field) []clause.Expression {
+	res := make([]clause.Expression, len(exprs))
+	for i, expr := range exprs {
+		res[i] = mark(expr, fields)
+	}
+	return res
+}
+
+// secretOf mark v as secret, the elements of a list are marked one by one as they are bound one by one,
+// the expressions are left as they are
+func secretOf(v interface{}) interface{} {
+	switch vs := v.(type) {
+	case []byte:
+		return secret{value: v}
+	case clause.Expression, gorm.Valuer:
+		return v
+	case []interface{}:
+		return secretsOf(vs)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		res := make([]interface{}, rv.Len())
+		for i := range res {
+			res[i] = secretOf(rv.Index(i).Interface())
+		}
+		return res
+	}
+	return secret{value: v}
+}
+
+func secretsOf(vs []interface{}) []interface{} {
+	res := make([]interface{}, len(vs))
+	for i, v := range vs {
+		res[i] = secretOf(v)
+	}
+	return res
+}
+
+// fieldValues the non zero values of field of the entity or the entities of v
+func fieldValues(ctx context.Context, field *schema.Field, v reflect.Value) []interface{} {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	res := make([]interface{}, 0)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			res = append(res, fieldValues(ctx, field, v.Index(i))...)
+		}
+	case reflect.Struct:
+		if v.Type() != field.Schema.ModelType {
+			return nil
+		}
+		if value, zero := field.ValueOf(ctx, v); !zero {
+			res = append(res, value)
+		}
+	}
+	return res
+}
+
+// isRedacted whether column is the column of one of fields
+func isRedacted(column interface{}, fields []*schema.Field) bool {
+	for _, field := range fields {
+		switch c := column.(type) {
+		case clause.Column:
+			if c.Name == field.DBName || c.Name == field.Name {
+				return true
+			}
+		case string:
+			if c == field.DBName || c == field.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
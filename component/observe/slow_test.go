@@ -0,0 +1,188 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type _testAccount struct {
+	ID       uint `gorm:"primaryKey"`
+	Email    string
+	Password string `gestful:"redact"`
+}
+
+type _testSlowQueries struct {
+	suite.Suite
+	db     *gorm.DB
+	slow   *SlowQueries
+	logs   *bytes.Buffer
+	mapper mapper.BaseMapper[_testAccount]
+}
+
+func (t *_testSlowQueries) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(filepath.Join(t.T().TempDir(), "slow.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testAccount{}))
+	t.logs = &bytes.Buffer{}
+	t.slow = NewSlowQueries(0, WithExplain(), WithSlowQueryLogger(slog.New(slog.NewJSONHandler(t.logs, nil))))
+	t.Require().NoError(t.db.Use(t.slow))
+	t.mapper = NewMapper(mapper.NewBaseMapper[_testAccount](t.db), "accounts", New())
+}
+
+func (t *_testSlowQueries) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+// find the slow query of the report whose SQL starts with prefix
+func (t *_testSlowQueries) find(prefix string) SlowQuery {
+	for _, q := range t.slow.Report() {
+		if strings.HasPrefix(q.SQL, prefix) {
+			return q
+		}
+	}
+	t.FailNow("no slow query " + prefix)
+	return SlowQuery{}
+}
+
+func (t *_testSlowQueries) Test_Report() {
+	ctx := WithRequestID(context.TODO(), "req-1")
+	t.NoError(t.mapper.Create(ctx, &_testAccount{Email: "a@example.com", Password: "hunter2"}))
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		_, err := t.mapper.All(ctx, func(db *gorm.DB) *gorm.DB {
+			return db.Where(clause.Eq{Column: clause.Column{Name: "email"}, Value: email}).Where("password = ?", "hunter2")
+		})
+		t.NoError(err)
+	}
+
+	insert := t.find("INSERT")
+	t.Equal("accounts", insert.Resource)
+	t.Contains(insert.Caller, "slow_test.go:")
+	t.Equal([]interface{}{"a@example.com", Redacted}, insert.Params)
+	t.Empty(insert.Plan, "the writes are not explained")
+
+	selects := t.find("SELECT")
+	t.Equal(2, selects.Count)
+	t.Equal("SELECT * FROM `_test_accounts` WHERE `email` = ? AND password = ?", selects.SQL)
+	t.Equal([]interface{}{"b@example.com", Redacted}, selects.Params)
+	t.Contains(selects.Plan, "_test_accounts")
+	t.GreaterOrEqual(selects.Total, selects.Max)
+	t.Equal(1, strings.Count(t.logs.String(), `"plan":`), "the fingerprint is explained once by interval")
+
+	t.NotContains(t.logs.String(), "hunter2")
+	t.Contains(t.logs.String(), `"request_id":"req-1"`)
+	t.Contains(t.logs.String(), `"msg":"slow query"`)
+}
+
+func (t *_testSlowQueries) Test_ExplainInterval() {
+	for i := 0; i < 2; i++ {
+		t.NoError(t.db.Find(&[]_testAccount{}).Error)
+	}
+	t.Equal(1, strings.Count(t.logs.String(), `"plan":`))
+
+	t.slow.interval = 0
+	t.NoError(t.db.Find(&[]_testAccount{}).Error)
+	t.Equal(2, strings.Count(t.logs.String(), `"plan":`))
+
+	t.slow.interval = time.Hour
+	t.slow.Reset()
+	t.NoError(t.db.Find(&[]_testAccount{}).Error)
+	t.Equal(3, strings.Count(t.logs.String(), `"plan":`), "the reset report is explained again")
+}
+
+func (t *_testSlowQueries) Test_RedactPosition() {
+	t.NoError(t.db.Create(&_testAccount{Email: "hunter2", Password: "hunter2"}).Error)
+	t.Equal([]interface{}{"hunter2", Redacted}, t.find("INSERT").Params, "the email equal to the password is kept")
+
+	t.NoError(t.db.Model(&_testAccount{}).Where("email = ?", "hunter2").
+		Updates(map[string]interface{}{"email": "s3cret", "password": "s3cret"}).Error)
+	t.Equal([]interface{}{"s3cret", Redacted, "hunter2"}, t.find("UPDATE").Params)
+
+	var accounts []_testAccount
+	t.NoError(t.db.Where(clause.IN{Column: clause.Column{Name: "password"}, Values: []interface{}{"a", "b"}}).
+		Or("email IN ?", []string{"a", "b"}).Find(&accounts).Error)
+	t.Equal([]interface{}{Redacted, Redacted, "a", "b"}, t.find("SELECT").Params)
+
+	t.slow.Reset()
+	t.NoError(t.db.Model(&_testAccount{}).Where("password = ?", "s3cret").Delete(&_testAccount{}).Error)
+	t.Equal([]interface{}{Redacted}, t.find("DELETE").Params)
+}
+
+func (t *_testSlowQueries) Test_Skipped() {
+	t.NoError(t.db.Create(&_testAccount{ID: 1}).Error)
+	t.slow.Reset()
+	t.Error(t.db.Create(&_testAccount{ID: 1}).Error, "duplicate primary key")
+	t.Error(t.db.Table("missing").Find(&[]_testAccount{}).Error)
+	t.Require().NoError(t.db.Transaction(func(tx *gorm.DB) error {
+		rows, err := tx.Model(&_testAccount{}).Rows()
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	}))
+	t.Empty(t.slow.Report())
+}
+
+func (t *_testSlowQueries) Test_Handle() {
+	t.NoError(t.db.Create(&_testAccount{Email: "a@example.com"}).Error)
+	mux := http.NewServeMux()
+	t.slow.Handle(web.ServeMux(mux, ""), SlowQueriesPath)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, SlowQueriesPath, nil))
+	t.Equal(http.StatusOK, w.Code)
+	var report []SlowQuery
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &report))
+	t.Len(report, 1)
+	t.Equal("_test_accounts", report[0].Resource)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, SlowQueriesPath, nil))
+	t.Equal(http.StatusNoContent, w.Code)
+	t.Empty(t.slow.Report())
+}
+
+func (t *_testSlowQueries) Test_Threshold() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.T().TempDir(), "threshold.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(db.AutoMigrate(&_testAccount{}))
+	slow := NewSlowQueries(time.Hour, WithMaxFingerprints(1))
+	t.Require().NoError(db.Use(slow))
+	t.NoError(db.Create(&_testAccount{Email: "a@example.com"}).Error)
+	t.Empty(slow.Report())
+
+	slow.threshold = 0
+	t.NoError(db.Create(&_testAccount{Email: "b@example.com"}).Error)
+	t.NoError(db.First(&_testAccount{}).Error)
+	t.Len(slow.Report(), 1)
+	sqlDB, err := db.DB()
+	t.Require().NoError(err)
+	t.NoError(sqlDB.Close())
+}
+
+func (t *_testSlowQueries) Test_Normalize() {
+	t.Equal("SELECT * FROM a WHERE id IN (?, ...) AND name = ? AND b2 > ? LIMIT ?",
+		Normalize("SELECT *\n FROM a WHERE id IN ($1,$2, $3) AND name = 'it''s'  AND b2 > 1.5 LIMIT 10"))
+}
+
+func TestSlowQueries(t *testing.T) {
+	suite.Run(t, &_testSlowQueries{})
+}
@@ -0,0 +1,57 @@
+package observe
+
+import "context"
+
+// Tracer start the spans of the operations, it is shaped after the OpenTelemetry tracer so an adapter is a few lines:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...observe.Attribute) (context.Context, observe.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithAttributes(toOtel(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start a span named name as a child of the span of ctx, ctx of the span is returned
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span a traced operation
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError record the failure of the operation
+	RecordError(err error)
+	End()
+}
+
+// Attribute key value of a span, the value is a string, an int or a bool
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// NopTracer tracer of the spans doing nothing, the default one
+type NopTracer struct{}
+
+func (NopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+
+func (nopSpan) RecordError(error) {}
+
+func (nopSpan) End() {}
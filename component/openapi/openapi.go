@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// Version of the OpenAPI specification of Document
+const Version = "3.1.0"
+
+// Document OpenAPI document, resources are described into it by their services
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+	Tags       []Tag                `json:"tags,omitempty"`
+
+	generator *generator
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server base url of the paths, e.g. the prefix of the router the resources are registered to
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *Schema     `json:"schema,omitempty"`
+	Example     interface{} `json:"example,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type MediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+// New empty Document
+func New(info Info) *Document {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	doc.generator = newGenerator(doc.Components.Schemas)
+	return doc
+}
+
+// Schema of v's type, a named struct is added to the components and referenced
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.generator.schema(typeOf(v))
+}
+
+// Path the PathItem of path, it is added when missing
+func (d *Document) Path(path string) *PathItem {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	return item
+}
+
+// AddTag add the tag once
+func (d *Document) AddTag(tag Tag) {
+	for _, t := range d.Tags {
+		if t.Name == tag.Name {
+			return
+		}
+	}
+	d.Tags = append(d.Tags, tag)
+}
+
+// JSON encode the document
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// WriteFile export the document to the json file name
+func (d *Document) WriteFile(name string) error {
+	b, err := d.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o644)
+}
+
+// Handler serve the document
+func (d *Document) Handler() web.HandlerFunc {
+	return func(ctx web.Context) {
+		ctx.JSON(http.StatusOK, d)
+	}
+}
+
+// Serve register the document to router at path, e.g. /openapi.json
+func Serve(router web.Router, path string, doc *Document) {
+	router.Handle(http.MethodGet, path, doc.Handler())
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type _testOwner struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email" example:"a@example.com"`
+}
+
+type _testFoo struct {
+	ID        uint        `json:"id" example:"1"`
+	Name      string      `json:"name" binding:"required" description:"display name"`
+	Note      *string     `json:"note"`
+	Secret    string      `json:"-"`
+	Tags      []string    `json:"tags"`
+	Owner     *_testOwner `json:"owner"`
+	Children  []_testFoo  `json:"children"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func TestSchema_Struct(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	assert.Equal(t, Ref("_testFoo"), doc.Schema(_testFoo{}))
+
+	s := doc.Components.Schemas["_testFoo"]
+	require.NotNil(t, s)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"name"}, s.Required)
+	assert.NotContains(t, s.Properties, "Secret")
+	assert.EqualValues(t, 1, s.Properties["id"].Example)
+	assert.Equal(t, "int64", s.Properties["id"].Format)
+	assert.Equal(t, new(float64), s.Properties["id"].Minimum)
+	assert.Equal(t, "display name", s.Properties["name"].Description)
+	assert.Equal(t, []string{"string", "null"}, s.Properties["note"].Type)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, s.Properties["tags"])
+	assert.Equal(t, Ref("_testOwner"), s.Properties["owner"])
+	assert.Equal(t, Ref("_testFoo"), s.Properties["children"].Items)
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["created_at"])
+	assert.Equal(t, "a@example.com", doc.Components.Schemas["_testOwner"].Properties["email"].Example)
+}
+
+func TestSchema_Integer(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	zero := new(float64)
+	for _, c := range []struct {
+		v       interface{}
+		format  string
+		minimum *float64
+	}{
+		{int8(0), "int32", nil},
+		{int32(0), "int32", nil},
+		{uint16(0), "int32", zero},
+		{0, "int64", nil},
+		{int64(0), "int64", nil},
+		{uint(0), "int64", zero},
+		{uint32(0), "int64", zero},
+		{uint64(0), "int64", zero},
+	} {
+		assert.Equal(t, &Schema{Type: "integer", Format: c.format, Minimum: c.minimum}, doc.Schema(c.v), "%T", c.v)
+	}
+}
+
+func TestSchema_Generic(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	assert.Equal(t, Ref("PageRes__testFoo"), doc.Schema(mapper.PageRes[_testFoo]{}))
+	s := doc.Components.Schemas["PageRes__testFoo"]
+	require.NotNil(t, s)
+	// the embedded paginator is flattened
+	assert.Contains(t, s.Properties, "start_id")
+	assert.Contains(t, s.Properties, "limit")
+	assert.Contains(t, s.Properties, "more")
+	assert.Equal(t, Ref("_testFoo"), s.Properties["data"].Items)
+
+	assert.Equal(t, Ref("CRUDPageResult__testFoo"), doc.Schema(mapper.CRUDPageResult[_testFoo]{}))
+	s = doc.Components.Schemas["CRUDPageResult__testFoo"]
+	assert.Contains(t, s.Properties, "total")
+	assert.Contains(t, s.Properties, "page_size")
+}
+
+func TestQueryParameters(t *testing.T) {
+	type page struct {
+		mapper.Paginator
+		Name string `form:"name" example:"foo"`
+		Skip string `form:"-"`
+	}
+	doc := New(Info{Title: "test", Version: "1"})
+	params := doc.QueryParameters(page{})
+	names := make([]string, 0, len(params))
+	for _, p := range params {
+		names = append(names, p.Name)
+		assert.Equal(t, "query", p.In)
+	}
+	assert.Equal(t, []string{"start_id", "limit", "name"}, names)
+	assert.Equal(t, "foo", params[2].Example)
+}
+
+func TestServeAndWriteFile(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Schema(_testFoo{})
+	mux := http.NewServeMux()
+	Serve(web.ServeMux(mux, ""), "/openapi.json", doc)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var served map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, Version, served["openapi"])
+
+	name := filepath.Join(t.TempDir(), "openapi.json")
+	require.NoError(t, doc.WriteFile(name))
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &written))
+	assert.Equal(t, served, written)
+}
@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema JSON schema of OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+}
+
+// Ref reference to the component schema name
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeArgumentPattern = regexp.MustCompile(`[\w./-]*\.`)
+	invalidNamePattern  = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator(schemas map[string]*Schema) *generator {
+	return &generator{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+func typeOf(v interface{}) reflect.Type {
+	if t, ok := v.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(v)
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	s := g.inline(t)
+	if nullable && s.Ref == "" {
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+	}
+	return s
+}
+
+func (g *generator) inline(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Implements(jsonMarshalerType), t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Kind() == reflect.Struct && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Int, reflect.Int64:
+		// int is 64-bit on the 64-bit platforms
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// ref add the named struct t to the components once and reference it
+func (g *generator) ref(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return Ref(name)
+	}
+	name := schemaName(t)
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%s%d", schemaName(t), i)
+	}
+	g.names[t] = name
+	// reserve the name before generating the properties, so that recursive types reference it
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return Ref(name)
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	return s
+}
+
+// fields add the json fields of t to s, the embedded structs are flattened like encoding/json does
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop := g.schema(field.Type)
+		if example, ok := field.Tag.Lookup("example"); ok {
+			prop = withExample(prop, example, field.Type)
+		}
+		if description := field.Tag.Get("description"); description != "" {
+			prop = withDescription(prop, description)
+		}
+		s.Properties[name] = prop
+		if strings.Contains(field.Tag.Get("binding"), "required") && !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// QueryParameters the query parameters of the form tagged fields of v's struct type, e.g. the filters of a page request
+func (d *Document) QueryParameters(v interface{}) []*Parameter {
+	t := typeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return d.generator.parameters(t)
+}
+
+func (g *generator) parameters(t reflect.Type) []*Parameter {
+	var res []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			res = append(res, g.parameters(ft)...)
+			continue
+		}
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		p := &Parameter{
+			Name:        name,
+			In:          "query",
+			Description: field.Tag.Get("description"),
+			Required:    strings.Contains(field.Tag.Get("binding"), "required"),
+			Schema:      g.schema(field.Type),
+		}
+		if example, ok := field.Tag.Lookup("example"); ok {
+			p.Example = parseExample(example, field.Type)
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+func withExample(s *Schema, example string, t reflect.Type) *Schema {
+	if s.Ref != "" {
+		return s
+	}
+	s.Example = parseExample(example, t)
+	return s
+}
+
+func withDescription(s *Schema, description string) *Schema {
+	if s.Ref != "" {
+		// sibling keywords of $ref are allowed since OpenAPI 3.1
+		return &Schema{Ref: s.Ref, Description: description}
+	}
+	s.Description = description
+	return s
+}
+
+// parseExample the example tag as a value of t
+func parseExample(example string, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(example); err == nil {
+			return v
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, err := strconv.ParseInt(example, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseUint(example, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(example, 64); err == nil {
+			return v
+		}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		var v interface{}
+		if err := json.Unmarshal([]byte(example), &v); err == nil {
+			return v
+		}
+	}
+	return example
+}
+
+// schemaName component name of t, the package paths of generic type arguments are dropped,
+// e.g. PageRes[github.com/x/model.Foo] is PageRes_Foo
+func schemaName(t reflect.Type) string {
+	name := typeArgumentPattern.ReplaceAllString(t.Name(), "")
+	name = invalidNamePattern.ReplaceAllString(name, "_")
+	return strings.TrimRight(name, "_")
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// schemaCaches the schema caches by naming strategy
+var schemaCaches = &sync.Map{}
+
+// parse the schema of T named by namer, the default naming strategy when namer is nil
+func parse[T any](namer schema.Namer) *schema.Schema {
+	var t T
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	cache := &sync.Map{}
+	if reflect.TypeOf(namer).Comparable() {
+		v, _ := schemaCaches.LoadOrStore(namer, cache)
+		cache = v.(*sync.Map)
+	}
+	s, err := schema.Parse(&t, cache, namer)
+	if err != nil {
+		panic(fmt.Sprintf("query: parse %T: %v", t, err))
+	}
+	return s
+}
+
+// namerOf the naming strategy of the optional namer argument
+func namerOf(namer []schema.Namer) schema.Namer {
+	if len(namer) > 0 {
+		return namer[0]
+	}
+	return nil
+}
+
+// TableOf the table of the model T with the naming strategy namer, the default naming strategy when it is omitted
+func TableOf[T any](namer ...schema.Namer) string {
+	return parse[T](namerOf(namer)).Table
+}
+
+// tableIn the table of the model T with the naming strategy of db
+func tableIn[T any](db *gorm.DB) string {
+	return parse[T](db.NamingStrategy).Table
+}
+
+// Bind the columns of the model T to the Column fields of the struct C, matched by field name,
+// a `column:"name"` tag matches the field of T by its column name instead, e.g.
+//
+//	var User = query.Bind[struct {
+//		ID   query.Column[uint]
+//		Name query.Column[string]
+//	}, model.User]()
+//
+// The columns are named by namer, it is to be the NamingStrategy of the gorm.DB queried when it is not the default one, e.g.
+//
+//	var User = query.Bind[UserColumns, model.User](schema.NamingStrategy{TablePrefix: "t_"})
+//
+// The columns are qualified by the table of T, it panics when a field of C matches no field of T
+func Bind[C, T any](namer ...schema.Namer) C {
+	var c C
+	s := parse[T](namerOf(namer))
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() || !isColumn(sf.Type) {
+			continue
+		}
+		var field *schema.Field
+		if name, ok := sf.Tag.Lookup("column"); ok {
+			field = s.LookUpField(name)
+		} else {
+			field = s.FieldsByName[sf.Name]
+		}
+		if field == nil || field.DBName == "" {
+			panic(fmt.Sprintf("query: %s has no column of %s", s.Name, sf.Name))
+		}
+		v.Field(i).FieldByName("Table").SetString(s.Table)
+		v.Field(i).FieldByName("Name").SetString(field.DBName)
+	}
+	return c
+}
+
+func isColumn(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == reflect.TypeOf(Column[int]{}).PkgPath() &&
+		strings.HasPrefix(t.Name(), "Column[")
+}
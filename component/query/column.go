@@ -0,0 +1,106 @@
+package query
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Field a column usable in Select and OrderBy whatever its type is
+type Field interface {
+	column() clause.Column
+}
+
+// Column typed reference to a column of values V, Table qualifies it when it is not empty,
+// the columns of a model are bound by Bind
+type Column[V any] struct {
+	Table string
+	Name  string
+}
+
+// Col the unqualified column name
+func Col[V any](name string) Column[V] {
+	return Column[V]{Name: name}
+}
+
+func (c Column[V]) column() clause.Column {
+	return clause.Column{Table: c.Table, Name: c.Name}
+}
+
+func (c Column[V]) Eq(v V) Condition {
+	return expr(clause.Eq{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Ne(v V) Condition {
+	return expr(clause.Neq{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Gt(v V) Condition {
+	return expr(clause.Gt{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Gte(v V) Condition {
+	return expr(clause.Gte{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Lt(v V) Condition {
+	return expr(clause.Lt{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Lte(v V) Condition {
+	return expr(clause.Lte{Column: c.column(), Value: v})
+}
+
+func (c Column[V]) Between(from, to V) Condition {
+	return expr(clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{c.column(), from, to}})
+}
+
+func (c Column[V]) In(values ...V) Condition {
+	vars := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		vars = append(vars, v)
+	}
+	return expr(clause.IN{Column: c.column(), Values: vars})
+}
+
+func (c Column[V]) NotIn(values ...V) Condition {
+	return Not(c.In(values...))
+}
+
+// Like match the pattern, % and _ of the pattern are wildcards
+func (c Column[V]) Like(pattern string) Condition {
+	return expr(clause.Like{Column: c.column(), Value: pattern})
+}
+
+func (c Column[V]) IsNull() Condition {
+	return expr(clause.Eq{Column: c.column(), Value: nil})
+}
+
+func (c Column[V]) IsNotNull() Condition {
+	return expr(clause.Neq{Column: c.column(), Value: nil})
+}
+
+// EqColumn compare with another column of the same type, e.g. the ON condition of a Join
+func (c Column[V]) EqColumn(other Column[V]) Condition {
+	return expr(clause.Eq{Column: c.column(), Value: other.column()})
+}
+
+// InQuery the column is in the values selected by the subquery
+func (c Column[V]) InQuery(sub Subquery) Condition {
+	return Condition{build: func(db *gorm.DB) clause.Expression {
+		return clause.Expr{SQL: "? IN (?)", Vars: []interface{}{c.column(), sub.subquery(db)}}
+	}}
+}
+
+func (c Column[V]) Asc() Order {
+	return Order{column: c.column()}
+}
+
+func (c Column[V]) Desc() Order {
+	return Order{column: c.column(), desc: true}
+}
+
+// Order of OrderBy made by Column.Asc and Column.Desc
+type Order struct {
+	column clause.Column
+	desc   bool
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Condition typed condition made by the methods of Column and combined by And, Or and Not
+type Condition struct {
+	build func(db *gorm.DB) clause.Expression
+}
+
+func expr(e clause.Expression) Condition {
+	return Condition{build: func(*gorm.DB) clause.Expression {
+		return e
+	}}
+}
+
+func buildAll(db *gorm.DB, conditions []Condition) []clause.Expression {
+	res := make([]clause.Expression, 0, len(conditions))
+	for _, c := range conditions {
+		res = append(res, c.build(db))
+	}
+	return res
+}
+
+// And all the conditions hold
+func And(conditions ...Condition) Condition {
+	return Condition{build: func(db *gorm.DB) clause.Expression {
+		return clause.And(buildAll(db, conditions)...)
+	}}
+}
+
+// Or any of the conditions holds
+func Or(conditions ...Condition) Condition {
+	return Condition{build: func(db *gorm.DB) clause.Expression {
+		return clause.Or(buildAll(db, conditions)...)
+	}}
+}
+
+// Not the condition does not hold
+func Not(condition Condition) Condition {
+	return Condition{build: func(db *gorm.DB) clause.Expression {
+		return clause.Not(condition.build(db))
+	}}
+}
+
+// Exists the subquery selects a row
+func Exists(sub Subquery) Condition {
+	return Condition{build: func(db *gorm.DB) clause.Expression {
+		return clause.Expr{SQL: "EXISTS (?)", Vars: []interface{}{sub.subquery(db)}}
+	}}
+}
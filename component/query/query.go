@@ -0,0 +1,150 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Subquery a Query used in Column.InQuery and Exists
+type Subquery interface {
+	subquery(db *gorm.DB) *gorm.DB
+}
+
+// Query typed query of the model T, it converts to the wrapper of the mappers by Wrapper,
+// e.g. mapper.All(ctx, query.New[User]().Where(User.Age.Gt(18)).OrderBy(User.Name.Desc()).Wrapper())
+// The joined models and the subqueries are isolated in the tenant of the mapper like the model T
+type Query[T any] struct {
+	selects    []Field
+	conditions []Condition
+	joins      []Join
+	orders     []Order
+	limit      int
+	offset     int
+}
+
+func New[T any]() *Query[T] {
+	return &Query[T]{}
+}
+
+// Select only the fields, e.g. the column of a subquery
+func (q *Query[T]) Select(fields ...Field) *Query[T] {
+	q.selects = append(q.selects, fields...)
+	return q
+}
+
+// Where add the conditions, all of them hold
+func (q *Query[T]) Where(conditions ...Condition) *Query[T] {
+	q.conditions = append(q.conditions, conditions...)
+	return q
+}
+
+func (q *Query[T]) Join(joins ...Join) *Query[T] {
+	q.joins = append(q.joins, joins...)
+	return q
+}
+
+func (q *Query[T]) OrderBy(orders ...Order) *Query[T] {
+	q.orders = append(q.orders, orders...)
+	return q
+}
+
+// Limit at most n rows, 0 means no limit
+func (q *Query[T]) Limit(n int) *Query[T] {
+	q.limit = n
+	return q
+}
+
+func (q *Query[T]) Offset(n int) *Query[T] {
+	q.offset = n
+	return q
+}
+
+// Wrapper the wrapper of the query, for the mappers
+func (q *Query[T]) Wrapper() func(*gorm.DB) *gorm.DB {
+	return q.apply
+}
+
+func (q *Query[T]) apply(db *gorm.DB) *gorm.DB {
+	if len(q.selects) > 0 {
+		vars := make([]interface{}, 0, len(q.selects))
+		for _, f := range q.selects {
+			vars = append(vars, f.column())
+		}
+		db = db.Select(strings.TrimSuffix(strings.Repeat("?,", len(vars)), ","), vars...)
+	}
+	for _, join := range q.joins {
+		table, conditions := join.scope(db)
+		on := clause.And(append([]clause.Expression{join.on.build(db)}, conditions...)...)
+		db = db.Joins(join.kind+" JOIN ? ON ?", clause.Table{Name: table}, on)
+	}
+	if len(q.conditions) > 0 {
+		db = db.Where(clause.Where{Exprs: buildAll(db, q.conditions)})
+	}
+	for _, order := range q.orders {
+		db = db.Order(clause.OrderByColumn{Column: order.column, Desc: order.desc})
+	}
+	if q.limit > 0 {
+		db = db.Limit(q.limit)
+	}
+	if q.offset > 0 {
+		db = db.Offset(q.offset)
+	}
+	return db
+}
+
+// subquery the query in the tenant of db, see mapper.TenantScope, gorm skips the soft deleted rows of it
+func (q *Query[T]) subquery(db *gorm.DB) *gorm.DB {
+	var t T
+	sub := db.Session(&gorm.Session{NewDB: true}).Model(&t)
+	table, conditions, err := mapper.TenantScope(db, &t)
+	if err != nil {
+		_ = db.AddError(err)
+		return q.apply(sub)
+	}
+	sub = sub.Table(table)
+	if len(conditions) > 0 {
+		sub = sub.Where(clause.Where{Exprs: conditions})
+	}
+	return q.apply(sub)
+}
+
+// Join of Query made by InnerJoin and LeftJoin
+type Join struct {
+	kind  string
+	scope func(db *gorm.DB) (string, []clause.Expression)
+	on    Condition
+}
+
+// InnerJoin join the table of the model U named by the naming strategy of the queried db on the condition
+func InnerJoin[U any](on Condition) Join {
+	return Join{kind: "INNER", scope: joinScope[U], on: on}
+}
+
+// LeftJoin left join the table of the model U named by the naming strategy of the queried db on the condition
+func LeftJoin[U any](on Condition) Join {
+	return Join{kind: "LEFT", scope: joinScope[U], on: on}
+}
+
+// joinScope the table of the model U joined to db and the conditions of its rows, which are in the tenant of db,
+// see mapper.TenantScope, and are not soft deleted unless db is unscoped
+func joinScope[U any](db *gorm.DB) (string, []clause.Expression) {
+	var u U
+	table, conditions, err := mapper.TenantScope(db, &u)
+	if err != nil {
+		_ = db.AddError(err)
+		return tableIn[U](db), nil
+	}
+	if db.Statement.Unscoped {
+		return table, conditions
+	}
+	s := parse[U](db.NamingStrategy)
+	for _, c := range s.QueryClauses {
+		if softDelete, ok := c.(gorm.SoftDeleteQueryClause); ok {
+			conditions = append(conditions, clause.Eq{Column: clause.Column{Table: s.Table, Name: softDelete.Field.DBName}, Value: nil})
+		}
+	}
+	return table, conditions
+}
@@ -0,0 +1,230 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type _testUser struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+	Age  int
+}
+
+type _testOrder struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint
+	Amount int
+}
+
+var (
+	User = Bind[struct {
+		ID   Column[uint]
+		Name Column[string]
+		Age  Column[int]
+	}, _testUser]()
+	Orders = Bind[struct {
+		ID     Column[uint]
+		UserID Column[uint] `column:"user_id"`
+		Amount Column[int]
+	}, _testOrder]()
+)
+
+type _testQuery struct {
+	suite.Suite
+	db     *gorm.DB
+	mapper mapper.BaseMapper[_testUser]
+}
+
+func (t *_testQuery) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testUser{}, &_testOrder{}))
+	t.mapper = mapper.NewBaseMapper[_testUser](t.db)
+	for _, u := range []_testUser{{Name: "alice", Age: 30}, {Name: "bob", Age: 17}, {Name: "carol", Age: 40}} {
+		t.Require().NoError(t.db.Create(&u).Error)
+	}
+	for _, o := range []_testOrder{{UserID: 1, Amount: 10}, {UserID: 3, Amount: 100}} {
+		t.Require().NoError(t.db.Create(&o).Error)
+	}
+}
+
+func (t *_testQuery) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func names(users []_testUser) []string {
+	res := make([]string, 0, len(users))
+	for _, u := range users {
+		res = append(res, u.Name)
+	}
+	return res
+}
+
+func (t *_testQuery) Test_Bind() {
+	t.Equal(Column[int]{Table: "_test_users", Name: "age"}, User.Age)
+	t.Equal(Column[uint]{Table: "_test_orders", Name: "user_id"}, Orders.UserID)
+	t.Panics(func() {
+		Bind[struct{ Missing Column[int] }, _testUser]()
+	})
+}
+
+func (t *_testQuery) Test_NamingStrategy() {
+	namer := schema.NamingStrategy{TablePrefix: "t_"}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{NamingStrategy: namer})
+	t.Require().NoError(err)
+	db = db.Debug()
+	defer func() {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}()
+	t.Require().NoError(db.AutoMigrate(&_testUser{}, &_testOrder{}))
+	t.Require().NoError(db.Create(&_testUser{Name: "alice"}).Error)
+	t.Require().NoError(db.Create(&_testOrder{UserID: 1, Amount: 10}).Error)
+
+	user := Bind[struct {
+		ID   Column[uint]
+		Name Column[string]
+	}, _testUser](namer)
+	orders := Bind[struct {
+		UserID Column[uint]
+		Amount Column[int]
+	}, _testOrder](namer)
+	t.Equal("t__test_users", TableOf[_testUser](namer))
+	t.Equal(Column[uint]{Table: "t__test_users", Name: "id"}, user.ID)
+	t.Equal("_test_users", User.ID.Table, "the default naming strategy is cached apart")
+
+	q := New[_testUser]().Select(user.ID, user.Name).
+		Join(InnerJoin[_testOrder](orders.UserID.EqColumn(user.ID))).
+		Where(orders.Amount.Gte(5))
+	res, err := mapper.NewBaseMapper[_testUser](db).All(context.TODO(), q.Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"alice"}, names(res))
+}
+
+func (t *_testQuery) Test_WhereOrderBy() {
+	q := New[_testUser]().Where(User.Age.Gt(18)).OrderBy(User.Name.Desc())
+	res, err := t.mapper.All(context.TODO(), q.Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"carol", "alice"}, names(res))
+}
+
+func (t *_testQuery) Test_Conditions() {
+	ctx := context.TODO()
+	res, err := t.mapper.All(ctx, New[_testUser]().Where(Or(User.Name.Eq("bob"), User.Age.Between(35, 45))).OrderBy(User.ID.Asc()).Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"bob", "carol"}, names(res))
+
+	res, err = t.mapper.All(ctx, New[_testUser]().Where(User.Name.NotIn("alice", "bob"), User.Name.Like("c%")).Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"carol"}, names(res))
+
+	count, err := t.mapper.Count(ctx, New[_testUser]().Where(Not(User.Age.Lte(17))).Wrapper())
+	t.Require().NoError(err)
+	t.Equal(2, count)
+}
+
+func (t *_testQuery) Test_Join() {
+	q := New[_testUser]().Select(User.ID, User.Name).
+		Join(InnerJoin[_testOrder](Orders.UserID.EqColumn(User.ID))).
+		Where(Orders.Amount.Gte(50))
+	res, err := t.mapper.All(context.TODO(), q.Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"carol"}, names(res))
+}
+
+func (t *_testQuery) Test_Subquery() {
+	ctx := context.TODO()
+	buyers := New[_testOrder]().Select(Orders.UserID)
+	res, err := t.mapper.All(ctx, New[_testUser]().Where(User.ID.InQuery(buyers)).OrderBy(User.ID.Asc()).Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"alice", "carol"}, names(res))
+
+	res, err = t.mapper.All(ctx, New[_testUser]().Where(Not(Exists(New[_testOrder]().Where(Orders.UserID.EqColumn(User.ID))))).Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"bob"}, names(res))
+}
+
+type _testTenantUser struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID string
+	Name     string
+}
+
+type _testTenantOrder struct {
+	ID        uint `gorm:"primaryKey"`
+	TenantID  string
+	UserID    uint
+	DeletedAt gorm.DeletedAt
+}
+
+func (t *_testQuery) Test_CrossTenant() {
+	t.Require().NoError(t.db.AutoMigrate(&_testTenantUser{}, &_testTenantOrder{}))
+	for _, u := range []_testTenantUser{{ID: 1, TenantID: "a", Name: "alice"}, {ID: 2, TenantID: "b", Name: "bob"}, {ID: 3, TenantID: "b", Name: "carol"}} {
+		t.Require().NoError(t.db.Create(&u).Error)
+	}
+	// user 2 has an order in the other tenant, user 3 a deleted order
+	for _, o := range []_testTenantOrder{{ID: 1, TenantID: "a", UserID: 2}, {ID: 2, TenantID: "b", UserID: 3}} {
+		t.Require().NoError(t.db.Create(&o).Error)
+	}
+	t.Require().NoError(t.db.Delete(&_testTenantOrder{}, 2).Error)
+
+	user := Bind[struct{ ID Column[uint] }, _testTenantUser]()
+	orders := Bind[struct {
+		UserID Column[uint] `column:"user_id"`
+	}, _testTenantOrder]()
+	m := mapper.NewBaseMapper[_testTenantUser](t.db, mapper.WithTenantStrategy(mapper.ColumnTenant{Column: "tenant_id"}))
+	ctx := tenant.WithTenant(context.TODO(), "b")
+
+	res, err := m.All(ctx, New[_testTenantUser]().Join(InnerJoin[_testTenantOrder](orders.UserID.EqColumn(user.ID))).Wrapper())
+	t.Require().NoError(err)
+	t.Empty(tenantNames(res), "joined")
+
+	res, err = m.All(ctx, New[_testTenantUser]().Where(user.ID.InQuery(New[_testTenantOrder]().Select(orders.UserID))).Wrapper())
+	t.Require().NoError(err)
+	t.Empty(tenantNames(res), "in subquery")
+
+	res, err = m.All(ctx, New[_testTenantUser]().Where(Exists(New[_testTenantOrder]().Where(orders.UserID.EqColumn(user.ID)))).Wrapper())
+	t.Require().NoError(err)
+	t.Empty(tenantNames(res), "exists")
+
+	res, err = m.All(ctx, New[_testTenantUser]().Join(LeftJoin[_testTenantOrder](orders.UserID.EqColumn(user.ID))).
+		Where(orders.UserID.IsNull()).Wrapper())
+	t.Require().NoError(err)
+	t.Equal([]string{"bob", "carol"}, tenantNames(res))
+
+	res, err = m.All(tenant.WithTenant(context.TODO(), "a"), New[_testTenantUser]().Join(InnerJoin[_testTenantOrder](orders.UserID.EqColumn(user.ID))).Wrapper())
+	t.Require().NoError(err)
+	t.Empty(tenantNames(res), "the order of tenant a is for a user of tenant b")
+}
+
+func tenantNames(users []_testTenantUser) []string {
+	res := make([]string, 0, len(users))
+	for _, u := range users {
+		res = append(res, u.Name)
+	}
+	return res
+}
+
+func (t *_testQuery) Test_Paginate() {
+	res, err := t.mapper.Paginate(context.TODO(), mapper.Paginator{Limit: 1}, New[_testUser]().Where(User.Age.Gt(18)).Wrapper())
+	t.Require().NoError(err)
+	t.True(res.More)
+	t.Equal([]string{"alice"}, names(res.Data))
+}
+
+func TestQuery(t *testing.T) {
+	suite.Run(t, &_testQuery{})
+}
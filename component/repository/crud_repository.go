@@ -7,10 +7,10 @@ type CrudRepository[T, ID any] interface {
 	FindById(id ID) (*T, error)
 	ExistsById(id ID) (bool, error)
 	FindAll() ([]T, error)
-	FindAllById(id ...[]ID) ([]T, error)
+	FindAllById(id ...ID) ([]T, error)
 	Count() (int, error)
 	DeleteById(id ID) error
 	Delete(entity T) error
-	DeleteAllById(id ...[]ID) error
+	DeleteAllById(id ...ID) error
 	DeleteAll(entity ...T) error
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-gosh/gestful/component/domain"
+)
+
+// RevisionRepository read the revisions of the entities, the history tables are isolated by the tenant in ctx
+type RevisionRepository[T, ID any] interface {
+	FindRevisions(ctx context.Context, id ID) ([]domain.Revision[T], error)
+	FindRevision(ctx context.Context, id ID, rev uint) (*domain.Revision[T], error)
+	FindLastChangeRevision(ctx context.Context, id ID) (*domain.Revision[T], error)
+	// FindRevisionAt the revision current at the time, the entity does not exist at the time when it is deleted
+	FindRevisionAt(ctx context.Context, id ID, at time.Time) (*domain.Revision[T], error)
+}
@@ -1,21 +1,59 @@
 package support
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-gosh/gestful/component/audit"
 	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/revision"
 	"gorm.io/gorm"
 )
 
 type GormJpaRepository[T, ID any] struct {
 	*gorm.DB
+	// Auditor populate the audit fields on save, optional
+	Auditor audit.AuditorAware
+	// Recorder record the audit logs of the writes, optional
+	Recorder audit.Recorder
+	// Revisions write the revisions of the writes to the history table, see revision.AutoMigrate
+	Revisions bool
+	// Clock of the audit fields, the audit logs and the revisions, time.Now when nil
+	Clock func() time.Time
 }
 
 func (g GormJpaRepository[T, ID]) Save(entity *T) (*T, error) {
-	err := g.DB.Create(entity).Error
+	if err := g.touch(entity); err != nil {
+		return entity, err
+	}
+	err := g.write(func(tx *gorm.DB) error {
+		if err := tx.Create(entity).Error; err != nil {
+			return err
+		}
+		return g.record(tx, audit.OperationCreate, nil, entity)
+	})
 	return entity, err
 }
 
 func (g GormJpaRepository[T, ID]) SaveAll(entity ...*T) ([]*T, error) {
-	err := g.DB.Create(&entity).Error
+	for _, e := range entity {
+		if err := g.touch(e); err != nil {
+			return entity, err
+		}
+	}
+	err := g.write(func(tx *gorm.DB) error {
+		if err := tx.Create(&entity).Error; err != nil {
+			return err
+		}
+		for _, e := range entity {
+			if err := g.record(tx, audit.OperationCreate, nil, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	return entity, err
 }
 
@@ -47,22 +85,44 @@ func (g GormJpaRepository[T, ID]) FindAllById(id ...ID) ([]T, error) {
 
 func (g GormJpaRepository[T, ID]) Count() (int, error) {
 	var c int64
-	err := g.DB.Count(&c).Error
+	var t T
+	err := g.DB.Model(&t).Count(&c).Error
 	return int(c), err
 }
 
 func (g GormJpaRepository[T, ID]) DeleteById(id ID) error {
-	var t T
-	return g.DB.Model(&t).Where("id=?", id).Delete(&t).Error
+	return g.DeleteAllById(id)
 }
 
 func (g GormJpaRepository[T, ID]) Delete(entity T) error {
-	return g.DB.Delete(&entity).Error
+	return g.write(func(tx *gorm.DB) error {
+		if err := tx.Delete(&entity).Error; err != nil {
+			return err
+		}
+		return g.record(tx, audit.OperationDelete, &entity, nil)
+	})
 }
 
 func (g GormJpaRepository[T, ID]) DeleteAllById(id ...ID) error {
 	var t T
-	return g.DB.Model(&t).Where("id in ?", id).Delete(&t).Error
+	if !g.recording() {
+		return g.DB.Model(&t).Where("id in ?", id).Delete(&t).Error
+	}
+	return g.DB.Transaction(func(tx *gorm.DB) error {
+		before := make([]T, 0)
+		if err := tx.Where("id in ?", id).Find(&before).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&t).Where("id in ?", id).Delete(&t).Error; err != nil {
+			return err
+		}
+		for i := range before {
+			if err := g.record(tx, audit.OperationDelete, &before[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (g GormJpaRepository[T, ID]) DeleteAll(entity ...T) error {
@@ -72,6 +132,9 @@ func (g GormJpaRepository[T, ID]) DeleteAll(entity ...T) error {
 			if err != nil {
 				return err
 			}
+			if err := g.record(tx, audit.OperationDelete, &entity[i], nil); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
@@ -92,3 +155,76 @@ func (g GormJpaRepository[T, ID]) FindAllByPage(page domain.Pageable) (domain.Pa
 	}
 	return domain.NewPage(total, page, r), nil
 }
+
+func (g GormJpaRepository[T, ID]) context() context.Context {
+	if g.DB.Statement.Context != nil {
+		return g.DB.Statement.Context
+	}
+	return context.Background()
+}
+
+func (g GormJpaRepository[T, ID]) now() time.Time {
+	if g.Clock != nil {
+		return g.Clock()
+	}
+	return time.Now()
+}
+
+func (g GormJpaRepository[T, ID]) recording() bool {
+	return g.Recorder != nil || g.Revisions
+}
+
+// write run fn in a transaction when the writes are recorded
+func (g GormJpaRepository[T, ID]) write(fn func(tx *gorm.DB) error) error {
+	if !g.recording() {
+		return fn(g.DB)
+	}
+	return g.DB.Transaction(fn)
+}
+
+func (g GormJpaRepository[T, ID]) touch(entity *T) error {
+	if g.Auditor == nil {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: g.DB}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	return audit.Touch(g.context(), stmt.Schema, reflect.ValueOf(entity), g.Auditor, g.now(), true)
+}
+
+func (g GormJpaRepository[T, ID]) record(tx *gorm.DB, op audit.Operation, before, after *T) error {
+	if !g.recording() {
+		return nil
+	}
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	stmt := &gorm.Statement{DB: g.DB}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	var id interface{}
+	if stmt.Schema.PrioritizedPrimaryField != nil {
+		id, _ = stmt.Schema.PrioritizedPrimaryField.ValueOf(g.context(), reflect.ValueOf(entity))
+	}
+	now := g.now()
+	if g.Recorder != nil {
+		log, err := audit.NewLog(g.context(), stmt.Schema.Name, fmt.Sprint(id), op, g.Auditor, now, before, after)
+		if err != nil {
+			return err
+		}
+		if err := g.Recorder.Record(g.context(), tx, log); err != nil {
+			return err
+		}
+	}
+	if g.Revisions {
+		var actor string
+		if g.Auditor != nil {
+			actor, _ = g.Auditor.CurrentAuditor(g.context())
+		}
+		return revision.Write(g.context(), tx, revision.TableName(stmt.Schema), domain.RevisionType(op), fmt.Sprint(id), actor, now, entity)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package support
+
+import (
+	"testing"
+
+	"github.com/go-gosh/gestful/component/repository"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// the gorm repository is a repository.CrudRepository
+var _ repository.CrudRepository[_testFoo, uint] = GormJpaRepository[_testFoo, uint]{}
+
+type _testGormJpa struct {
+	suite.Suite
+	db   *gorm.DB
+	repo repository.CrudRepository[_testFoo, uint]
+}
+
+func (t *_testGormJpa) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	t.repo = GormJpaRepository[_testFoo, uint]{DB: t.db}
+}
+
+func (t *_testGormJpa) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testGormJpa) Test_ById() {
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := t.repo.Save(&_testFoo{Name: name})
+		t.Require().NoError(err)
+	}
+
+	found, err := t.repo.FindAllById(1, 3)
+	t.NoError(err)
+	t.Len(found, 2)
+	t.Equal("a", found[0].Name)
+	t.Equal("c", found[1].Name)
+
+	t.NoError(t.repo.DeleteAllById(1, 2))
+	found, err = t.repo.FindAll()
+	t.NoError(err)
+	t.Len(found, 1)
+	t.Equal("c", found[0].Name)
+}
+
+func (t *_testGormJpa) Test_Count() {
+	count, err := t.repo.Count()
+	t.NoError(err)
+	t.Equal(0, count)
+	_, err = t.repo.SaveAll(&_testFoo{Name: "a"}, &_testFoo{Name: "b"})
+	t.Require().NoError(err)
+	count, err = t.repo.Count()
+	t.NoError(err)
+	t.Equal(2, count)
+}
+
+func TestGormJpa(t *testing.T) {
+	suite.Run(t, &_testGormJpa{})
+}
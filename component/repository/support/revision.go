@@ -0,0 +1,100 @@
+package support
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/revision"
+	"github.com/go-gosh/gestful/component/tenant"
+	"gorm.io/gorm"
+)
+
+// GormRevisionRepository read the revisions of T from its history table
+type GormRevisionRepository[T, ID any] struct {
+	*gorm.DB
+	// Tenant read the history table of the tenant in the context as the mapper of T with the strategy writes it, optional
+	Tenant mapper.TenantStrategy
+}
+
+// history the history table of T in the tenant of ctx
+func (g GormRevisionRepository[T, ID]) history(ctx context.Context) *gorm.DB {
+	db := g.DB.WithContext(ctx)
+	var t T
+	stmt := &gorm.Statement{DB: g.DB}
+	if err := stmt.Parse(&t); err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	table := revision.TableName(stmt.Schema)
+	if g.Tenant == nil {
+		return db.Table(table)
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		_ = db.AddError(tenant.ErrTenantRequired)
+		return db
+	}
+	root, err := g.Tenant.Database(g.DB, id)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	table, err = g.Tenant.Table(table, id)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	return root.WithContext(ctx).Table(table)
+}
+
+func (g GormRevisionRepository[T, ID]) FindRevisions(ctx context.Context, id ID) ([]domain.Revision[T], error) {
+	histories := make([]revision.History, 0)
+	err := g.history(ctx).Where("entity_id=?", id).Order("revision asc").Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Revision[T], 0, len(histories))
+	for _, h := range histories {
+		rev, err := revision.Decode[T](h)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, nil
+}
+
+func (g GormRevisionRepository[T, ID]) FindRevision(ctx context.Context, id ID, rev uint) (*domain.Revision[T], error) {
+	return g.findOne(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("entity_id=? AND revision=?", id, rev)
+	})
+}
+
+func (g GormRevisionRepository[T, ID]) FindLastChangeRevision(ctx context.Context, id ID) (*domain.Revision[T], error) {
+	return g.findOne(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("entity_id=?", id).Order("revision desc")
+	})
+}
+
+func (g GormRevisionRepository[T, ID]) FindRevisionAt(ctx context.Context, id ID, at time.Time) (*domain.Revision[T], error) {
+	rev, err := g.findOne(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("entity_id=? AND created_at<=?", id, at).Order("revision desc")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rev.Type == domain.RevisionTypeDelete {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return rev, nil
+}
+
+func (g GormRevisionRepository[T, ID]) findOne(ctx context.Context, wrapper func(*gorm.DB) *gorm.DB) (*domain.Revision[T], error) {
+	var h revision.History
+	if err := wrapper(g.history(ctx)).First(&h).Error; err != nil {
+		return nil, err
+	}
+	return revision.Decode[T](h)
+}
@@ -0,0 +1,141 @@
+package support
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/revision"
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testFoo struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `json:"name"`
+}
+
+type _testRevision struct {
+	suite.Suite
+	db   *gorm.DB
+	repo GormRevisionRepository[_testFoo, uint]
+}
+
+func (t *_testRevision) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	t.Require().NoError(revision.AutoMigrate(t.db, &_testFoo{}))
+	t.repo = GormRevisionRepository[_testFoo, uint]{DB: t.db}
+}
+
+func (t *_testRevision) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testRevision) Test_MapperRevisions() {
+	ctx := context.TODO()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := at
+	m := mapper.NewBaseMapper[_testFoo](t.db, mapper.WithRevisions(), mapper.WithClock(func() time.Time { return now }))
+	foo := _testFoo{Name: "v1"}
+	t.Require().NoError(m.Create(ctx, &foo))
+	t.Require().NoError(m.Create(ctx, &_testFoo{Name: "other"}))
+	t.Require().NoError(m.UpdateById(ctx, foo.ID, map[string]interface{}{"name": "v2"}))
+	now = at.Add(time.Second)
+	t.Require().NoError(m.DeleteById(ctx, foo.ID))
+
+	revs, err := t.repo.FindRevisions(ctx, foo.ID)
+	t.NoError(err)
+	t.Len(revs, 3)
+	for i, typ := range []domain.RevisionType{domain.RevisionTypeCreate, domain.RevisionTypeUpdate, domain.RevisionTypeDelete} {
+		t.EqualValues(typ, revs[i].Type)
+	}
+	t.EqualValues("v1", revs[0].Entity.Name)
+	t.EqualValues("v2", revs[1].Entity.Name)
+
+	rev, err := t.repo.FindRevision(ctx, foo.ID, revs[0].Number)
+	t.NoError(err)
+	t.EqualValues("v1", rev.Entity.Name)
+	_, err = t.repo.FindRevision(ctx, foo.ID, revs[0].Number+1)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	rev, err = t.repo.FindLastChangeRevision(ctx, foo.ID)
+	t.NoError(err)
+	t.EqualValues(domain.RevisionTypeDelete, rev.Type)
+
+	rev, err = t.repo.FindRevisionAt(ctx, foo.ID, at)
+	t.NoError(err)
+	t.EqualValues("v2", rev.Entity.Name)
+	_, err = t.repo.FindRevisionAt(ctx, foo.ID, at.Add(time.Second))
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func (t *_testRevision) Test_RepositoryRevisions() {
+	ctx := context.TODO()
+	repo := GormJpaRepository[_testFoo, uint]{DB: t.db, Revisions: true}
+	foo, err := repo.Save(&_testFoo{Name: "v1"})
+	t.Require().NoError(err)
+	t.Require().NoError(repo.DeleteById(foo.ID))
+	revs, err := t.repo.FindRevisions(ctx, foo.ID)
+	t.NoError(err)
+	t.Len(revs, 2)
+	t.EqualValues(domain.RevisionTypeDelete, revs[1].Type)
+	t.EqualValues("v1", revs[1].Entity.Name)
+}
+
+func (t *_testRevision) Test_SchemaTenantRevisions() {
+	dir := t.T().TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "main.db")), &gorm.Config{})
+	t.Require().NoError(err)
+	db = db.Debug()
+	sqlDB, err := db.DB()
+	t.Require().NoError(err)
+	defer sqlDB.Close()
+	// the attached databases are the schemas of the connection
+	sqlDB.SetMaxOpenConns(1)
+	for _, name := range []string{"a", "b"} {
+		t.Require().NoError(db.Exec("ATTACH DATABASE ? AS tenant_"+name, filepath.Join(dir, name+".db")).Error)
+		t.Require().NoError(db.Exec("CREATE TABLE tenant_" + name + "._test_foos (id integer primary key, name text)").Error)
+		t.Require().NoError(db.Exec("CREATE TABLE tenant_" + name + "._test_foos_history " +
+			"(revision integer primary key, entity_id text, type text, actor text, snapshot text, created_at datetime)").Error)
+	}
+	strategy := mapper.SchemaTenant{Format: "tenant_%s"}
+	m := mapper.NewBaseMapper[_testFoo](db, mapper.WithTenantStrategy(strategy), mapper.WithRevisions())
+	repo := GormRevisionRepository[_testFoo, uint]{DB: db, Tenant: strategy}
+	ctxA := tenant.WithTenant(context.TODO(), "a")
+	ctxB := tenant.WithTenant(context.TODO(), "b")
+	t.Require().NoError(m.Create(ctxA, &_testFoo{ID: 1, Name: "a1"}))
+	t.Require().NoError(m.Create(ctxB, &_testFoo{ID: 1, Name: "b1"}))
+	t.Require().NoError(m.UpdateById(ctxB, 1, map[string]interface{}{"name": "b2"}))
+
+	revs, err := repo.FindRevisions(ctxA, 1)
+	t.NoError(err)
+	t.Len(revs, 1)
+	t.EqualValues("a1", revs[0].Entity.Name)
+	revs, err = repo.FindRevisions(ctxB, 1)
+	t.NoError(err)
+	t.Len(revs, 2)
+	t.EqualValues("b2", revs[1].Entity.Name)
+	rev, err := repo.FindLastChangeRevision(ctxA, 1)
+	t.NoError(err)
+	t.EqualValues("a1", rev.Entity.Name)
+
+	_, err = repo.FindRevisions(context.TODO(), 1)
+	t.ErrorIs(err, tenant.ErrTenantRequired)
+	_, err = repo.FindRevisions(tenant.WithTenant(context.TODO(), "a; --"), 1)
+	t.ErrorIs(err, mapper.ErrInvalidTenant)
+}
+
+func TestRevision(t *testing.T) {
+	suite.Run(t, &_testRevision{})
+}
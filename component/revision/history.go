@@ -0,0 +1,77 @@
+package revision
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-gosh/gestful/component/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// History row of the shadow <table>_history table of an entity
+type History struct {
+	Revision  uint                `gorm:"primaryKey"`
+	EntityID  string              `gorm:"size:191"`
+	Type      domain.RevisionType `gorm:"size:16"`
+	Actor     string
+	Snapshot  string
+	CreatedAt time.Time
+}
+
+// TableName history table of the entity schema
+func TableName(s *schema.Schema) string {
+	return s.Table + "_history"
+}
+
+// AutoMigrate create the history tables of models
+func AutoMigrate(db *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := TableName(stmt.Schema)
+		if err := db.Table(table).AutoMigrate(&History{}); err != nil {
+			return err
+		}
+		index := "idx_" + table + "_entity_id"
+		if err := db.Exec("CREATE INDEX IF NOT EXISTS " + db.Statement.Quote(index) +
+			" ON " + db.Statement.Quote(table) + " (" + db.Statement.Quote("entity_id") + ", " + db.Statement.Quote("revision") + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a revision of the entity at now to its history table in tx, the table is TableName qualified as the table of the entity
+func Write(ctx context.Context, tx *gorm.DB, table string, typ domain.RevisionType, entityID, actor string, now time.Time, entity interface{}) error {
+	snapshot, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	// the insert names the table too, as the sqlite dialect drops the schema of Table on insert
+	return tx.WithContext(ctx).Table(table).Clauses(clause.Insert{Table: clause.Table{Name: table}}).Create(&History{
+		EntityID:  entityID,
+		Type:      typ,
+		Actor:     actor,
+		Snapshot:  string(snapshot),
+		CreatedAt: now,
+	}).Error
+}
+
+// Decode the revision of the history
+func Decode[T any](h History) (*domain.Revision[T], error) {
+	rev := &domain.Revision[T]{
+		Number:    h.Revision,
+		Type:      h.Type,
+		Actor:     h.Actor,
+		Timestamp: h.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(h.Snapshot), &rev.Entity); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
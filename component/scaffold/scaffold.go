@@ -0,0 +1,310 @@
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// markers of the lists which Resource adds the migrations and the routes of the scaffolded resources to
+const (
+	MigrationsMarker = "// gestful:migrations"
+	RoutesMarker     = "// gestful:routes"
+)
+
+// ErrExists the project or the resource already exists
+var ErrExists = errors.New("already exists")
+
+// requires the modules required by the scaffolded project and their versions in the go.mod of gestful,
+// the versions gestful is built with are preferred, see requirements
+var requires = [][2]string{
+	{"github.com/gin-gonic/gin", "v1.8.1"},
+	{"github.com/go-gosh/gestful", "v0.0.0-00010101000000-000000000000"},
+	{"github.com/stretchr/testify", "v1.8.4"},
+	{"gorm.io/driver/sqlite", "v1.3.6"},
+	{"gorm.io/gorm", "v1.23.8"},
+}
+
+// requirements the require lines of the scaffolded go.mod in the versions of the build of gestful,
+// a development build of gestful has no version of itself and requires the placeholder version of the go tool,
+// to be replaced by go get github.com/go-gosh/gestful@version or a replace directive
+func requirements() []string {
+	versions := make(map[string]string)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range append([]*debug.Module{&info.Main}, info.Deps...) {
+			if m.Replace == nil && m.Version != "" && m.Version != "(devel)" {
+				versions[m.Path] = m.Version
+			}
+		}
+	}
+	res := make([]string, 0, len(requires))
+	for _, r := range requires {
+		version, ok := versions[r[0]]
+		if !ok {
+			version = r[1]
+		}
+		res = append(res, r[0]+" "+version)
+	}
+	return res
+}
+
+//go:embed templates
+var templates embed.FS
+
+var tmpl = template.Must(template.ParseFS(templates, "templates/*.tmpl"))
+
+// types the go type of the field types of a Field, e.g. title:string
+var types = map[string]string{
+	"string":   "string",
+	"text":     "string",
+	"int":      "int",
+	"int64":    "int64",
+	"uint":     "uint",
+	"float":    "float64",
+	"float64":  "float64",
+	"bool":     "bool",
+	"time":     "time.Time",
+	"datetime": "time.Time",
+}
+
+// samples of the types in the scaffolded tests, the go literal and the json value
+var samples = map[string][2]string{
+	"string":    {`"foo"`, `"foo"`},
+	"int":       {"1", "1"},
+	"int64":     {"1", "1"},
+	"uint":      {"1", "1"},
+	"float64":   {"1.5", "1.5"},
+	"bool":      {"true", "true"},
+	"time.Time": {"time.Now()", `"2024-01-01T00:00:00Z"`},
+}
+
+// Field field of a scaffolded resource
+type Field struct {
+	Name   string
+	Column string
+	Type   string
+	// Required the field is required by the create request, e.g. title:string:required
+	Required bool
+	// Search the field is searched by ?q=, e.g. title:string:search
+	Search bool
+}
+
+func (f Field) GoSample() string {
+	return samples[f.Type][0]
+}
+
+// ParseField parse name:type[:option,...] of the command line, the options are required and search
+func ParseField(s string) (Field, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return Field{}, fmt.Errorf("scaffold: invalid field %q, expected name:type[:options]", s)
+	}
+	typ, ok := types[parts[1]]
+	if !ok {
+		return Field{}, fmt.Errorf("scaffold: unknown type %q of field %s", parts[1], parts[0])
+	}
+	naming := schema.NamingStrategy{}
+	f := Field{Name: camel(parts[0]), Type: typ}
+	f.Column = naming.ColumnName("", f.Name)
+	if f.Name == "ID" || f.Name == "CreatedAt" || f.Name == "UpdatedAt" {
+		return Field{}, fmt.Errorf("scaffold: field %s is always generated", parts[0])
+	}
+	if len(parts) == 3 {
+		for _, option := range strings.Split(parts[2], ",") {
+			switch option {
+			case "required":
+				f.Required = true
+			case "search":
+				f.Search = true
+			default:
+				return Field{}, fmt.Errorf("scaffold: unknown option %q of field %s", option, parts[0])
+			}
+		}
+	}
+	return f, nil
+}
+
+// camel convert snake_case to CamelCase, the common initialisms are upper-cased like golint does, e.g. user_id to UserID
+func camel(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(s, "_") {
+		if word == "" {
+			continue
+		}
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+var initialisms = map[string]bool{"ID": true, "URL": true, "URI": true, "IP": true, "API": true, "HTTP": true, "JSON": true, "UUID": true}
+
+// New make a project of module in dir which serves the scaffolded resources by gin, dir is to be empty or absent
+func New(dir, module string) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("scaffold: %s: %w", dir, ErrExists)
+	}
+	data := map[string]interface{}{"Module": module, "Requires": requirements(), "MigrationsMarker": MigrationsMarker, "RoutesMarker": RoutesMarker}
+	files := []struct{ name, template string }{
+		{"go.mod", "go.mod.tmpl"},
+		{".gitignore", "gitignore.tmpl"},
+		{"main.go", "main.go.tmpl"},
+		{"model/migrations.go", "migrations.go.tmpl"},
+		{"api/routes.go", "routes.go.tmpl"},
+	}
+	for _, f := range files {
+		if err := render(filepath.Join(dir, f.name), f.template, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type resource struct {
+	Module string
+	Name   string
+	Table  string
+	Fields []Field
+}
+
+func (r resource) HasTime() bool {
+	for _, f := range r.Fields {
+		if f.Type == "time.Time" {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONSample the create request of the scaffolded tests
+func (r resource) JSONSample() string {
+	values := make([]string, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		values = append(values, fmt.Sprintf("%q:%s", f.Column, samples[f.Type][1]))
+	}
+	return `{"data":{` + strings.Join(values, ",") + `}}`
+}
+
+// Resource scaffold the model, the mapper, the routes and the tests of the resource name in the project of dir,
+// and add the migration creating its table and its routes to the lists of the project
+func Resource(dir, name string, fields ...Field) error {
+	module, err := modulePath(dir)
+	if err != nil {
+		return err
+	}
+	r := resource{Module: module, Name: camel(name), Fields: fields}
+	r.Table = schema.NamingStrategy{}.TableName(r.Name)
+	file := schema.NamingStrategy{}.ColumnName("", r.Name)
+	files := []struct{ name, template string }{
+		{filepath.Join(dir, "model", file+".go"), "model.go.tmpl"},
+		{filepath.Join(dir, "api", file+".go"), "api.go.tmpl"},
+		{filepath.Join(dir, "api", file+"_test.go"), "api_test.go.tmpl"},
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.name); err == nil {
+			return fmt.Errorf("scaffold: %s: %w", f.name, ErrExists)
+		}
+	}
+	for _, f := range files {
+		if err := render(f.name, f.template, r); err != nil {
+			return err
+		}
+	}
+	migrations := filepath.Join(dir, "model", "migrations.go")
+	version, err := nextVersion(migrations)
+	if err != nil {
+		return err
+	}
+	if err := insert(migrations, MigrationsMarker, fmt.Sprintf("migration.CreateTables(%d, %q, &%s{}),", version, "create_"+r.Table, r.Name)); err != nil {
+		return err
+	}
+	return insert(filepath.Join(dir, "api", "routes.go"), RoutesMarker, fmt.Sprintf("Register%s(router, db)", r.Name))
+}
+
+func render(path, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	src := buf.Bytes()
+	if strings.HasSuffix(path, ".go") {
+		var err error
+		if src, err = format.Source(src); err != nil {
+			return fmt.Errorf("scaffold: format %s: %w", path, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+// nextVersion the timestamp version of a new migration of the file, it is incremented until it is not in the file
+func nextVersion(path string) (int64, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	version, _ := strconv.ParseInt(time.Now().UTC().Format("20060102150405"), 10, 64)
+	for bytes.Contains(src, []byte(strconv.FormatInt(version, 10))) {
+		version++
+	}
+	return version, nil
+}
+
+// insert insert line before the marker of the file
+func insert(path, marker, line string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	i := bytes.Index(src, []byte(marker))
+	if i < 0 {
+		return fmt.Errorf("scaffold: %s has no %s", path, marker)
+	}
+	start := bytes.LastIndexByte(src[:i], '\n') + 1
+	indent := src[start:i]
+	res := make([]byte, 0, len(src)+len(line)+len(indent)+1)
+	res = append(res, src[:start]...)
+	res = append(res, indent...)
+	res = append(res, line+"\n"...)
+	res = append(res, src[start:]...)
+	if res, err = format.Source(res); err != nil {
+		return fmt.Errorf("scaffold: format %s: %w", path, err)
+	}
+	return os.WriteFile(path, res, 0o644)
+}
+
+// modulePath the module path of the go.mod of dir
+func modulePath(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("scaffold: %s has no module", filepath.Join(dir, "go.mod"))
+}
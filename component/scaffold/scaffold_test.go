@@ -0,0 +1,118 @@
+package scaffold
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type _testScaffold struct {
+	suite.Suite
+	dir string
+}
+
+func (t *_testScaffold) SetupTest() {
+	t.dir = filepath.Join(t.T().TempDir(), "shop")
+	t.Require().NoError(New(t.dir, "example.com/shop"))
+}
+
+func (t *_testScaffold) read(name string) string {
+	b, err := os.ReadFile(filepath.Join(t.dir, name))
+	t.Require().NoError(err)
+	return string(b)
+}
+
+func (t *_testScaffold) Test_New() {
+	gomod := t.read("go.mod")
+	t.True(strings.HasPrefix(gomod, "module example.com/shop\n\ngo 1.22\n\nrequire (\n"), gomod)
+	for _, r := range requires {
+		t.Contains(gomod, "\t"+r[0]+" v")
+	}
+	t.Contains(t.read("main.go"), `"example.com/shop/api"`)
+	t.ErrorIs(New(t.dir, "example.com/shop"), ErrExists)
+}
+
+func (t *_testScaffold) Test_ParseField() {
+	f, err := ParseField("author_id:uint:required,search")
+	t.Require().NoError(err)
+	t.Equal(Field{Name: "AuthorID", Column: "author_id", Type: "uint", Required: true, Search: true}, f)
+	f, err = ParseField("published_at:time")
+	t.Require().NoError(err)
+	t.Equal(Field{Name: "PublishedAt", Column: "published_at", Type: "time.Time"}, f)
+	for _, s := range []string{"title", ":string", "title:decimal", "title:string:unique", "id:uint"} {
+		_, err = ParseField(s)
+		t.Error(err, s)
+	}
+}
+
+func (t *_testScaffold) Test_Resource() {
+	title, err := ParseField("title:string:required")
+	t.Require().NoError(err)
+	at, err := ParseField("published_at:time")
+	t.Require().NoError(err)
+	t.Require().NoError(Resource(t.dir, "Book", title, at))
+	t.Require().NoError(Resource(t.dir, "blog_post"))
+
+	t.Contains(t.read("model/book.go"), "type Book struct")
+	t.Contains(t.read("model/book.go"), "Title       string    `json:\"title\" binding:\"required\"`")
+	t.Contains(t.read("model/blog_post.go"), "type BlogPost struct")
+	t.Contains(t.read("api/book.go"), `RegisterGroupRoute(router, "books")`)
+	t.Contains(t.read("api/book_test.go"), `{"data":{"title":"foo","published_at":"2024-01-01T00:00:00Z"}}`)
+	t.Contains(t.read("api/blog_post_test.go"), `{"data":{}}`)
+	migrations := t.read("model/migrations.go")
+	t.Regexp(`migration.CreateTables\((\d{14}), "create_books", &Book\{\}\),\n\tmigration.CreateTables\((\d{14}), "create_blog_posts", &BlogPost\{\}\),\n\t`+MigrationsMarker, migrations)
+	versions := regexp.MustCompile(`\d{14}`).FindAllString(migrations, -1)
+	t.Require().Len(versions, 2)
+	t.NotEqual(versions[0], versions[1])
+	routes := t.read("api/routes.go")
+	t.Contains(routes, "\tRegisterBook(router, db)\n\tRegisterBlogPost(router, db)\n\t"+RoutesMarker)
+
+	fset := token.NewFileSet()
+	for _, name := range []string{"main.go", "model/book.go", "model/migrations.go", "api/book.go", "api/book_test.go", "api/routes.go"} {
+		_, err := parser.ParseFile(fset, filepath.Join(t.dir, name), nil, 0)
+		t.NoError(err, name)
+	}
+
+	t.ErrorIs(Resource(t.dir, "Book"), ErrExists)
+	t.Error(Resource(t.T().TempDir(), "Book"))
+}
+
+func (t *_testScaffold) Test_Build() {
+	if testing.Short() {
+		t.T().Skip("builds the scaffolded project")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.T().Skip("go is not installed")
+	}
+	title, err := ParseField("title:string:required,search")
+	t.Require().NoError(err)
+	at, err := ParseField("published_at:time")
+	t.Require().NoError(err)
+	t.Require().NoError(Resource(t.dir, "Book", title, at))
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	t.Require().NoError(err)
+	f, err := os.OpenFile(filepath.Join(t.dir, "go.mod"), os.O_APPEND|os.O_WRONLY, 0)
+	t.Require().NoError(err)
+	_, err = f.WriteString("\nreplace github.com/go-gosh/gestful => " + root + "\n")
+	t.Require().NoError(err)
+	t.Require().NoError(f.Close())
+
+	cmd := exec.Command(gotool, "vet", "./...")
+	cmd.Dir = t.dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	t.NoError(err, string(out))
+}
+
+func TestScaffold(t *testing.T) {
+	suite.Run(t, &_testScaffold{})
+}
@@ -0,0 +1,48 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// ErrUnauthenticated no principal is resolved from the request
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+type principalKey struct{}
+
+// WithPrincipal carry the principal of the request in ctx
+func WithPrincipal(ctx context.Context, principal interface{}) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext the principal carried in ctx
+func PrincipalFromContext[P any](ctx context.Context) (P, bool) {
+	principal, ok := ctx.Value(principalKey{}).(P)
+	return principal, ok
+}
+
+// Resolver resolve the principal of the request
+type Resolver func(ctx web.Context) (interface{}, error)
+
+// Authenticate middleware carrying the resolved principal in the request context,
+// it responds 401 when resolve fails with ErrUnauthenticated
+func Authenticate(resolve Resolver) web.Middleware {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			principal, err := resolve(ctx)
+			if errors.Is(err, ErrUnauthenticated) {
+				ctx.JSON(http.StatusUnauthorized, err.Error())
+				return
+			}
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+			ctx.SetContext(WithPrincipal(ctx.Context(), principal))
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// bindAggregation the aggregation of the query, e.g. ?group_by=status&metrics=count,sum(amount)&limit=10,
+// the metric is count by default and the groups are only limited when limit is set
+func bindAggregation(ctx web.Context) (mapper.Aggregation, error) {
+	aggregation := mapper.GroupBy(splitQuery(ctx.Query("group_by"))...)
+	if v := ctx.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return aggregation, fmt.Errorf("%w: limit %q", ErrBadRequest, v)
+		}
+		aggregation = aggregation.WithLimit(limit)
+	}
+	metrics := splitQuery(ctx.Query("metrics"))
+	if len(metrics) == 0 {
+		metrics = []string{string(mapper.AggregateCount)}
+	}
+	for _, v := range metrics {
+		metric, err := mapper.ParseMetric(v)
+		if err != nil {
+			return aggregation, err
+		}
+		aggregation = aggregation.Aggregate(metric)
+	}
+	return aggregation, nil
+}
+
+func splitQuery(v string) []string {
+	res := make([]string, 0)
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func handleAggregate[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		aggregation, err := bindAggregation(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		req, err := s.BindPage(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := s.Aggregate(ctx.Context(), req, aggregation)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, res)
+	}
+}
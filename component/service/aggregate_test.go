@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testAggregateOrder struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Status string `json:"status" gestful:"search"`
+	Amount int    `json:"amount"`
+}
+
+type _testAggregate struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testAggregate) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testAggregateOrder{}))
+	for _, order := range []_testAggregateOrder{{Status: "paid", Amount: 10}, {Status: "paid", Amount: 30}, {Status: "open", Amount: 5}} {
+		t.Require().NoError(t.db.Create(&order).Error)
+	}
+	s := NewBaseService[_testAggregateOrder, BaseCreateRequest[_testAggregateOrder], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testAggregateOrder](t.db))
+	mux := http.NewServeMux()
+	s.RegisterGroupRoute(web.ServeMux(mux, ""), "orders")
+	t.handler = mux
+}
+
+func (t *_testAggregate) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testAggregate) do(path string) (int, []mapper.AggregateRow) {
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var rows []mapper.AggregateRow
+	_ = json.Unmarshal(w.Body.Bytes(), &rows)
+	return w.Code, rows
+}
+
+func (t *_testAggregate) Test_GroupBy() {
+	code, rows := t.do("/orders/_aggregate?group_by=status&metrics=count,sum(amount)")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Equal([]mapper.AggregateRow{
+		{Group: map[string]interface{}{"status": "open"}, Metrics: map[string]float64{"count": 1, "sum(amount)": 5}},
+		{Group: map[string]interface{}{"status": "paid"}, Metrics: map[string]float64{"count": 2, "sum(amount)": 40}},
+	}, rows)
+}
+
+func (t *_testAggregate) Test_Limit() {
+	code, rows := t.do("/orders/_aggregate?group_by=status&limit=1")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Equal([]mapper.AggregateRow{{Group: map[string]interface{}{"status": "open"}, Metrics: map[string]float64{"count": 1}}}, rows)
+
+	for i := 0; i < DefaultPageLimit; i++ {
+		t.Require().NoError(t.db.Create(&_testAggregateOrder{Status: fmt.Sprintf("s%02d", i)}).Error)
+	}
+	code, rows = t.do("/orders/_aggregate?group_by=status")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Len(rows, DefaultPageLimit+2, "the groups are not limited by the default page limit")
+	code, rows = t.do("/orders/_aggregate?group_by=status&limit=3")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Len(rows, 3)
+
+	for i := DefaultPageLimit; i < MaxPageLimit; i++ {
+		t.Require().NoError(t.db.Create(&_testAggregateOrder{Status: fmt.Sprintf("s%03d", i)}).Error)
+	}
+	code, _ = t.do("/orders/_aggregate?group_by=status")
+	t.EqualValues(http.StatusBadRequest, code, "too many groups without a limit")
+	code, rows = t.do("/orders/_aggregate?group_by=status&limit=1000")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Len(rows, MaxPageLimit)
+}
+
+func (t *_testAggregate) Test_DefaultCountWithSearch() {
+	code, rows := t.do("/orders/_aggregate?q=paid")
+	t.Require().EqualValues(http.StatusOK, code)
+	t.Equal([]mapper.AggregateRow{{Group: map[string]interface{}{}, Metrics: map[string]float64{"count": 2}}}, rows)
+}
+
+func (t *_testAggregate) Test_Invalid() {
+	code, _ := t.do("/orders/_aggregate?group_by=password")
+	t.EqualValues(http.StatusBadRequest, code)
+	code, _ = t.do("/orders/_aggregate?metrics=sum(status)")
+	t.EqualValues(http.StatusBadRequest, code)
+	code, _ = t.do("/orders/_aggregate?metrics=drop(amount)")
+	t.EqualValues(http.StatusBadRequest, code)
+}
+
+func TestAggregate(t *testing.T) {
+	suite.Run(t, &_testAggregate{})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/audit"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testAuditFoo struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedBy string    `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type _testAudit struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testAudit) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testAuditFoo{}))
+	s := NewBaseService[_testAuditFoo, BaseCreateRequest[_testAuditFoo], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testAuditFoo](t.db, mapper.WithAuditor(audit.PrincipalAuditor(func(name string) string { return name }))))
+	mux := http.NewServeMux()
+	principal := func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			if user := ctx.Header("X-User"); user != "" {
+				ctx.SetContext(security.WithPrincipal(ctx.Context(), user))
+			}
+			next(ctx)
+		}
+	}
+	s.RegisterGroupRoute(web.WithMiddleware(web.ServeMux(mux, ""), principal), "foos")
+	t.handler = mux
+}
+
+func (t *_testAudit) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testAudit) do(method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testAudit) Test_ForgedAuditFields() {
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/foos", "", `{"data":{"name":"a","created_by":"mallory","updated_by":"mallory"}}`).Code)
+	var foo _testAuditFoo
+	t.NoError(t.db.First(&foo, 1).Error)
+	t.Empty(foo.CreatedBy, "created without a principal")
+	t.Empty(foo.UpdatedBy)
+	created := foo.CreatedAt
+
+	t.EqualValues(http.StatusOK, t.do(http.MethodPut, "/foos/1", "alice",
+		`{"data":{"name":"b","created_by":"mallory","created_at":"2000-01-01T00:00:00Z"}}`).Code)
+	t.NoError(t.db.First(&foo, 1).Error)
+	t.EqualValues("b", foo.Name)
+	t.Empty(foo.CreatedBy)
+	t.EqualValues("alice", foo.UpdatedBy)
+	t.True(created.Equal(foo.CreatedAt))
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, &_testAudit{})
+}
@@ -1,12 +1,14 @@
 package service
 
 import (
-	"errors"
-	"fmt"
-	"net/http"
+	"context"
+	"reflect"
+	"strings"
 
-	"github.com/gin-gonic/gin"
+	"github.com/go-gosh/gestful/component/event"
 	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/openapi"
+	"github.com/go-gosh/gestful/component/web"
 	"gorm.io/gorm"
 )
 
@@ -15,8 +17,34 @@ type PageRequest interface {
 	MakeWrapper() func(*gorm.DB) *gorm.DB
 }
 
+// SearchRequest page request with a search keyword, see mapper.ISearchMapper
+type SearchRequest interface {
+	MakeSearch() string
+}
+
+// FieldsRequest request with sparse fieldset, e.g. ?fields=id,name,owner.email
+type FieldsRequest interface {
+	MakeFields() []string
+}
+
+type BaseFieldsRequest struct {
+	Fields string `json:"fields" form:"fields"`
+}
+
+func (b BaseFieldsRequest) MakeFields() []string {
+	fields := make([]string, 0)
+	for _, field := range strings.Split(b.Fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
 type BasePageRequest struct {
 	mapper.Paginator
+	BaseFieldsRequest
+	Q string `json:"q" form:"q"`
 }
 
 const DefaultPageLimit = 10
@@ -37,6 +65,10 @@ func (b BasePageRequest) MakeWrapper() func(*gorm.DB) *gorm.DB {
 	return mapper.EmptyWrapperFunc
 }
 
+func (b BasePageRequest) MakeSearch() string {
+	return strings.TrimSpace(b.Q)
+}
+
 type CreateRequest[T any] interface {
 	MakeCreate() (*T, error)
 }
@@ -64,163 +96,204 @@ func (b BaseUpdateRequest) MakeUpdate() (map[string]interface{}, error) {
 	return b.Data, nil
 }
 
-func RegisterGroupRoute[T, U any](group *gin.RouterGroup, source string, s RestfulService[T, U]) {
-	group.GET(fmt.Sprintf("/%s", source), func(ctx *gin.Context) {
-		res, err := s.Paginate(ctx)
-		if err != nil {
-			ctx.AbortWithStatusJSON(500, err.Error())
-			return
-		}
-		ctx.JSON(200, res)
-	})
-	group.POST(fmt.Sprintf("/%s", source), handleErrorAdapter(s.Create))
-	group.GET(fmt.Sprintf("/%s/:id", source), func(ctx *gin.Context) {
-		res, err := s.Retrieve(ctx)
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ctx.AbortWithStatusJSON(http.StatusNotFound, "not found")
-			return
-		}
-		if err != nil {
-			ctx.AbortWithStatusJSON(500, err.Error())
-			return
-		}
-		ctx.JSON(200, res)
-	})
-	group.PUT(fmt.Sprintf("/%s/:id", source), handleErrorAdapter(s.Update))
-	group.DELETE(fmt.Sprintf("/%s/:id", source), handleErrorAdapter(s.Delete))
+// BatchUpdateRequest update request of the entity ID in a batch
+type BatchUpdateRequest struct {
+	ID uint
+	UpdateRequest
 }
 
 type RestfulService[T, U any] interface {
-	Create(ctx *gin.Context) error
-	Paginate(ctx *gin.Context) (*U, error)
-	Retrieve(ctx *gin.Context) (*T, error)
-	Update(ctx *gin.Context) error
-	Delete(ctx *gin.Context) error
+	Create(ctx context.Context, req CreateRequest[T]) error
+	Paginate(ctx context.Context, req PageRequest) (*U, error)
+	Retrieve(ctx context.Context, id uint, fields ...string) (*T, error)
+	Update(ctx context.Context, id uint, req UpdateRequest) error
+	Delete(ctx context.Context, id uint) error
+	// Fields validate the sparse fieldset of T
+	Fields(fields ...string) (*mapper.FieldSet, error)
+	// Hooks register the lifecycle hooks
+	Hooks() *Hooks[T]
+	// SetPolicy authorize every operation with policy, mode decides how denied by id operations are reported
+	SetPolicy(policy Policy[T], mode DenyMode)
+	// SetDispatcher dispatch the lifecycle events and the events registered by the entity of every write
+	SetDispatcher(dispatcher *event.Dispatcher)
+	// Aggregate aggregate the entities matched by the filters of req
+	Aggregate(ctx context.Context, req PageRequest, aggregation mapper.Aggregation) ([]mapper.AggregateRow, error)
+	// CreateBatch create in a single transaction, the created entities are in the order of reqs
+	CreateBatch(ctx context.Context, reqs []CreateRequest[T], mode mapper.BatchMode) ([]*T, []mapper.BatchResult, error)
+	// UpdateBatch update in a single transaction
+	UpdateBatch(ctx context.Context, reqs []BatchUpdateRequest, mode mapper.BatchMode) ([]mapper.BatchResult, error)
+	// DeleteBatch delete the entities of ids in a single transaction
+	DeleteBatch(ctx context.Context, ids []uint, mode mapper.BatchMode) ([]mapper.BatchResult, error)
+}
+
+// RequestBinder bind the typed requests of a RestfulService from the web.Context
+type RequestBinder[T any] interface {
+	BindCreate(ctx web.Context) (CreateRequest[T], error)
+	BindPage(ctx web.Context) (PageRequest, error)
+	BindUpdate(ctx web.Context) (UpdateRequest, error)
+	// BindCreateBatch bind {"data":[...]} whose items are create requests
+	BindCreateBatch(ctx web.Context) ([]CreateRequest[T], error)
+	// BindUpdateBatch bind {"data":[...]} whose items are update requests with the id of their entity
+	BindUpdateBatch(ctx web.Context) ([]BatchUpdateRequest, error)
+}
+
+// HTTPService RestfulService which is able to bind its requests
+type HTTPService[T, U any] interface {
+	RestfulService[T, U]
+	RequestBinder[T]
 }
 
 type BaseRestfulService[T any] interface {
-	RestfulService[T, mapper.PageRes[T]]
+	HTTPService[T, mapper.PageRes[T]]
+	RegisterGroupRoute(router web.Router, source string)
+	// Describe describe the routes of RegisterGroupRoute of source into doc
+	Describe(doc *openapi.Document, source string)
 }
 
 // NewBaseService new base restful service
 func NewBaseService[T any, U CreateRequest[T], V PageRequest, W UpdateRequest](mapper mapper.BaseMapper[T]) BaseRestfulService[T] {
-	return &baseService[T, U, V, W]{mapper: mapper}
+	return NewRestfulService[T, U, V, W](NewCrudService[T, uint](mapper))
+}
+
+// NewRestfulService restful service binding the requests to crud
+func NewRestfulService[T any, U CreateRequest[T], V PageRequest, W UpdateRequest](crud CrudService[T, uint]) BaseRestfulService[T] {
+	return &baseService[T, U, V, W]{crud: crud}
 }
 
 type baseService[T any, U CreateRequest[T], V PageRequest, W UpdateRequest] struct {
-	mapper mapper.BaseMapper[T]
+	crud CrudService[T, uint]
 }
 
-func handleErrorAdapter(handler func(*gin.Context) error) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		err := handler(ctx)
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ctx.AbortWithStatusJSON(http.StatusNotFound, "not found")
-			return
-		}
-		if err != nil {
-			ctx.AbortWithStatusJSON(500, err.Error())
-			return
-		}
-		ctx.JSON(200, "success")
-	}
+func (s baseService[T, U, V, W]) RegisterGroupRoute(router web.Router, source string) {
+	RegisterGroupRoute[T, mapper.PageRes[T]](router, source, s)
 }
 
-func (s baseService[T, U, V, W]) RegisterGroupRoute(group *gin.RouterGroup, source string) {
-	group.GET(fmt.Sprintf("/%s", source), func(ctx *gin.Context) {
-		res, err := s.Paginate(ctx)
-		if err != nil {
-			ctx.AbortWithStatusJSON(500, err.Error())
-			return
-		}
-		ctx.JSON(200, res)
-	})
-	group.POST(fmt.Sprintf("/%s", source), handleErrorAdapter(s.Create))
-	group.GET(fmt.Sprintf("/%s/:id", source), func(ctx *gin.Context) {
-		res, err := s.Retrieve(ctx)
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ctx.AbortWithStatusJSON(http.StatusNotFound, "not found")
-			return
-		}
-		if err != nil {
-			ctx.AbortWithStatusJSON(500, err.Error())
-			return
-		}
-		ctx.JSON(200, res)
-	})
-	group.PUT(fmt.Sprintf("/%s/:id", source), handleErrorAdapter(s.Update))
-	group.DELETE(fmt.Sprintf("/%s/:id", source), handleErrorAdapter(s.Delete))
+func (s baseService[T, U, V, W]) Describe(doc *openapi.Document, source string) {
+	DescribeRoute[T, U, V, W, mapper.PageRes[T]](doc, source)
 }
 
-func (s baseService[T, U, V, W]) Create(ctx *gin.Context) error {
+func (s baseService[T, U, V, W]) BindCreate(ctx web.Context) (CreateRequest[T], error) {
 	var req U
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		return err
-	}
-	create, err := req.MakeCreate()
-	if err != nil {
-		return err
-	}
-	if err := s.mapper.Create(ctx, create); err != nil {
-		return err
+	if err := ctx.BindJSON(&req); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return req, nil
 }
 
-func (s baseService[T, U, V, W]) Paginate(ctx *gin.Context) (*mapper.PageRes[T], error) {
+func (s baseService[T, U, V, W]) BindPage(ctx web.Context) (PageRequest, error) {
 	var req V
-	err := ctx.ShouldBindQuery(&req)
-	if err != nil {
+	if err := ctx.BindQuery(&req); err != nil {
 		return nil, err
 	}
+	return req, nil
+}
 
-	res, err := s.mapper.Paginate(ctx, req.MakePage(), req.MakeWrapper())
-	if err != nil {
+func (s baseService[T, U, V, W]) BindUpdate(ctx web.Context) (UpdateRequest, error) {
+	var req W
+	if err := ctx.BindJSON(&req); err != nil {
 		return nil, err
 	}
+	return req, nil
+}
 
-	return res, nil
+func (s baseService[T, U, V, W]) BindCreateBatch(ctx web.Context) ([]CreateRequest[T], error) {
+	return bindCreateBatch[T](ctx, reflect.TypeFor[U]())
 }
 
-func (s baseService[T, U, V, W]) Retrieve(ctx *gin.Context) (*T, error) {
-	id := struct {
-		ID uint `uri:"id"`
-	}{}
-	if err := ctx.ShouldBindUri(&id); err != nil {
-		return nil, err
-	}
+func (s baseService[T, U, V, W]) BindUpdateBatch(ctx web.Context) ([]BatchUpdateRequest, error) {
+	return bindUpdateBatch(ctx, reflect.TypeFor[W]())
+}
 
-	return s.mapper.OneById(ctx, id.ID)
+func (s baseService[T, U, V, W]) Fields(fields ...string) (*mapper.FieldSet, error) {
+	return s.crud.Fields(fields...)
 }
 
-func (s baseService[T, U, V, W]) Update(ctx *gin.Context) error {
-	id := struct {
-		ID uint `uri:"id"`
-	}{}
-	if err := ctx.ShouldBindUri(&id); err != nil {
+func (s baseService[T, U, V, W]) Hooks() *Hooks[T] {
+	return s.crud.Hooks()
+}
+
+func (s baseService[T, U, V, W]) SetPolicy(policy Policy[T], mode DenyMode) {
+	s.crud.SetPolicy(policy, mode)
+}
+
+func (s baseService[T, U, V, W]) SetDispatcher(dispatcher *event.Dispatcher) {
+	s.crud.SetDispatcher(dispatcher)
+}
+
+func (s baseService[T, U, V, W]) Create(ctx context.Context, req CreateRequest[T]) error {
+	create, err := req.MakeCreate()
+	if err != nil {
 		return err
 	}
-	var req W
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		return err
+
+	return s.crud.Create(ctx, create)
+}
+
+// makeQuery the list query of req
+func makeQuery(req PageRequest) Query {
+	query := Query{
+		Paginator: req.MakePage(),
+		Wrapper:   req.MakeWrapper(),
 	}
+	if search, ok := req.(SearchRequest); ok {
+		query.Search = search.MakeSearch()
+	}
+	if fields, ok := req.(FieldsRequest); ok {
+		query.Fields = fields.MakeFields()
+	}
+	return query
+}
+
+func (s baseService[T, U, V, W]) Paginate(ctx context.Context, req PageRequest) (*mapper.PageRes[T], error) {
+	return s.crud.List(ctx, makeQuery(req))
+}
 
+func (s baseService[T, U, V, W]) Aggregate(ctx context.Context, req PageRequest, aggregation mapper.Aggregation) ([]mapper.AggregateRow, error) {
+	return s.crud.Aggregate(ctx, aggregation, makeQuery(req))
+}
+
+func (s baseService[T, U, V, W]) Retrieve(ctx context.Context, id uint, fields ...string) (*T, error) {
+	return s.crud.Get(ctx, id, fields...)
+}
+
+func (s baseService[T, U, V, W]) Update(ctx context.Context, id uint, req UpdateRequest) error {
 	updated, err := req.MakeUpdate()
 	if err != nil {
 		return err
 	}
 
-	return s.mapper.UpdateById(ctx, id.ID, updated)
+	return s.crud.Patch(ctx, id, updated)
 }
 
-func (s baseService[T, U, V, W]) Delete(ctx *gin.Context) error {
-	id := struct {
-		ID uint `uri:"id"`
-	}{}
-	if err := ctx.ShouldBindUri(&id); err != nil {
-		return err
+func (s baseService[T, U, V, W]) Delete(ctx context.Context, id uint) error {
+	return s.crud.Delete(ctx, id)
+}
+
+func (s baseService[T, U, V, W]) CreateBatch(ctx context.Context, reqs []CreateRequest[T], mode mapper.BatchMode) ([]*T, []mapper.BatchResult, error) {
+	entities := make([]*T, 0, len(reqs))
+	for _, req := range reqs {
+		entity, err := req.MakeCreate()
+		if err != nil {
+			return nil, nil, err
+		}
+		entities = append(entities, entity)
+	}
+	res, err := s.crud.CreateBatch(ctx, entities, mode)
+	return entities, res, err
+}
+
+func (s baseService[T, U, V, W]) UpdateBatch(ctx context.Context, reqs []BatchUpdateRequest, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	patches := make([]Patch[uint], 0, len(reqs))
+	for _, req := range reqs {
+		changes, err := req.MakeUpdate()
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, Patch[uint]{ID: req.ID, Changes: changes})
 	}
+	return s.crud.PatchBatch(ctx, patches, mode)
+}
 
-	return s.mapper.DeleteById(ctx, id.ID)
+func (s baseService[T, U, V, W]) DeleteBatch(ctx context.Context, ids []uint, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return s.crud.DeleteBatch(ctx, ids, mode)
 }
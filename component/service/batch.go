@@ -0,0 +1,216 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// MaxBatchSize max items of a batch request
+const MaxBatchSize = 1000
+
+// ErrBadRequest the request is malformed
+var ErrBadRequest = errors.New("bad request")
+
+// BatchResponse response of the batch routes, Results are in the order of the request
+type BatchResponse struct {
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+	Results   []BatchItem `json:"results"`
+}
+
+// BatchItem result of an item of a batch request
+type BatchItem struct {
+	Index  int         `json:"index"`
+	Status int         `json:"status"`
+	ID     uint        `json:"id,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+type batchBody struct {
+	Data []json.RawMessage `json:"data" binding:"required"`
+}
+
+func bindBatchBody(ctx web.Context) ([]json.RawMessage, error) {
+	var body batchBody
+	if err := ctx.BindJSON(&body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	if len(body.Data) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: more than %d items", ErrBadRequest, MaxBatchSize)
+	}
+	return body.Data, nil
+}
+
+func bindCreateBatch[T any](ctx web.Context, t reflect.Type) ([]CreateRequest[T], error) {
+	items, err := bindBatchBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]CreateRequest[T], 0, len(items))
+	for i, item := range items {
+		req, err := bind[CreateRequest[T]](t, func(v interface{}) error {
+			return web.DecodeJSON(item, v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: data[%d]: %v", ErrBadRequest, i, err)
+		}
+		res = append(res, req)
+	}
+	return res, nil
+}
+
+func bindUpdateBatch(ctx web.Context, t reflect.Type) ([]BatchUpdateRequest, error) {
+	items, err := bindBatchBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]BatchUpdateRequest, 0, len(items))
+	for i, item := range items {
+		var id struct {
+			ID uint `json:"id"`
+		}
+		if err := json.Unmarshal(item, &id); err != nil || id.ID == 0 {
+			return nil, fmt.Errorf("%w: data[%d]: missing id", ErrBadRequest, i)
+		}
+		req, err := bind[UpdateRequest](t, func(v interface{}) error {
+			return web.DecodeJSON(item, v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: data[%d]: %v", ErrBadRequest, i, err)
+		}
+		res = append(res, BatchUpdateRequest{ID: id.ID, UpdateRequest: req})
+	}
+	return res, nil
+}
+
+// bindBatchMode the mode query, atomic by default
+func bindBatchMode(ctx web.Context) (mapper.BatchMode, error) {
+	switch ctx.Query("mode") {
+	case "", "atomic":
+		return mapper.BatchAtomic, nil
+	case "partial":
+		return mapper.BatchPartial, nil
+	default:
+		return 0, fmt.Errorf("%w: unknown mode %s", ErrBadRequest, ctx.Query("mode"))
+	}
+}
+
+// bindIDs the ids query, e.g. ?ids=1,2,3
+func bindIDs(ctx web.Context) ([]uint, error) {
+	ids := make([]uint, 0)
+	for _, v := range strings.Split(ctx.Query("ids"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid id %s", ErrBadRequest, v)
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: missing ids", ErrBadRequest)
+	}
+	if len(ids) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: more than %d items", ErrBadRequest, MaxBatchSize)
+	}
+	return ids, nil
+}
+
+// writeBatch write the results of a batch, the status is the one of the failed item when an atomic batch fails,
+// 207 when some items of a partial batch fail and 200 otherwise. fill completes the items
+func writeBatch(ctx web.Context, results []mapper.BatchResult, err error, fill func(i int, item *BatchItem)) {
+	if results == nil && err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	res := BatchResponse{Results: make([]BatchItem, 0, len(results))}
+	for _, result := range results {
+		item := BatchItem{Index: result.Index, Status: http.StatusOK}
+		if result.Err != nil {
+			item.Status, item.Error = errorStatus(result.Err)
+			res.Failed++
+		} else {
+			res.Succeeded++
+		}
+		fill(result.Index, &item)
+		res.Results = append(res.Results, item)
+	}
+	switch {
+	case err != nil:
+		code, _ := errorStatus(err)
+		ctx.JSON(code, res)
+	case res.Failed > 0:
+		ctx.JSON(http.StatusMultiStatus, res)
+	default:
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+func handleCreateBatch[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		mode, err := bindBatchMode(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		reqs, err := s.BindCreateBatch(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		entities, res, err := s.CreateBatch(ctx.Context(), reqs, mode)
+		writeBatch(ctx, res, err, func(i int, item *BatchItem) {
+			if item.Status == http.StatusOK {
+				item.Data = entities[i]
+			}
+		})
+	}
+}
+
+func handleUpdateBatch[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		mode, err := bindBatchMode(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		reqs, err := s.BindUpdateBatch(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := s.UpdateBatch(ctx.Context(), reqs, mode)
+		writeBatch(ctx, res, err, func(i int, item *BatchItem) {
+			item.ID = reqs[i].ID
+		})
+	}
+}
+
+func handleDeleteBatch[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		mode, err := bindBatchMode(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		ids, err := bindIDs(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := s.DeleteBatch(ctx.Context(), ids, mode)
+		writeBatch(ctx, res, err, func(i int, item *BatchItem) {
+			item.ID = ids[i]
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testBatch struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testBatch) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	s := NewBaseService[_testFoo, BaseCreateRequest[_testFoo], BasePageRequest, BaseUpdateRequest](mapper.NewBaseMapper[_testFoo](t.db))
+	s.Hooks().BeforeCreate(func(ctx context.Context, entity *_testFoo) error {
+		if entity.Name == "bad" {
+			return Veto("create", "bad name")
+		}
+		return nil
+	})
+	mux := http.NewServeMux()
+	s.RegisterGroupRoute(web.ServeMux(mux, ""), "foos")
+	t.handler = mux
+}
+
+func (t *_testBatch) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testBatch) do(method, path, body string) (int, BatchResponse) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	var res BatchResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func (t *_testBatch) count() int64 {
+	var count int64
+	t.Require().NoError(t.db.Model(&_testFoo{}).Count(&count).Error)
+	return count
+}
+
+func (t *_testBatch) Test_CreateBatch_Atomic() {
+	code, res := t.do(http.MethodPost, "/foos/batch", `{"data":[{"data":{"name":"a"}},{"data":{"name":"bad"}},{"data":{"name":"c"}}]}`)
+	t.EqualValues(http.StatusUnprocessableEntity, code)
+	t.Equal(0, res.Succeeded)
+	t.Equal(3, res.Failed)
+	t.Equal([]int{http.StatusFailedDependency, http.StatusUnprocessableEntity, http.StatusFailedDependency},
+		[]int{res.Results[0].Status, res.Results[1].Status, res.Results[2].Status})
+	t.EqualValues(0, t.count())
+}
+
+func (t *_testBatch) Test_CreateBatch_Partial() {
+	code, res := t.do(http.MethodPost, "/foos/batch?mode=partial", `{"data":[{"data":{"name":"a"}},{"data":{"name":"bad"}},{"data":{"name":"c"}}]}`)
+	t.EqualValues(http.StatusMultiStatus, code)
+	t.Equal(2, res.Succeeded)
+	t.Equal(1, res.Failed)
+	t.EqualValues(http.StatusOK, res.Results[0].Status)
+	t.NotNil(res.Results[0].Data)
+	t.EqualValues(http.StatusUnprocessableEntity, res.Results[1].Status)
+	t.NotEmpty(res.Results[1].Error)
+	t.Nil(res.Results[1].Data)
+	t.EqualValues(2, t.count())
+}
+
+func (t *_testBatch) Test_UpdateAndDeleteBatch() {
+	code, _ := t.do(http.MethodPost, "/foos/batch", `{"data":[{"data":{"name":"a"}},{"data":{"name":"b"}}]}`)
+	t.Require().EqualValues(http.StatusOK, code)
+
+	code, res := t.do(http.MethodPatch, "/foos/batch", `{"data":[{"id":1,"data":{"name":"x"}},{"id":2,"data":{"name":"y"}}]}`)
+	t.EqualValues(http.StatusOK, code)
+	t.Equal(2, res.Succeeded)
+	var foo _testFoo
+	t.Require().NoError(t.db.First(&foo, 2).Error)
+	t.Equal("y", foo.Name)
+
+	code, _ = t.do(http.MethodPatch, "/foos/batch", `{"data":[{"data":{"name":"x"}}]}`)
+	t.EqualValues(http.StatusBadRequest, code)
+
+	code, res = t.do(http.MethodDelete, "/foos?ids=1,3&mode=partial", "")
+	t.EqualValues(http.StatusMultiStatus, code)
+	t.EqualValues(1, res.Results[0].ID)
+	t.EqualValues(http.StatusNotFound, res.Results[1].Status)
+	t.EqualValues(1, t.count())
+
+	code, _ = t.do(http.MethodDelete, "/foos?ids=a", "")
+	t.EqualValues(http.StatusBadRequest, code)
+}
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, &_testBatch{})
+}
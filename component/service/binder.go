@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// NewRequestBinder RequestBinder binding the requests of the types of create, page and update
+func NewRequestBinder[T any](create CreateRequest[T], page PageRequest, update UpdateRequest) RequestBinder[T] {
+	return &requestBinder[T]{
+		create: reflect.TypeOf(create),
+		page:   reflect.TypeOf(page),
+		update: reflect.TypeOf(update),
+	}
+}
+
+type requestBinder[T any] struct {
+	create reflect.Type
+	page   reflect.Type
+	update reflect.Type
+}
+
+// bind decode the request of the type t by fn, t may be a pointer type
+func bind[R any](t reflect.Type, fn func(v interface{}) error) (R, error) {
+	var res R
+	elem := t
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	v := reflect.New(elem)
+	if err := fn(v.Interface()); err != nil {
+		return res, err
+	}
+	if t.Kind() != reflect.Pointer {
+		v = v.Elem()
+	}
+	res, ok := v.Interface().(R)
+	if !ok {
+		return res, fmt.Errorf("%s is not a %s", t, reflect.TypeFor[R]())
+	}
+	return res, nil
+}
+
+func (b *requestBinder[T]) BindCreate(ctx web.Context) (CreateRequest[T], error) {
+	return bind[CreateRequest[T]](b.create, ctx.BindJSON)
+}
+
+func (b *requestBinder[T]) BindPage(ctx web.Context) (PageRequest, error) {
+	return bind[PageRequest](b.page, ctx.BindQuery)
+}
+
+func (b *requestBinder[T]) BindUpdate(ctx web.Context) (UpdateRequest, error) {
+	return bind[UpdateRequest](b.update, ctx.BindJSON)
+}
+
+func (b *requestBinder[T]) BindCreateBatch(ctx web.Context) ([]CreateRequest[T], error) {
+	return bindCreateBatch[T](ctx, b.create)
+}
+
+func (b *requestBinder[T]) BindUpdateBatch(ctx web.Context) ([]BatchUpdateRequest, error) {
+	return bindUpdateBatch(ctx, b.update)
+}
+
+// NewHTTPService HTTPService of s binding its requests by binder
+func NewHTTPService[T, U any](s RestfulService[T, U], binder RequestBinder[T]) HTTPService[T, U] {
+	return &httpService[T, U]{RestfulService: s, RequestBinder: binder}
+}
+
+type httpService[T, U any] struct {
+	RestfulService[T, U]
+	RequestBinder[T]
+}
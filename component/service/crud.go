@@ -0,0 +1,439 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-gosh/gestful/component/event"
+	"github.com/go-gosh/gestful/component/mapper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Query list query of CrudService
+type Query struct {
+	mapper.Paginator
+	// Search keyword, see mapper.ISearchMapper
+	Search string
+	// Fields sparse fieldset, see mapper.IQueryMapper.Fields
+	Fields []string
+	// Wrapper extra conditions, nil means no condition
+	Wrapper func(*gorm.DB) *gorm.DB
+}
+
+// CrudService transport agnostic service of T identified by ID,
+// it is reusable from http, grpc, cli jobs and tests
+type CrudService[T, ID any] interface {
+	Create(ctx context.Context, entity *T) error
+	// Get the entity of id, fields are validated and their relations preloaded but the entity is complete,
+	// so the policy, the hooks and the entity tag see every column, shape the response by Fields(fields...).Shape
+	Get(ctx context.Context, id ID, fields ...string) (*T, error)
+	List(ctx context.Context, query Query) (*mapper.PageRes[T], error)
+	Patch(ctx context.Context, id ID, changes map[string]interface{}) error
+	Delete(ctx context.Context, id ID) error
+	// Fields validate the sparse fieldset of T
+	Fields(fields ...string) (*mapper.FieldSet, error)
+	// Hooks register the lifecycle hooks
+	Hooks() *Hooks[T]
+	// SetPolicy authorize every operation with policy, mode decides how denied by id operations are reported
+	SetPolicy(policy Policy[T], mode DenyMode)
+	// SetDispatcher dispatch the lifecycle events and the events registered by the entity of every write
+	SetDispatcher(dispatcher *event.Dispatcher)
+	// Authorize op of an entity which is not loaded from the mapper, e.g. the last revision of a deleted entity,
+	// by the policy and by its scope evaluated on the values of entity, the denial is reported as Get reports it
+	Authorize(ctx context.Context, op Operation, entity *T) error
+	// Aggregate aggregate the entities matched by the wrapper and the search of query, see mapper.IQueryMapper.Aggregate,
+	// the groups are limited by the limit of aggregation and MaxPageLimit, the page limit of query is ignored as it
+	// always has a default. Without a limit, more than MaxPageLimit groups are an ErrBadRequest rather than truncated
+	Aggregate(ctx context.Context, aggregation mapper.Aggregation, query Query) ([]mapper.AggregateRow, error)
+	// CreateBatch Create every entity in a single transaction, see mapper.Batch
+	CreateBatch(ctx context.Context, entities []*T, mode mapper.BatchMode) ([]mapper.BatchResult, error)
+	// PatchBatch Patch every entity in a single transaction, see mapper.Batch
+	PatchBatch(ctx context.Context, patches []Patch[ID], mode mapper.BatchMode) ([]mapper.BatchResult, error)
+	// DeleteBatch Delete every entity in a single transaction, see mapper.Batch
+	DeleteBatch(ctx context.Context, ids []ID, mode mapper.BatchMode) ([]mapper.BatchResult, error)
+}
+
+// Patch changes of the entity ID in a batch
+type Patch[ID any] struct {
+	ID      ID
+	Changes map[string]interface{}
+}
+
+// NewCrudService crud service over mapper
+func NewCrudService[T, ID any](mapper mapper.BaseMapper[T]) CrudService[T, ID] {
+	return &crudService[T, ID]{mapper: mapper, hooks: &Hooks[T]{}, config: &config[T]{policy: allowAllPolicy[T]{}}}
+}
+
+type crudService[T, ID any] struct {
+	mapper mapper.BaseMapper[T]
+	hooks  *Hooks[T]
+	config *config[T]
+}
+
+type config[T any] struct {
+	policy     Policy[T]
+	mode       DenyMode
+	dispatcher *event.Dispatcher
+}
+
+func wrapperFuncById[ID any](id ID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("id=?", id)
+	}
+}
+
+func (s crudService[T, ID]) Hooks() *Hooks[T] {
+	return s.hooks
+}
+
+func (s crudService[T, ID]) SetPolicy(policy Policy[T], mode DenyMode) {
+	s.config.policy = policy
+	s.config.mode = mode
+}
+
+func (s crudService[T, ID]) SetDispatcher(dispatcher *event.Dispatcher) {
+	s.config.dispatcher = dispatcher
+}
+
+type pendingKey struct{}
+
+// pendingEvents the events of a batch, they are dispatched after the batch commits
+type pendingEvents struct {
+	events []event.Event
+}
+
+// write run fn in a transaction and dispatch the events it collects
+func (s crudService[T, ID]) write(ctx context.Context, fn func(ctx context.Context, collect func(entity *T, action string)) error) error {
+	var events []event.Event
+	err := s.mapper.Transaction(ctx, func(ctx context.Context) error {
+		events = events[:0]
+		if err := fn(ctx, func(entity *T, action string) {
+			events = append(events, event.Collect(entity, action)...)
+		}); err != nil {
+			return err
+		}
+		if s.config.dispatcher == nil {
+			return nil
+		}
+		tx, _ := mapper.TxFromContext(ctx)
+		return s.config.dispatcher.InTransaction(ctx, tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	if pending, ok := ctx.Value(pendingKey{}).(*pendingEvents); ok {
+		pending.events = append(pending.events, events...)
+		return nil
+	}
+	if s.config.dispatcher != nil {
+		s.config.dispatcher.AfterCommit(ctx, events...)
+	}
+	return nil
+}
+
+// batch run fn for the n items in a single transaction and dispatch the events of the succeeded items after it commits
+func (s crudService[T, ID]) batch(ctx context.Context, n int, mode mapper.BatchMode, fn func(ctx context.Context, i int) error) ([]mapper.BatchResult, error) {
+	pending := &pendingEvents{}
+	res, err := mapper.Batch(context.WithValue(ctx, pendingKey{}, pending), s.mapper.Transaction, n, mode, fn)
+	if err != nil {
+		return res, err
+	}
+	if s.config.dispatcher != nil {
+		s.config.dispatcher.AfterCommit(ctx, pending.events...)
+	}
+	return res, nil
+}
+
+func (s crudService[T, ID]) scope(ctx context.Context, op Operation, wrapper func(*gorm.DB) *gorm.DB) func(*gorm.DB) *gorm.DB {
+	if scope := s.config.policy.Scope(ctx, op); scope != nil {
+		return mapper.ChainWrapperFunc(wrapper, scope)
+	}
+	return wrapper
+}
+
+func (s crudService[T, ID]) deny(err error) error {
+	if errors.Is(err, ErrForbidden) && s.config.mode == DenyNotFound {
+		return gorm.ErrRecordNotFound
+	}
+	return err
+}
+
+func (s crudService[T, ID]) Authorize(ctx context.Context, op Operation, entity *T) error {
+	if scope := s.config.policy.Scope(ctx, op); scope != nil {
+		count, err := s.mapper.Count(ctx, func(db *gorm.DB) *gorm.DB {
+			return scope(valuesOf(ctx, db, entity))
+		})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return s.deny(ErrForbidden)
+		}
+	}
+	if err := s.config.policy.Authorize(ctx, op, entity); err != nil {
+		return s.deny(err)
+	}
+	return nil
+}
+
+// valuesOf query the single row of the values of entity in place of its table,
+// so that the conditions of db, e.g. the scope of a policy and the tenant of the mapper, are evaluated on entity
+func valuesOf[T any](ctx context.Context, db *gorm.DB, entity *T) *gorm.DB {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	columns := make([]string, 0, len(stmt.Schema.DBNames))
+	values := make([]interface{}, 0, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		value, _ := stmt.Schema.FieldsByDBName[name].ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		columns = append(columns, "? AS "+db.Statement.Quote(name))
+		values = append(values, value)
+	}
+	table := "(SELECT " + strings.Join(columns, ", ") + ") AS " + db.Statement.Quote(stmt.Schema.Table)
+	return db.Unscoped().Table(table, values...)
+}
+
+// load the entity of id for op, it is denied when filtered by the scope or the policy
+func (s crudService[T, ID]) load(ctx context.Context, op Operation, id ID, wrapper func(*gorm.DB) *gorm.DB) (*T, error) {
+	entity, err := s.mapper.One(ctx, s.scope(ctx, op, mapper.ChainWrapperFunc(wrapperFuncById(id), wrapper)))
+	if errors.Is(err, gorm.ErrRecordNotFound) && s.config.mode == DenyForbidden && s.config.policy.Scope(ctx, op) != nil {
+		count, cerr := s.mapper.Count(ctx, wrapperFuncById(id))
+		if cerr != nil {
+			return nil, cerr
+		}
+		if count > 0 {
+			return nil, ErrForbidden
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.config.policy.Authorize(ctx, op, entity); err != nil {
+		return nil, s.deny(err)
+	}
+	return entity, nil
+}
+
+// lock load the entity of id to write in the transaction of ctx and check the If-Match precondition of ctx,
+// the entity is locked until the transaction ends when there is a precondition, see WithIfMatch
+func (s crudService[T, ID]) lock(ctx context.Context, op Operation, id ID) (*T, error) {
+	match, ok := ifMatch(ctx)
+	if !ok {
+		return s.load(ctx, op, id, mapper.EmptyWrapperFunc)
+	}
+	entity, err := s.load(ctx, op, id, func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Locking{Strength: "UPDATE"})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPreconditionFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the entity tag of the representation retrieved by Get
+	current := *entity
+	if err := runHooks(ctx, s.hooks.afterLoad, &current); err != nil {
+		return nil, err
+	}
+	etag, err := ETag(&current, &current)
+	if err != nil {
+		return nil, err
+	}
+	if !matchETag(match, etag, false) {
+		return nil, ErrPreconditionFailed
+	}
+	return entity, nil
+}
+
+func (s crudService[T, ID]) Create(ctx context.Context, entity *T) error {
+	return s.write(ctx, func(ctx context.Context, collect func(*T, string)) error {
+		if err := runHooks(ctx, s.hooks.beforeCreate, entity); err != nil {
+			return err
+		}
+		// the entity is authorized as it is created, after the hooks which may change it
+		if err := s.config.policy.Authorize(ctx, OperationCreate, entity); err != nil {
+			return err
+		}
+		if err := s.mapper.Create(ctx, entity); err != nil {
+			return err
+		}
+		if err := runHooks(ctx, s.hooks.afterCreate, entity); err != nil {
+			return err
+		}
+		collect(entity, event.ActionCreated)
+		return nil
+	})
+}
+
+func (s crudService[T, ID]) Get(ctx context.Context, id ID, fields ...string) (*T, error) {
+	wrapper := mapper.EmptyWrapperFunc
+	if len(fields) > 0 {
+		fs, err := s.mapper.Fields(fields...)
+		if err != nil {
+			return nil, err
+		}
+		wrapper = fs.Relations()
+	}
+
+	entity, err := s.load(ctx, OperationGet, id, wrapper)
+	if err != nil {
+		return nil, err
+	}
+	if err := runHooks(ctx, s.hooks.afterLoad, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// where the wrapper of the list operations of query
+func (s crudService[T, ID]) where(ctx context.Context, query Query) func(*gorm.DB) *gorm.DB {
+	wrapper := mapper.EmptyWrapperFunc
+	if query.Wrapper != nil {
+		wrapper = query.Wrapper
+	}
+	wrapper = s.scope(ctx, OperationList, wrapper)
+	if query.Search != "" {
+		wrapper = mapper.ChainWrapperFunc(wrapper, mapper.SearchWrapperFunc(s.mapper, query.Search))
+	}
+	return wrapper
+}
+
+func (s crudService[T, ID]) List(ctx context.Context, query Query) (*mapper.PageRes[T], error) {
+	if err := s.config.policy.Authorize(ctx, OperationList, nil); err != nil {
+		return nil, err
+	}
+	wrapper := s.where(ctx, query)
+	if len(query.Fields) > 0 {
+		fs, err := s.mapper.Fields(query.Fields...)
+		if err != nil {
+			return nil, err
+		}
+		wrapper = mapper.ChainWrapperFunc(wrapper, fs.Wrapper())
+	}
+
+	res, err := s.mapper.Paginate(ctx, query.Paginator, wrapper)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Data {
+		if err := runHooks(ctx, s.hooks.afterLoad, &res.Data[i]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (s crudService[T, ID]) Aggregate(ctx context.Context, aggregation mapper.Aggregation, query Query) ([]mapper.AggregateRow, error) {
+	if err := s.config.policy.Authorize(ctx, OperationList, nil); err != nil {
+		return nil, err
+	}
+	limited := aggregation.Limit > 0
+	if !limited {
+		aggregation = aggregation.WithLimit(MaxPageLimit + 1)
+	} else if aggregation.Limit > MaxPageLimit {
+		aggregation = aggregation.WithLimit(MaxPageLimit)
+	}
+	res, err := s.mapper.Aggregate(ctx, aggregation, s.where(ctx, query))
+	if err != nil {
+		return nil, err
+	}
+	if !limited && len(res) > MaxPageLimit {
+		return nil, fmt.Errorf("%w: more than %d groups, set a limit", ErrBadRequest, MaxPageLimit)
+	}
+	return res, nil
+}
+
+func (s crudService[T, ID]) Patch(ctx context.Context, id ID, changes map[string]interface{}) error {
+	return s.write(ctx, func(ctx context.Context, collect func(*T, string)) error {
+		entity, err := s.lock(ctx, OperationUpdate, id)
+		if err != nil {
+			return err
+		}
+		if err := runUpdateHooks(ctx, s.hooks.beforeUpdate, entity, changes); err != nil {
+			return err
+		}
+		if err := s.mapper.Update(ctx, wrapperFuncById(id), changes); err != nil {
+			return err
+		}
+		updated, err := s.updated(ctx, id)
+		if err != nil {
+			return err
+		}
+		if agg, ok := any(entity).(event.Aggregate); ok {
+			if dst, ok := any(updated).(interface{ RegisterEvent(event.Event) }); ok {
+				for _, e := range agg.DomainEvents() {
+					dst.RegisterEvent(e)
+				}
+			}
+		}
+		if err := runHooks(ctx, s.hooks.afterUpdate, updated); err != nil {
+			return err
+		}
+		collect(updated, event.ActionUpdated)
+		return nil
+	})
+}
+
+// updated reload the entity of id after its update in the transaction of ctx, the update is denied when
+// the changes move the entity out of the scope of the principal or the policy does not authorize the result
+func (s crudService[T, ID]) updated(ctx context.Context, id ID) (*T, error) {
+	updated, err := s.mapper.One(ctx, s.scope(ctx, OperationUpdate, wrapperFuncById(id)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.config.policy.Authorize(ctx, OperationUpdate, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s crudService[T, ID]) Delete(ctx context.Context, id ID) error {
+	return s.write(ctx, func(ctx context.Context, collect func(*T, string)) error {
+		entity, err := s.lock(ctx, OperationDelete, id)
+		if err != nil {
+			return err
+		}
+		if err := runHooks(ctx, s.hooks.beforeDelete, entity); err != nil {
+			return err
+		}
+		if err := s.mapper.Delete(ctx, wrapperFuncById(id)); err != nil {
+			return err
+		}
+		if err := runHooks(ctx, s.hooks.afterDelete, entity); err != nil {
+			return err
+		}
+		collect(entity, event.ActionDeleted)
+		return nil
+	})
+}
+
+func (s crudService[T, ID]) Fields(fields ...string) (*mapper.FieldSet, error) {
+	return s.mapper.Fields(fields...)
+}
+
+func (s crudService[T, ID]) CreateBatch(ctx context.Context, entities []*T, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return s.batch(ctx, len(entities), mode, func(ctx context.Context, i int) error {
+		return s.Create(ctx, entities[i])
+	})
+}
+
+func (s crudService[T, ID]) PatchBatch(ctx context.Context, patches []Patch[ID], mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return s.batch(ctx, len(patches), mode, func(ctx context.Context, i int) error {
+		return s.Patch(ctx, patches[i].ID, patches[i].Changes)
+	})
+}
+
+func (s crudService[T, ID]) DeleteBatch(ctx context.Context, ids []ID, mode mapper.BatchMode) ([]mapper.BatchResult, error) {
+	return s.batch(ctx, len(ids), mode, func(ctx context.Context, i int) error {
+		return s.Delete(ctx, ids[i])
+	})
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testFoo struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `json:"name" gestful:"search"`
+}
+
+type _testCrudService struct {
+	suite.Suite
+	db      *gorm.DB
+	service CrudService[_testFoo, uint]
+}
+
+func (t *_testCrudService) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testFoo{}))
+	t.service = NewCrudService[_testFoo, uint](mapper.NewBaseMapper[_testFoo](t.db))
+}
+
+func (t *_testCrudService) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testCrudService) Test_CreateAndGet() {
+	ctx := context.TODO()
+	foo := _testFoo{Name: "alice"}
+	t.NoError(t.service.Create(ctx, &foo))
+	t.NotZero(foo.ID)
+	res, err := t.service.Get(ctx, foo.ID)
+	t.NoError(err)
+	t.EqualValues("alice", res.Name)
+	_, err = t.service.Get(ctx, foo.ID+1)
+	t.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func (t *_testCrudService) Test_List() {
+	ctx := context.TODO()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		t.Require().NoError(t.service.Create(ctx, &_testFoo{Name: name}))
+	}
+	res, err := t.service.List(ctx, Query{Paginator: mapper.Paginator{Limit: 2}})
+	t.NoError(err)
+	t.True(res.More)
+	t.Len(res.Data, 2)
+	res, err = t.service.List(ctx, Query{Paginator: mapper.Paginator{Limit: 2}, Search: "bo"})
+	t.NoError(err)
+	t.False(res.More)
+	t.Len(res.Data, 1)
+	_, err = t.service.List(ctx, Query{Paginator: mapper.Paginator{Limit: 2}, Fields: []string{"password"}})
+	t.ErrorIs(err, mapper.ErrInvalidField)
+}
+
+func (t *_testCrudService) Test_PatchAndDelete() {
+	ctx := context.TODO()
+	foo := _testFoo{Name: "alice"}
+	t.Require().NoError(t.service.Create(ctx, &foo))
+	t.NoError(t.service.Patch(ctx, foo.ID, map[string]interface{}{"name": "bob"}))
+	res, err := t.service.Get(ctx, foo.ID)
+	t.NoError(err)
+	t.EqualValues("bob", res.Name)
+	t.NoError(t.service.Delete(ctx, foo.ID))
+	t.ErrorIs(t.service.Delete(ctx, foo.ID), gorm.ErrRecordNotFound)
+	t.ErrorIs(t.service.Patch(ctx, foo.ID, map[string]interface{}{"name": "bob"}), gorm.ErrRecordNotFound)
+}
+
+func (t *_testCrudService) Test_Hooks_MutateEntity() {
+	ctx := context.TODO()
+	t.service.Hooks().
+		BeforeCreate(func(ctx context.Context, entity *_testFoo) error {
+			entity.Name = "owner:" + entity.Name
+			return nil
+		}).
+		BeforeUpdate(func(ctx context.Context, entity *_testFoo, changes map[string]interface{}) error {
+			changes["name"] = entity.Name + ":updated"
+			return nil
+		}).
+		AfterLoad(func(ctx context.Context, entity *_testFoo) error {
+			entity.Name += ":loaded"
+			return nil
+		})
+	foo := _testFoo{Name: "alice"}
+	t.NoError(t.service.Create(ctx, &foo))
+	res, err := t.service.Get(ctx, foo.ID)
+	t.NoError(err)
+	t.EqualValues("owner:alice:loaded", res.Name)
+	t.NoError(t.service.Patch(ctx, foo.ID, map[string]interface{}{}))
+	page, err := t.service.List(ctx, Query{Paginator: mapper.Paginator{Limit: 10}})
+	t.NoError(err)
+	t.EqualValues("owner:alice:updated:loaded", page.Data[0].Name)
+}
+
+func (t *_testCrudService) Test_Hooks_VetoRollback() {
+	ctx := context.TODO()
+	var updated *_testFoo
+	t.service.Hooks().
+		AfterCreate(func(ctx context.Context, entity *_testFoo) error {
+			if entity.Name == "bob" {
+				return Veto("create", "no bob")
+			}
+			return nil
+		}).
+		AfterUpdate(func(ctx context.Context, entity *_testFoo) error {
+			updated = entity
+			return Veto("update", "read only")
+		}).
+		BeforeDelete(func(ctx context.Context, entity *_testFoo) error {
+			return Veto("delete", "keep it")
+		})
+	err := t.service.Create(ctx, &_testFoo{Name: "bob"})
+	t.ErrorIs(err, ErrVetoed)
+	var veto *VetoError
+	t.ErrorAs(err, &veto)
+	t.EqualValues("create", veto.Operation)
+	var count int64
+	t.NoError(t.db.Model(&_testFoo{}).Count(&count).Error)
+	t.EqualValues(0, count)
+
+	foo := _testFoo{Name: "alice"}
+	t.NoError(t.service.Create(ctx, &foo))
+	t.ErrorIs(t.service.Patch(ctx, foo.ID, map[string]interface{}{"name": "carol"}), ErrVetoed)
+	t.EqualValues("carol", updated.Name)
+	t.ErrorIs(t.service.Delete(ctx, foo.ID), ErrVetoed)
+	res, err := t.service.Get(ctx, foo.ID)
+	t.NoError(err)
+	t.EqualValues("alice", res.Name)
+}
+
+func TestCrudService(t *testing.T) {
+	suite.Run(t, &_testCrudService{})
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// ErrPreconditionFailed the If-Match precondition of a write does not hold
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ETag entity tag of v, made from its version field, which is tagged `gestful:"version"` or named Version,
+// or from the hash of its json. representation is the json actually written, e.g. a sparse fieldset of v
+func ETag(v interface{}, representation interface{}) (string, error) {
+	b, err := json.Marshal(representation)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	if version, ok := versionOf(v); ok {
+		return fmt.Sprintf(`"v%v-%s"`, version, hex.EncodeToString(sum[:4])), nil
+	}
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// LastModified the UpdatedAt of the entity v. A list has none, the latest UpdatedAt of its rows
+// does not change when a row is deleted from it or leaves its filter
+func LastModified(v interface{}) (time.Time, bool) {
+	val := indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return time.Time{}, false
+	}
+	if field := val.FieldByName("UpdatedAt"); field.IsValid() {
+		if t, ok := indirect(field).Interface().(time.Time); ok && !t.IsZero() {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func versionOf(v interface{}) (interface{}, bool) {
+	val := indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil, false
+	}
+	var field reflect.Value
+	for i := 0; i < val.NumField(); i++ {
+		for _, option := range strings.Split(val.Type().Field(i).Tag.Get(mapper.TagName), ",") {
+			if strings.TrimSpace(option) == "version" {
+				field = val.Field(i)
+			}
+		}
+	}
+	if !field.IsValid() {
+		field = val.FieldByName("Version")
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.String:
+		return field.Interface(), true
+	}
+	return nil, false
+}
+
+func indirect(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+// matchETag whether etag is in the header list of entity tags, weak tags only match when weak is true
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate, etag = strings.TrimPrefix(candidate, "W/"), strings.TrimPrefix(etag, "W/")
+		}
+		if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeConditional write v with its validators, or 304 when the If-None-Match or If-Modified-Since of the request holds.
+// Last-Modified is only the one of a single entity, a list is validated by the entity tag of its body
+func writeConditional(ctx web.Context, entity, v interface{}) {
+	etag, err := ETag(entity, v)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	ctx.SetHeader("ETag", etag)
+	modified, hasModified := LastModified(entity)
+	if hasModified {
+		ctx.SetHeader("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if match := ctx.Header("If-None-Match"); match != "" {
+		if matchETag(match, etag, true) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	} else if since := ctx.Header("If-Modified-Since"); since != "" && hasModified {
+		if t, err := http.ParseTime(since); err == nil && !modified.Truncate(time.Second).After(t) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, v)
+}
+
+type ifMatchKey struct{}
+
+// WithIfMatch carry the If-Match header of a write in ctx, CrudService.Patch and CrudService.Delete check it
+// against the entity they lock in their transaction, the entity tag is the one of the full representation
+func WithIfMatch(ctx context.Context, match string) context.Context {
+	if match == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ifMatchKey{}, match)
+}
+
+func ifMatch(ctx context.Context) (string, bool) {
+	match, ok := ctx.Value(ifMatchKey{}).(string)
+	return match, ok
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testETagFoo struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type _testETag struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testETag) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testETagFoo{}))
+	s := NewBaseService[_testETagFoo, BaseCreateRequest[_testETagFoo], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testETagFoo](t.db))
+	mux := http.NewServeMux()
+	s.RegisterGroupRoute(web.ServeMux(mux, ""), "foos")
+	t.handler = mux
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/foos", nil, `{"data":{"name":"foo"}}`).Code)
+}
+
+func (t *_testETag) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testETag) do(method, path string, header map[string]string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testETag) Test_IfNoneMatch() {
+	w := t.do(http.MethodGet, "/foos/1", nil, "")
+	t.Require().EqualValues(http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	t.NotEmpty(etag)
+	t.NotEmpty(w.Header().Get("Last-Modified"))
+
+	w = t.do(http.MethodGet, "/foos/1", map[string]string{"If-None-Match": etag}, "")
+	t.EqualValues(http.StatusNotModified, w.Code)
+	t.Empty(w.Body.String())
+
+	w = t.do(http.MethodGet, "/foos/1?fields=name", map[string]string{"If-None-Match": etag}, "")
+	t.EqualValues(http.StatusOK, w.Code)
+	t.NotEqual(etag, w.Header().Get("ETag"))
+
+	page := t.do(http.MethodGet, "/foos", nil, "")
+	t.Require().EqualValues(http.StatusOK, page.Code)
+	t.Empty(page.Header().Get("Last-Modified"), "a list has no last modification")
+	w = t.do(http.MethodGet, "/foos", map[string]string{"If-None-Match": page.Header().Get("ETag")}, "")
+	t.EqualValues(http.StatusNotModified, w.Code)
+}
+
+func (t *_testETag) Test_IfModifiedSince() {
+	w := t.do(http.MethodGet, "/foos/1", map[string]string{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}, "")
+	t.EqualValues(http.StatusNotModified, w.Code)
+	w = t.do(http.MethodGet, "/foos/1", map[string]string{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}, "")
+	t.EqualValues(http.StatusOK, w.Code)
+
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	t.EqualValues(http.StatusOK, t.do(http.MethodGet, "/foos", map[string]string{"If-Modified-Since": since}, "").Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodDelete, "/foos/1", nil, "").Code)
+	w = t.do(http.MethodGet, "/foos", map[string]string{"If-Modified-Since": since}, "")
+	t.EqualValues(http.StatusOK, w.Code, "the deleted entity is gone from the list")
+}
+
+func (t *_testETag) Test_InvalidID() {
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		w := t.do(method, "/foos/abc", nil, `{"data":{"name":"bar"}}`)
+		t.EqualValues(http.StatusBadRequest, w.Code, method)
+	}
+}
+
+func (t *_testETag) Test_IfMatch() {
+	etag := t.do(http.MethodGet, "/foos/1", nil, "").Header().Get("ETag")
+	w := t.do(http.MethodPut, "/foos/1", map[string]string{"If-Match": `"stale"`}, `{"data":{"name":"bar"}}`)
+	t.EqualValues(http.StatusPreconditionFailed, w.Code)
+	w = t.do(http.MethodDelete, "/foos/1", map[string]string{"If-Match": `"stale"`}, "")
+	t.EqualValues(http.StatusPreconditionFailed, w.Code)
+
+	w = t.do(http.MethodPut, "/foos/1", map[string]string{"If-Match": etag}, `{"data":{"name":"bar"}}`)
+	t.EqualValues(http.StatusOK, w.Code)
+	w = t.do(http.MethodPut, "/foos/1", map[string]string{"If-Match": etag}, `{"data":{"name":"baz"}}`)
+	t.EqualValues(http.StatusPreconditionFailed, w.Code)
+}
+
+func (t *_testETag) Test_IfMatch_Missing() {
+	w := t.do(http.MethodPut, "/foos/2", map[string]string{"If-Match": "*"}, `{"data":{"name":"bar"}}`)
+	t.EqualValues(http.StatusPreconditionFailed, w.Code)
+	w = t.do(http.MethodDelete, "/foos/2", map[string]string{"If-Match": "*"}, "")
+	t.EqualValues(http.StatusPreconditionFailed, w.Code)
+	w = t.do(http.MethodDelete, "/foos/2", nil, "")
+	t.EqualValues(http.StatusNotFound, w.Code)
+	w = t.do(http.MethodDelete, "/foos/1", map[string]string{"If-Match": "*"}, "")
+	t.EqualValues(http.StatusOK, w.Code)
+}
+
+func (t *_testETag) Test_IfMatch_Service() {
+	ctx := context.TODO()
+	crud := NewCrudService[_testETagFoo, uint](mapper.NewBaseMapper[_testETagFoo](t.db))
+	foo, err := crud.Get(ctx, 1)
+	t.Require().NoError(err)
+	etag, err := ETag(foo, foo)
+	t.Require().NoError(err)
+	// the precondition is checked against the entity of the transaction of the write
+	t.ErrorIs(mapper.Transaction(ctx, t.db, func(ctx context.Context) error {
+		tx, _ := mapper.TxFromContext(ctx)
+		if err := tx.Model(&_testETagFoo{}).Where("id = ?", 1).Update("name", "other").Error; err != nil {
+			return err
+		}
+		return crud.Patch(WithIfMatch(ctx, etag), 1, map[string]interface{}{"name": "bar"})
+	}), ErrPreconditionFailed)
+	t.NoError(crud.Patch(WithIfMatch(ctx, etag), 1, map[string]interface{}{"name": "bar"}))
+	t.ErrorIs(crud.Delete(WithIfMatch(ctx, etag), 1), ErrPreconditionFailed)
+}
+
+func TestETag(t *testing.T) {
+	suite.Run(t, &_testETag{})
+}
+
+func TestETag_Version(t *testing.T) {
+	type versioned struct {
+		ID  uint
+		Rev int `gestful:"version"`
+	}
+	a, err := ETag(versioned{ID: 1, Rev: 1}, versioned{ID: 1, Rev: 1})
+	if err != nil || !strings.HasPrefix(a, `"v1-`) {
+		t.Fatalf("etag %s %v", a, err)
+	}
+}
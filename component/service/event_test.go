@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-gosh/gestful/component/event"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testOrderPlaced struct {
+	OrderID uint `json:"order_id"`
+}
+
+func (_testOrderPlaced) EventName() string {
+	return "order.placed"
+}
+
+type _testOrder struct {
+	event.AggregateRoot
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	State string `json:"state"`
+}
+
+type _testEvent struct {
+	suite.Suite
+	db      *gorm.DB
+	bus     *event.Bus
+	service CrudService[_testOrder, uint]
+}
+
+func (t *_testEvent) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open("file:event?mode=memory&cache=shared"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testOrder{}, &event.OutboxMessage{}))
+	t.bus = event.NewBus()
+	t.service = NewCrudService[_testOrder, uint](mapper.NewBaseMapper[_testOrder](t.db))
+	t.service.SetDispatcher(&event.Dispatcher{Bus: t.bus, Outbox: true})
+	t.service.Hooks().AfterCreate(func(ctx context.Context, entity *_testOrder) error {
+		entity.RegisterEvent(_testOrderPlaced{OrderID: entity.ID})
+		return nil
+	})
+}
+
+func (t *_testEvent) TearDownTest() {
+	t.Require().NoError(t.db.Migrator().DropTable(&_testOrder{}, &event.OutboxMessage{}))
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testEvent) Test_Subscribers() {
+	var mu sync.Mutex
+	names := make([]string, 0)
+	t.bus.Subscribe(event.AllEvents, func(ctx context.Context, e event.Event) error {
+		_, ok := mapper.TxFromContext(ctx)
+		t.True(ok)
+		return nil
+	})
+	t.bus.SubscribeAsync(event.AllEvents, func(ctx context.Context, e event.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, e.EventName())
+		return nil
+	})
+	order := _testOrder{State: "new"}
+	t.Require().NoError(t.service.Create(context.TODO(), &order))
+	t.Require().NoError(t.service.Patch(context.TODO(), order.ID, map[string]interface{}{"state": "paid"}))
+	t.Require().NoError(t.service.Delete(context.TODO(), order.ID))
+	t.bus.Wait()
+	t.ElementsMatch([]string{"_testOrder.created", "order.placed", "_testOrder.updated", "_testOrder.deleted"}, names)
+	t.Empty(order.DomainEvents())
+}
+
+func (t *_testEvent) Test_SyncSubscriberRollback() {
+	async := false
+	t.bus.Subscribe("order.placed", func(ctx context.Context, e event.Event) error {
+		return errors.New("out of stock")
+	})
+	t.bus.SubscribeAsync(event.AllEvents, func(ctx context.Context, e event.Event) error {
+		async = true
+		return nil
+	})
+	t.EqualError(t.service.Create(context.TODO(), &_testOrder{}), "out of stock")
+	t.bus.Wait()
+	t.False(async)
+	var count int64
+	t.NoError(t.db.Model(&_testOrder{}).Count(&count).Error)
+	t.EqualValues(0, count)
+	t.NoError(t.db.Model(&event.OutboxMessage{}).Count(&count).Error)
+	t.EqualValues(0, count)
+}
+
+func (t *_testEvent) Test_OutboxOtherTransaction() {
+	other, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(other.AutoMigrate(&event.OutboxMessage{}))
+	err = mapper.Transaction(context.TODO(), t.db, func(ctx context.Context) error {
+		return mapper.Transaction(ctx, other, func(ctx context.Context) error {
+			return t.service.Create(ctx, &_testOrder{State: "new"})
+		})
+	})
+	t.Require().NoError(err)
+	var count int64
+	t.NoError(t.db.Model(&event.OutboxMessage{}).Count(&count).Error)
+	t.EqualValues(2, count)
+	t.NoError(other.Model(&event.OutboxMessage{}).Count(&count).Error)
+	t.EqualValues(0, count)
+}
+
+func (t *_testEvent) Test_OutboxRelay() {
+	t.Require().NoError(t.service.Create(context.TODO(), &_testOrder{State: "new"}))
+	publisher := &event.MemoryPublisher{Err: errors.New("broker down")}
+	relay := event.NewRelay(t.db, publisher)
+	delivered, err := relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(0, delivered)
+	var pending []event.OutboxMessage
+	t.NoError(t.db.Find(&pending).Error)
+	t.Len(pending, 2)
+	t.EqualValues(1, pending[0].Attempts)
+	t.EqualValues("broker down", pending[0].LastError)
+
+	publisher.Err = nil
+	delivered, err = relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(2, delivered)
+	messages := publisher.Messages()
+	t.Len(messages, 2)
+	t.EqualValues("_testOrder.created", messages[0].Name)
+	t.EqualValues("order.placed", messages[1].Name)
+	t.JSONEq(`{"order_id":1}`, messages[1].Payload)
+	delivered, err = relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(0, delivered)
+}
+
+// _testFlakyPublisher fail the messages named fail
+type _testFlakyPublisher struct {
+	event.MemoryPublisher
+	fail string
+}
+
+func (p *_testFlakyPublisher) Publish(ctx context.Context, message event.OutboxMessage) error {
+	if message.Name == p.fail {
+		return errors.New("rejected")
+	}
+	return p.MemoryPublisher.Publish(ctx, message)
+}
+
+func (t *_testEvent) Test_OutboxRelay_InOrder() {
+	t.Require().NoError(t.service.Create(context.TODO(), &_testOrder{State: "new"}))
+	t.Require().NoError(t.service.Create(context.TODO(), &_testOrder{State: "new"}))
+	publisher := &_testFlakyPublisher{fail: "order.placed"}
+	relay := event.NewRelay(t.db, publisher)
+	delivered, err := relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(1, delivered, "the batch stops at the failed message")
+	var pending []event.OutboxMessage
+	t.NoError(t.db.Where("published_at IS NULL").Order("id").Find(&pending).Error)
+	t.Len(pending, 3)
+	t.EqualValues(1, pending[0].Attempts)
+	t.EqualValues(0, pending[1].Attempts)
+	for _, message := range pending {
+		t.Empty(message.ClaimedBy)
+	}
+
+	publisher.fail = ""
+	delivered, err = relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(3, delivered)
+	names := make([]string, 0)
+	for _, message := range publisher.Messages() {
+		names = append(names, message.Name)
+	}
+	t.Equal([]string{"_testOrder.created", "order.placed", "_testOrder.created", "order.placed"}, names)
+}
+
+func (t *_testEvent) Test_OutboxRelay_Lease() {
+	t.Require().NoError(t.service.Create(context.TODO(), &_testOrder{State: "new"}))
+	until := time.Now().Add(time.Minute)
+	t.Require().NoError(t.db.Model(&event.OutboxMessage{}).Where("id = ?", 1).
+		Updates(map[string]interface{}{"claimed_by": "other", "claimed_until": until}).Error)
+	publisher := &event.MemoryPublisher{}
+	relay := event.NewRelay(t.db, publisher)
+	delivered, err := relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(0, delivered, "the messages after the ones leased by another relay wait for them")
+	t.Empty(publisher.Messages())
+
+	t.Require().NoError(t.db.Model(&event.OutboxMessage{}).Where("id = ?", 1).
+		Update("claimed_until", time.Now().Add(-time.Second)).Error)
+	delivered, err = relay.RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(2, delivered, "an expired lease is taken over")
+	delivered, err = event.NewRelay(t.db, publisher).RelayOnce(context.TODO())
+	t.NoError(err)
+	t.EqualValues(0, delivered)
+	t.Len(publisher.Messages(), 2)
+}
+
+func TestEvent(t *testing.T) {
+	suite.Run(t, &_testEvent{})
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrVetoed the operation is rejected by a hook
+var ErrVetoed = errors.New("vetoed")
+
+// VetoError returned by a hook to reject the operation, the transaction is rolled back
+type VetoError struct {
+	Operation string
+	Reason    string
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Operation, ErrVetoed, e.Reason)
+}
+
+func (e *VetoError) Unwrap() error {
+	return ErrVetoed
+}
+
+// Veto make a VetoError of operation
+func Veto(operation, reason string) error {
+	return &VetoError{Operation: operation, Reason: reason}
+}
+
+// HookFunc lifecycle hook of the entity, it may mutate the entity
+type HookFunc[T any] func(ctx context.Context, entity *T) error
+
+// UpdateHookFunc hook before update with the current entity, it may mutate the changes
+type UpdateHookFunc[T any] func(ctx context.Context, entity *T, changes map[string]interface{}) error
+
+// Hooks lifecycle hooks of a resource, they run in order inside the transaction of the operation,
+// use mapper.TxFromContext to write in the same transaction
+type Hooks[T any] struct {
+	beforeCreate []HookFunc[T]
+	afterCreate  []HookFunc[T]
+	beforeUpdate []UpdateHookFunc[T]
+	afterUpdate  []HookFunc[T]
+	beforeDelete []HookFunc[T]
+	afterDelete  []HookFunc[T]
+	afterLoad    []HookFunc[T]
+}
+
+func (h *Hooks[T]) BeforeCreate(fn ...HookFunc[T]) *Hooks[T] {
+	h.beforeCreate = append(h.beforeCreate, fn...)
+	return h
+}
+
+func (h *Hooks[T]) AfterCreate(fn ...HookFunc[T]) *Hooks[T] {
+	h.afterCreate = append(h.afterCreate, fn...)
+	return h
+}
+
+func (h *Hooks[T]) BeforeUpdate(fn ...UpdateHookFunc[T]) *Hooks[T] {
+	h.beforeUpdate = append(h.beforeUpdate, fn...)
+	return h
+}
+
+func (h *Hooks[T]) AfterUpdate(fn ...HookFunc[T]) *Hooks[T] {
+	h.afterUpdate = append(h.afterUpdate, fn...)
+	return h
+}
+
+func (h *Hooks[T]) BeforeDelete(fn ...HookFunc[T]) *Hooks[T] {
+	h.beforeDelete = append(h.beforeDelete, fn...)
+	return h
+}
+
+func (h *Hooks[T]) AfterDelete(fn ...HookFunc[T]) *Hooks[T] {
+	h.afterDelete = append(h.afterDelete, fn...)
+	return h
+}
+
+// AfterLoad run for every entity read by Get and List
+func (h *Hooks[T]) AfterLoad(fn ...HookFunc[T]) *Hooks[T] {
+	h.afterLoad = append(h.afterLoad, fn...)
+	return h
+}
+
+func runHooks[T any](ctx context.Context, hooks []HookFunc[T], entity *T) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runUpdateHooks[T any](ctx context.Context, hooks []UpdateHookFunc[T], entity *T, changes map[string]interface{}) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, entity, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
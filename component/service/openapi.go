@@ -0,0 +1,233 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/openapi"
+)
+
+// errorResponses the error responses of abortWithError, the body is the json string of the error
+var errorResponses = map[int]string{
+	http.StatusBadRequest:          "invalid request",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not found",
+	http.StatusPreconditionFailed:  "precondition failed",
+	http.StatusUnprocessableEntity: Veto("create", "reason").Error(),
+	http.StatusInternalServerError: "internal error",
+	http.StatusFailedDependency:    "rolled back",
+}
+
+// RouteTypes the types of the routes of a resource
+type RouteTypes struct {
+	Entity reflect.Type
+	Create reflect.Type
+	Page   reflect.Type
+	Update reflect.Type
+	Result reflect.Type
+}
+
+// DescribeRoute describe the routes of RegisterGroupRoute of source into doc, only the ones of operations when it is not empty,
+// T is the entity, U, V and W the create, page and update requests and R the page result
+func DescribeRoute[T, U, V, W, R any](doc *openapi.Document, source string, operations ...Operation) {
+	Describe(doc, source, RouteTypes{
+		Entity: reflect.TypeFor[T](),
+		Create: reflect.TypeFor[U](),
+		Page:   reflect.TypeFor[V](),
+		Update: reflect.TypeFor[W](),
+		Result: reflect.TypeFor[R](),
+	}, operations...)
+}
+
+// Describe like DescribeRoute, with the types known at runtime
+func Describe(doc *openapi.Document, source string, types RouteTypes, operations ...Operation) {
+	enabled := func(op Operation) bool {
+		return len(operations) == 0 || hasOperation(operations, op)
+	}
+	name := types.Entity.Name()
+	doc.AddTag(openapi.Tag{Name: source})
+	doc.Components.Schemas["Error"] = &openapi.Schema{Type: "string", Description: "error message"}
+	idParam := &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: doc.Schema(uint(0)), Example: 1}
+	fieldsParam := &openapi.Parameter{Name: "fields", In: "query", Description: "sparse fieldset, e.g. id,name,owner.email", Schema: &openapi.Schema{Type: "string"}}
+	ifMatch := &openapi.Parameter{Name: "If-Match", In: "header", Description: "ETag of the current entity, 412 on mismatch", Schema: &openapi.Schema{Type: "string"}}
+	ifNoneMatch := &openapi.Parameter{Name: "If-None-Match", In: "header", Description: "304 when the ETag matches", Schema: &openapi.Schema{Type: "string"}}
+	ifModifiedSince := &openapi.Parameter{Name: "If-Modified-Since", In: "header", Description: "304 when not modified since", Schema: &openapi.Schema{Type: "string"}}
+
+	collection := fmt.Sprintf("/%s", source)
+	entity := fmt.Sprintf("/%s/{id}", source)
+	if enabled(OperationList) {
+		doc.Path(collection).Get = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "List " + name,
+			OperationID: source + ".list",
+			Parameters:  append(doc.QueryParameters(types.Page), ifNoneMatch),
+			Responses: describeResponses(conditionalResponse(doc.Schema(types.Result), false),
+				http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationList) {
+		doc.Path(fmt.Sprintf("/%s/_aggregate", source)).Get = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Aggregate " + name,
+			OperationID: source + ".aggregate",
+			Parameters: append([]*openapi.Parameter{
+				{Name: "group_by", In: "query", Description: "comma separated columns", Schema: &openapi.Schema{Type: "string"}, Example: "status"},
+				{Name: "metrics", In: "query", Description: "comma separated count, sum(column), avg(column), min(column) or max(column)", Schema: &openapi.Schema{Type: "string"}, Example: "count,sum(amount)"},
+			}, doc.QueryParameters(types.Page)...),
+			Responses: describeResponses(&openapi.Response{
+				Description: "success",
+				Content: map[string]*openapi.MediaType{"application/json": {
+					Schema: &openapi.Schema{Type: "array", Items: doc.Schema(reflect.TypeFor[mapper.AggregateRow]())},
+				}},
+			}, http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationCreate) {
+		doc.Path(collection).Post = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Create " + name,
+			OperationID: source + ".create",
+			RequestBody: jsonBody(doc.Schema(types.Create)),
+			Responses: describeResponses(successResponse(),
+				http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		}
+	}
+	batch := fmt.Sprintf("/%s/batch", source)
+	modeParam := &openapi.Parameter{Name: "mode", In: "query", Description: "atomic (all or nothing) or partial", Schema: &openapi.Schema{Type: "string"}, Example: "atomic"}
+	batchResponses := func(codes ...int) map[string]*openapi.Response {
+		res := describeResponses(&openapi.Response{
+			Description: "every item succeeded, or the failed item of an atomic batch is reported with its status",
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: doc.Schema(reflect.TypeFor[BatchResponse]())}},
+		}, codes...)
+		res[strconv.Itoa(http.StatusMultiStatus)] = &openapi.Response{
+			Description: "some items of a partial batch failed",
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: doc.Schema(reflect.TypeFor[BatchResponse]())}},
+		}
+		return res
+	}
+	if enabled(OperationCreate) {
+		doc.Path(batch).Post = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Create " + name + " in batch",
+			OperationID: source + ".createBatch",
+			Parameters:  []*openapi.Parameter{modeParam},
+			RequestBody: jsonBody(batchSchema(doc.Schema(types.Create))),
+			Responses: batchResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity,
+				http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationUpdate) {
+		doc.Path(batch).Patch = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Update " + name + " in batch",
+			OperationID: source + ".updateBatch",
+			Parameters:  []*openapi.Parameter{modeParam},
+			RequestBody: jsonBody(batchSchema(&openapi.Schema{AllOf: []*openapi.Schema{
+				{Type: "object", Properties: map[string]*openapi.Schema{"id": doc.Schema(uint(0))}, Required: []string{"id"}},
+				doc.Schema(types.Update),
+			}})),
+			Responses: batchResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+				http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationDelete) {
+		doc.Path(collection).Delete = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Delete " + name + " in batch",
+			OperationID: source + ".deleteBatch",
+			Parameters: []*openapi.Parameter{
+				{Name: "ids", In: "query", Required: true, Description: "comma separated ids", Schema: &openapi.Schema{Type: "string"}, Example: "1,2,3"},
+				modeParam,
+			},
+			Responses: batchResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+				http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationGet) {
+		doc.Path(entity).Get = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Retrieve " + name,
+			OperationID: source + ".retrieve",
+			Parameters:  []*openapi.Parameter{idParam, fieldsParam, ifNoneMatch, ifModifiedSince},
+			Responses: describeResponses(conditionalResponse(doc.Schema(types.Entity), true),
+				http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationUpdate) {
+		doc.Path(entity).Put = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Update " + name,
+			OperationID: source + ".update",
+			Parameters:  []*openapi.Parameter{idParam, ifMatch},
+			RequestBody: jsonBody(doc.Schema(types.Update)),
+			Responses: describeResponses(successResponse(),
+				http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed,
+				http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		}
+	}
+	if enabled(OperationDelete) {
+		doc.Path(entity).Delete = &openapi.Operation{
+			Tags:        []string{source},
+			Summary:     "Delete " + name,
+			OperationID: source + ".delete",
+			Parameters:  []*openapi.Parameter{idParam, ifMatch},
+			Responses: describeResponses(successResponse(),
+				http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed,
+				http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		}
+	}
+}
+
+func batchSchema(item *openapi.Schema) *openapi.Schema {
+	return &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"data": {Type: "array", Items: item}},
+		Required:   []string{"data"},
+	}
+}
+
+func jsonBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]*openapi.MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func successResponse() *openapi.Response {
+	return &openapi.Response{
+		Description: "success",
+		Content:     map[string]*openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "string"}, Example: "success"}},
+	}
+}
+
+func conditionalResponse(schema *openapi.Schema, modified bool) *openapi.Response {
+	res := &openapi.Response{
+		Description: "success",
+		Headers:     map[string]*openapi.Header{"ETag": {Schema: &openapi.Schema{Type: "string"}}},
+		Content:     map[string]*openapi.MediaType{"application/json": {Schema: schema}},
+	}
+	if modified {
+		res.Headers["Last-Modified"] = &openapi.Header{Schema: &openapi.Schema{Type: "string"}}
+	}
+	return res
+}
+
+func describeResponses(ok *openapi.Response, codes ...int) map[string]*openapi.Response {
+	res := map[string]*openapi.Response{strconv.Itoa(http.StatusOK): ok}
+	if len(ok.Headers) > 0 {
+		res[strconv.Itoa(http.StatusNotModified)] = &openapi.Response{Description: "not modified"}
+	}
+	for _, code := range codes {
+		res[strconv.Itoa(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content: map[string]*openapi.MediaType{"application/json": {
+				Schema:  openapi.Ref("Error"),
+				Example: errorResponses[code],
+			}},
+		}
+	}
+	return res
+}
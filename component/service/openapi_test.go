@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestDescribe(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	s := NewBaseService[_testFoo, BaseCreateRequest[_testFoo], BasePageRequest, BaseUpdateRequest](mapper.NewBaseMapper[_testFoo](db))
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	s.Describe(doc, "foos")
+
+	collection := doc.Paths["/foos"]
+	require.NotNil(t, collection)
+	require.NotNil(t, collection.Get)
+	require.NotNil(t, collection.Post)
+	params := make([]string, 0)
+	for _, p := range collection.Get.Parameters {
+		params = append(params, p.In+":"+p.Name)
+	}
+	assert.Equal(t, []string{"query:start_id", "query:limit", "query:fields", "query:q", "header:If-None-Match"}, params)
+	assert.Equal(t, openapi.Ref("PageRes__testFoo"), collection.Get.Responses["200"].Content["application/json"].Schema)
+	assert.Contains(t, collection.Get.Responses, "304")
+	assert.Equal(t, openapi.Ref("BaseCreateRequest__testFoo"), collection.Post.RequestBody.Content["application/json"].Schema)
+	require.Contains(t, collection.Post.Responses, "422")
+	assert.Equal(t, "create vetoed: reason", collection.Post.Responses["422"].Content["application/json"].Example)
+
+	entity := doc.Paths["/foos/{id}"]
+	require.NotNil(t, entity)
+	assert.Equal(t, openapi.Ref("_testFoo"), entity.Get.Responses["200"].Content["application/json"].Schema)
+	assert.Contains(t, entity.Put.Responses, "412")
+	assert.Equal(t, "not found", entity.Delete.Responses["404"].Content["application/json"].Example)
+	assert.Equal(t, openapi.Ref("Error"), entity.Delete.Responses["404"].Content["application/json"].Schema)
+	assert.Contains(t, doc.Components.Schemas, "Error")
+	assert.Contains(t, doc.Components.Schemas["BaseCreateRequest__testFoo"].Properties, "data")
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrForbidden the operation is denied by the policy
+var ErrForbidden = errors.New("forbidden")
+
+// Operation of a resource evaluated by Policy
+type Operation string
+
+const (
+	OperationList   Operation = "list"
+	OperationGet    Operation = "get"
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Policy access policy of a resource, the principal is read from ctx, see security.PrincipalFromContext
+type Policy[T any] interface {
+	// Authorize return ErrForbidden to deny op, entity is nil for list,
+	// the entity to create after the before create hooks for create and the loaded entity for the others,
+	// an update is authorized twice, the loaded entity before the changes and the updated one after them
+	Authorize(ctx context.Context, op Operation, entity *T) error
+	// Scope row level filter of list and by id queries, nil means no filter
+	Scope(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB
+}
+
+// DenyMode how a denied by id operation is reported
+type DenyMode int
+
+const (
+	// DenyForbidden report ErrForbidden
+	DenyForbidden DenyMode = iota
+	// DenyNotFound report gorm.ErrRecordNotFound to hide the existence of the entity
+	DenyNotFound
+)
+
+// PolicyFuncs Policy of functions, nil function allows everything
+type PolicyFuncs[T any] struct {
+	AuthorizeFunc func(ctx context.Context, op Operation, entity *T) error
+	ScopeFunc     func(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB
+}
+
+func (p PolicyFuncs[T]) Authorize(ctx context.Context, op Operation, entity *T) error {
+	if p.AuthorizeFunc == nil {
+		return nil
+	}
+	return p.AuthorizeFunc(ctx, op, entity)
+}
+
+func (p PolicyFuncs[T]) Scope(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB {
+	if p.ScopeFunc == nil {
+		return nil
+	}
+	return p.ScopeFunc(ctx, op)
+}
+
+type allowAllPolicy[T any] struct{}
+
+func (allowAllPolicy[T]) Authorize(context.Context, Operation, *T) error {
+	return nil
+}
+
+func (allowAllPolicy[T]) Scope(context.Context, Operation) func(*gorm.DB) *gorm.DB {
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testOwned struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	OwnerID uint   `json:"owner_id"`
+	Name    string `json:"name"`
+}
+
+type _testPolicy struct {
+	suite.Suite
+	db      *gorm.DB
+	service BaseRestfulService[_testOwned]
+	handler http.Handler
+}
+
+func (t *_testPolicy) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testOwned{}))
+	t.service = NewBaseService[_testOwned, BaseCreateRequest[_testOwned], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testOwned](t.db))
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		AuthorizeFunc: func(ctx context.Context, op Operation, entity *_testOwned) error {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			if op == OperationCreate && entity.OwnerID != user {
+				return ErrForbidden
+			}
+			return nil
+		},
+		ScopeFunc: func(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Where("owner_id=?", user)
+			}
+		},
+	}, DenyForbidden)
+	mux := http.NewServeMux()
+	router := web.WithMiddleware(web.ServeMux(mux, ""), security.Authenticate(func(ctx web.Context) (interface{}, error) {
+		user, err := strconv.ParseUint(ctx.Header("X-User"), 10, 0)
+		if err != nil {
+			return nil, security.ErrUnauthenticated
+		}
+		return uint(user), nil
+	}))
+	t.service.RegisterGroupRoute(router, "owned")
+	t.handler = mux
+	for _, foo := range []_testOwned{{OwnerID: 1, Name: "a"}, {OwnerID: 1, Name: "b"}, {OwnerID: 2, Name: "c"}} {
+		t.Require().NoError(t.db.Create(&foo).Error)
+	}
+}
+
+func (t *_testPolicy) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testPolicy) do(method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testPolicy) Test_Unauthenticated() {
+	w := t.do(http.MethodGet, "/owned", "", "")
+	t.EqualValues(http.StatusUnauthorized, w.Code)
+}
+
+func (t *_testPolicy) Test_ListScoped() {
+	w := t.do(http.MethodGet, "/owned", "1", "")
+	t.EqualValues(http.StatusOK, w.Code)
+	var res mapper.PageRes[_testOwned]
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.Len(res.Data, 2)
+	for _, v := range res.Data {
+		t.EqualValues(1, v.OwnerID)
+	}
+}
+
+func (t *_testPolicy) Test_ByIdForbidden() {
+	t.EqualValues(http.StatusOK, t.do(http.MethodGet, "/owned/3", "2", "").Code)
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodGet, "/owned/3", "1", "").Code)
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodPut, "/owned/3", "1", `{"data":{"name":"x"}}`).Code)
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodDelete, "/owned/3", "1", "").Code)
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodGet, "/owned/4", "1", "").Code)
+	var foo _testOwned
+	t.NoError(t.db.First(&foo, 3).Error)
+	t.EqualValues("c", foo.Name)
+}
+
+func (t *_testPolicy) Test_ByIdNotFound() {
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		ScopeFunc: func(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Where("owner_id=?", user)
+			}
+		},
+	}, DenyNotFound)
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodGet, "/owned/3", "1", "").Code)
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodDelete, "/owned/3", "1", "").Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodDelete, "/owned/1", "1", "").Code)
+}
+
+func (t *_testPolicy) Test_CreateForbidden() {
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodPost, "/owned", "1", `{"data":{"owner_id":2}}`).Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/owned", "1", `{"data":{"owner_id":1}}`).Code)
+}
+
+func (t *_testPolicy) Test_CreateAuthorizeHooked() {
+	t.service.Hooks().BeforeCreate(func(ctx context.Context, entity *_testOwned) error {
+		if entity.Name == "transfer" {
+			entity.OwnerID = 2
+		}
+		return nil
+	})
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodPost, "/owned", "1", `{"data":{"owner_id":1,"name":"transfer"}}`).Code)
+	var count int64
+	t.NoError(t.db.Model(&_testOwned{}).Where("name=?", "transfer").Count(&count).Error)
+	t.Zero(count)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/owned", "1", `{"data":{"owner_id":1,"name":"kept"}}`).Code)
+}
+
+func (t *_testPolicy) Test_FieldsAuthorizeFullRow() {
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		AuthorizeFunc: func(ctx context.Context, op Operation, entity *_testOwned) error {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			if op == OperationGet && entity.OwnerID != user {
+				return ErrForbidden
+			}
+			return nil
+		},
+	}, DenyForbidden)
+	w := t.do(http.MethodGet, "/owned/1?fields=name", "1", "")
+	t.EqualValues(http.StatusOK, w.Code)
+	t.JSONEq(`{"name":"a"}`, w.Body.String())
+	t.NotEmpty(w.Header().Get("ETag"))
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodGet, "/owned/1?fields=name", "2", "").Code)
+}
+
+func (t *_testPolicy) Test_UpdateAuthorizeMerged() {
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodPut, "/owned/1", "1", `{"data":{"owner_id":2}}`).Code)
+	var foo _testOwned
+	t.NoError(t.db.First(&foo, 1).Error)
+	t.EqualValues(1, foo.OwnerID)
+
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		AuthorizeFunc: func(ctx context.Context, op Operation, entity *_testOwned) error {
+			if op == OperationUpdate && entity.Name == "root" {
+				return ErrForbidden
+			}
+			return nil
+		},
+	}, DenyForbidden)
+	t.EqualValues(http.StatusForbidden, t.do(http.MethodPut, "/owned/1", "1", `{"data":{"name":"root"}}`).Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPut, "/owned/1", "1", `{"data":{"name":"x"}}`).Code)
+	t.NoError(t.db.First(&foo, 1).Error)
+	t.EqualValues("x", foo.Name)
+}
+
+func TestPolicy(t *testing.T) {
+	suite.Run(t, &_testPolicy{})
+}
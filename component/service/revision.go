@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/repository"
+	"github.com/go-gosh/gestful/component/web"
+	"gorm.io/gorm"
+)
+
+// RegisterRevisionRoute register GET /{source}/{id}/revisions and GET /{source}/{id}/revisions/{rev},
+// the current entity is loaded by crud first so the revisions are guarded by its policy, owner and tenant scopes,
+// the revisions of a deleted entity are guarded by its last revision, see CrudService.Authorize.
+// The unknown and the denied entities are reported as Get reports them
+func RegisterRevisionRoute[T any](router web.Router, source string, crud CrudService[T, uint], repo repository.RevisionRepository[T, uint]) {
+	router.Handle(http.MethodGet, fmt.Sprintf("/%s/{id}/revisions", source), func(ctx web.Context) {
+		id, err := bindRevisioned(ctx, crud, repo)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := repo.FindRevisions(ctx.Context(), id)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		ctx.JSON(200, res)
+	})
+	router.Handle(http.MethodGet, fmt.Sprintf("/%s/{id}/revisions/{rev}", source), func(ctx web.Context) {
+		id, err := bindRevisioned(ctx, crud, repo)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		rev, err := strconv.ParseUint(ctx.Param("rev"), 10, 0)
+		if err != nil {
+			abortWithError(ctx, fmt.Errorf("%w: %v", ErrBadRequest, err))
+			return
+		}
+		res, err := repo.FindRevision(ctx.Context(), id, uint(rev))
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		ctx.JSON(200, res)
+	})
+}
+
+// bindRevisioned the id of the path whose entity is readable by the principal of ctx,
+// the entity is the one of the last revision when it is deleted
+func bindRevisioned[T any](ctx web.Context, crud CrudService[T, uint], repo repository.RevisionRepository[T, uint]) (uint, error) {
+	id, err := bindID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	_, err = crud.Get(ctx.Context(), id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	last, lerr := repo.FindLastChangeRevision(ctx.Context(), id)
+	if errors.Is(lerr, gorm.ErrRecordNotFound) || (lerr == nil && last.Type != domain.RevisionTypeDelete) {
+		return 0, err
+	}
+	if lerr != nil {
+		return 0, lerr
+	}
+	if err := crud.Authorize(ctx.Context(), OperationGet, &last.Entity); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
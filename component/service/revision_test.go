@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/domain"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/repository/support"
+	"github.com/go-gosh/gestful/component/revision"
+	"github.com/go-gosh/gestful/component/security"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testRevisionRoute struct {
+	suite.Suite
+	db      *gorm.DB
+	mapper  mapper.BaseMapper[_testOwned]
+	service CrudService[_testOwned, uint]
+	handler http.Handler
+}
+
+func (t *_testRevisionRoute) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testOwned{}))
+	t.Require().NoError(revision.AutoMigrate(t.db, &_testOwned{}))
+	t.mapper = mapper.NewBaseMapper[_testOwned](t.db, mapper.WithRevisions())
+	t.service = NewCrudService[_testOwned, uint](t.mapper)
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		ScopeFunc: func(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Where("owner_id=?", user)
+			}
+		},
+	}, DenyForbidden)
+	mux := http.NewServeMux()
+	router := web.WithMiddleware(web.ServeMux(mux, ""), security.Authenticate(func(ctx web.Context) (interface{}, error) {
+		user, err := strconv.ParseUint(ctx.Header("X-User"), 10, 0)
+		if err != nil {
+			return nil, security.ErrUnauthenticated
+		}
+		return uint(user), nil
+	}))
+	RegisterRevisionRoute[_testOwned](router, "owned", t.service, support.GormRevisionRepository[_testOwned, uint]{DB: t.db})
+	t.handler = mux
+	for _, foo := range []_testOwned{{OwnerID: 1, Name: "a"}, {OwnerID: 2, Name: "b"}} {
+		t.Require().NoError(t.mapper.Create(context.TODO(), &foo))
+	}
+	t.Require().NoError(t.mapper.UpdateById(context.TODO(), 1, map[string]interface{}{"name": "x"}))
+}
+
+func (t *_testRevisionRoute) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testRevisionRoute) do(path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testRevisionRoute) Test_Revisions() {
+	w := t.do("/owned/1/revisions", "1")
+	t.EqualValues(http.StatusOK, w.Code)
+	var res []domain.Revision[_testOwned]
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.Len(res, 2)
+	t.EqualValues("x", res[1].Entity.Name)
+
+	w = t.do("/owned/1/revisions/1", "1")
+	t.EqualValues(http.StatusOK, w.Code)
+	var rev domain.Revision[_testOwned]
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &rev))
+	t.EqualValues("a", rev.Entity.Name)
+	t.EqualValues(http.StatusNotFound, t.do("/owned/1/revisions/9", "1").Code)
+	t.EqualValues(http.StatusBadRequest, t.do("/owned/1/revisions/x", "1").Code)
+}
+
+func (t *_testRevisionRoute) Test_UnknownEntity() {
+	t.EqualValues(http.StatusNotFound, t.do("/owned/9/revisions", "1").Code)
+	t.EqualValues(http.StatusNotFound, t.do("/owned/9/revisions/1", "1").Code)
+}
+
+func (t *_testRevisionRoute) Test_Denied() {
+	t.EqualValues(http.StatusForbidden, t.do("/owned/2/revisions", "1").Code)
+	t.EqualValues(http.StatusForbidden, t.do("/owned/2/revisions/1", "1").Code)
+	t.EqualValues(http.StatusOK, t.do("/owned/2/revisions", "2").Code)
+
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		ScopeFunc: func(ctx context.Context, op Operation) func(*gorm.DB) *gorm.DB {
+			user, _ := security.PrincipalFromContext[uint](ctx)
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Where("owner_id=?", user)
+			}
+		},
+	}, DenyNotFound)
+	t.EqualValues(http.StatusNotFound, t.do("/owned/2/revisions", "1").Code)
+}
+
+func (t *_testRevisionRoute) Test_DeletedEntity() {
+	t.Require().NoError(t.mapper.DeleteById(context.TODO(), 1))
+	t.Require().NoError(t.mapper.DeleteById(context.TODO(), 2))
+
+	w := t.do("/owned/1/revisions", "1")
+	t.Require().EqualValues(http.StatusOK, w.Code)
+	var res []domain.Revision[_testOwned]
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.Len(res, 3)
+	t.EqualValues(domain.RevisionTypeDelete, res[2].Type)
+	t.EqualValues(http.StatusOK, t.do("/owned/1/revisions/1", "1").Code)
+
+	t.EqualValues(http.StatusForbidden, t.do("/owned/2/revisions", "1").Code, "the scope holds on the deleted entity")
+	t.EqualValues(http.StatusOK, t.do("/owned/2/revisions", "2").Code)
+	t.EqualValues(http.StatusNotFound, t.do("/owned/9/revisions", "1").Code)
+
+	t.service.SetPolicy(PolicyFuncs[_testOwned]{
+		AuthorizeFunc: func(ctx context.Context, op Operation, entity *_testOwned) error {
+			if entity.Name == "x" {
+				return ErrForbidden
+			}
+			return nil
+		},
+	}, DenyNotFound)
+	t.EqualValues(http.StatusNotFound, t.do("/owned/1/revisions", "1").Code)
+	t.EqualValues(http.StatusOK, t.do("/owned/2/revisions", "1").Code)
+}
+
+func TestRevisionRoute(t *testing.T) {
+	suite.Run(t, &_testRevisionRoute{})
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/go-gosh/gestful/component/web"
+	"gorm.io/gorm"
+)
+
+// Route a route of a resource made by Routes
+type Route struct {
+	Operation Operation
+	Method    string
+	Path      string
+	Handler   web.HandlerFunc
+}
+
+// Routes the routes of source, only the ones of operations when it is not empty
+func Routes[T, U any](source string, s HTTPService[T, U], operations ...Operation) []Route {
+	routes := []Route{
+		{OperationList, http.MethodGet, fmt.Sprintf("/%s", source), handlePaginate(s)},
+		{OperationCreate, http.MethodPost, fmt.Sprintf("/%s", source), handleCreate(s)},
+		{OperationCreate, http.MethodPost, fmt.Sprintf("/%s/batch", source), handleCreateBatch(s)},
+		{OperationUpdate, http.MethodPatch, fmt.Sprintf("/%s/batch", source), handleUpdateBatch(s)},
+		{OperationDelete, http.MethodDelete, fmt.Sprintf("/%s", source), handleDeleteBatch(s)},
+		{OperationList, http.MethodGet, fmt.Sprintf("/%s/_aggregate", source), handleAggregate(s)},
+		{OperationGet, http.MethodGet, fmt.Sprintf("/%s/{id}", source), handleRetrieve(s)},
+		{OperationUpdate, http.MethodPut, fmt.Sprintf("/%s/{id}", source), handleUpdate(s)},
+		{OperationDelete, http.MethodDelete, fmt.Sprintf("/%s/{id}", source), handleDelete(s)},
+	}
+	if len(operations) == 0 {
+		return routes
+	}
+	res := make([]Route, 0, len(routes))
+	for _, route := range routes {
+		if hasOperation(operations, route.Operation) {
+			res = append(res, route)
+		}
+	}
+	return res
+}
+
+func hasOperation(operations []Operation, op Operation) bool {
+	for _, o := range operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func RegisterGroupRoute[T, U any](router web.Router, source string, s HTTPService[T, U]) {
+	for _, route := range Routes(source, s) {
+		router.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func abortWithError(ctx web.Context, err error) {
+	code, message := errorStatus(err)
+	ctx.JSON(code, message)
+}
+
+// errorStatus the status code and the message of err
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "not found"
+	case errors.Is(err, mapper.ErrInvalidField), errors.Is(err, mapper.ErrSearchUnsupported), errors.Is(err, tenant.ErrTenantRequired),
+		errors.Is(err, mapper.ErrInvalidTenant), errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, err.Error()
+	case errors.Is(err, ErrVetoed):
+		return http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, mapper.ErrRolledBack):
+		return http.StatusFailedDependency, err.Error()
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+func bindID(ctx web.Context) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid id %s", ErrBadRequest, ctx.Param("id"))
+	}
+	return uint(id), nil
+}
+
+func handleErrorAdapter(handler func(web.Context) error) web.HandlerFunc {
+	return func(ctx web.Context) {
+		err := handler(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		ctx.JSON(200, "success")
+	}
+}
+
+func handleCreate[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return handleErrorAdapter(func(ctx web.Context) error {
+		req, err := s.BindCreate(ctx)
+		if err != nil {
+			return err
+		}
+		return s.Create(ctx.Context(), req)
+	})
+}
+
+func handleUpdate[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return handleErrorAdapter(func(ctx web.Context) error {
+		id, err := bindID(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := s.BindUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		return s.Update(WithIfMatch(ctx.Context(), ctx.Header("If-Match")), id, req)
+	})
+}
+
+func handleDelete[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return handleErrorAdapter(func(ctx web.Context) error {
+		id, err := bindID(ctx)
+		if err != nil {
+			return err
+		}
+		return s.Delete(WithIfMatch(ctx.Context(), ctx.Header("If-Match")), id)
+	})
+}
+
+func handlePaginate[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		req, err := s.BindPage(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := s.Paginate(ctx.Context(), req)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		if fields, ok := req.(FieldsRequest); ok && len(fields.MakeFields()) > 0 {
+			fs, err := s.Fields(fields.MakeFields()...)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+			shaped, err := fs.ShapeField(res, "data")
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+			writeConditional(ctx, res, shaped)
+			return
+		}
+		writeConditional(ctx, res, res)
+	}
+}
+
+func handleRetrieve[T, U any](s HTTPService[T, U]) web.HandlerFunc {
+	return func(ctx web.Context) {
+		id, err := bindID(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		var req BaseFieldsRequest
+		if err := ctx.BindQuery(&req); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		res, err := s.Retrieve(ctx.Context(), id, req.MakeFields()...)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		if fields := req.MakeFields(); len(fields) > 0 {
+			fs, err := s.Fields(fields...)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+			shaped, err := fs.Shape(res)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+			writeConditional(ctx, res, shaped)
+			return
+		}
+		writeConditional(ctx, res, res)
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testShardFoo struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type _testShardService struct {
+	suite.Suite
+	dbs     []*gorm.DB
+	service CrudService[_testShardFoo, uint]
+}
+
+func (t *_testShardService) SetupTest() {
+	dir := t.T().TempDir()
+	t.dbs = make([]*gorm.DB, 2)
+	for i := range t.dbs {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, fmt.Sprintf("shard%d.db", i))), &gorm.Config{})
+		t.Require().NoError(err)
+		t.dbs[i] = db.Debug()
+		t.Require().NoError(t.dbs[i].AutoMigrate(&_testShardFoo{}))
+	}
+	// users below 10 in shard 0, the others in shard 1
+	t.service = NewCrudService[_testShardFoo, uint](mapper.NewBaseMapper[_testShardFoo](t.dbs[0],
+		mapper.WithSharding("user_id", mapper.RangeSharding{Bounds: []int64{10}, DBs: t.dbs})))
+}
+
+func (t *_testShardService) TearDownTest() {
+	for _, db := range t.dbs {
+		sqlDB, err := db.DB()
+		t.Require().NoError(err)
+		t.Require().NoError(sqlDB.Close())
+	}
+}
+
+func (t *_testShardService) names(i int) []string {
+	names := make([]string, 0)
+	t.Require().NoError(t.dbs[i].Model(&_testShardFoo{}).Order("id").Pluck("name", &names).Error)
+	return names
+}
+
+func (t *_testShardService) Test_Writes() {
+	ctx := context.TODO()
+	t.NoError(t.service.Create(ctx, &_testShardFoo{ID: 1, UserID: 1, Name: "a"}))
+	t.NoError(t.service.Create(ctx, &_testShardFoo{ID: 2, UserID: 15, Name: "b"}))
+	t.Equal([]string{"a"}, t.names(0))
+	t.Equal([]string{"b"}, t.names(1))
+
+	t.NoError(t.service.Patch(ctx, 2, map[string]interface{}{"name": "bb"}))
+	t.Equal([]string{"bb"}, t.names(1))
+	res, err := t.service.Get(ctx, 2)
+	t.NoError(err)
+	t.EqualValues("bb", res.Name)
+	t.ErrorIs(t.service.Patch(ctx, 3, map[string]interface{}{"name": "c"}), gorm.ErrRecordNotFound)
+
+	t.NoError(t.service.Delete(ctx, 1))
+	t.Empty(t.names(0))
+	t.ErrorIs(t.service.Delete(ctx, 1), gorm.ErrRecordNotFound)
+}
+
+func (t *_testShardService) Test_Hooks() {
+	ctx := context.TODO()
+	t.service.Hooks().AfterCreate(func(ctx context.Context, foo *_testShardFoo) error {
+		return Veto("create", "after")
+	})
+	t.ErrorIs(t.service.Create(ctx, &_testShardFoo{ID: 1, UserID: 15, Name: "a"}), ErrVetoed)
+	t.Empty(t.names(1), "the create is rolled back on its shard")
+}
+
+func TestShardService(t *testing.T) {
+	suite.Run(t, &_testShardService{})
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/tenant"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testTenantFoo struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+}
+
+type _testTenant struct {
+	suite.Suite
+	db      *gorm.DB
+	handler http.Handler
+}
+
+func (t *_testTenant) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.db = t.db.Debug()
+	t.Require().NoError(t.db.AutoMigrate(&_testTenantFoo{}))
+	s := NewBaseService[_testTenantFoo, BaseCreateRequest[_testTenantFoo], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testTenantFoo](t.db, mapper.WithTenantStrategy(mapper.ColumnTenant{Column: "tenant_id"})))
+	mux := http.NewServeMux()
+	s.RegisterGroupRoute(web.WithMiddleware(web.ServeMux(mux, ""), tenant.Middleware(tenant.FromHeader("X-Tenant"))), "foos")
+	t.handler = mux
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/foos", "a", `{"data":{"name":"a1"}}`).Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/foos", "b", `{"data":{"name":"b1","tenant_id":"a"}}`).Code)
+}
+
+func (t *_testTenant) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testTenant) do(method, path, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set("X-Tenant", tenant)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testTenant) Test_TenantRequired() {
+	t.EqualValues(http.StatusBadRequest, t.do(http.MethodGet, "/foos", "", "").Code)
+}
+
+func (t *_testTenant) Test_InvalidTenant() {
+	s := NewBaseService[_testTenantFoo, BaseCreateRequest[_testTenantFoo], BasePageRequest, BaseUpdateRequest](
+		mapper.NewBaseMapper[_testTenantFoo](t.db, mapper.WithTenantStrategy(mapper.SchemaTenant{Format: "tenant_%s"})))
+	mux := http.NewServeMux()
+	s.RegisterGroupRoute(web.WithMiddleware(web.ServeMux(mux, ""), tenant.Middleware(tenant.FromHeader("X-Tenant"))), "foos")
+	t.handler = mux
+	t.EqualValues(http.StatusBadRequest, t.do(http.MethodGet, "/foos", "a; --", "").Code)
+	t.EqualValues(http.StatusBadRequest, t.do(http.MethodPost, "/foos", "a.b", `{"data":{"name":"x"}}`).Code)
+}
+
+func (t *_testTenant) Test_CrossTenantRead() {
+	w := t.do(http.MethodGet, "/foos", "b", "")
+	t.EqualValues(http.StatusOK, w.Code)
+	var res mapper.PageRes[_testTenantFoo]
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.Len(res.Data, 1)
+	t.EqualValues("b1", res.Data[0].Name)
+	t.EqualValues("b", res.Data[0].TenantID)
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodGet, "/foos/1", "b", "").Code)
+}
+
+func (t *_testTenant) Test_CrossTenantWrite() {
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodPut, "/foos/1", "b", `{"data":{"name":"x"}}`).Code)
+	t.EqualValues(http.StatusNotFound, t.do(http.MethodDelete, "/foos/1", "b", "").Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPut, "/foos/2", "b", `{"data":{"tenant_id":"a","name":"b2"}}`).Code)
+	var foos []_testTenantFoo
+	t.NoError(t.db.Order("id").Find(&foos).Error)
+	t.Len(foos, 2)
+	t.EqualValues(_testTenantFoo{ID: 1, TenantID: "a", Name: "a1"}, foos[0])
+	t.EqualValues(_testTenantFoo{ID: 2, TenantID: "b", Name: "b2"}, foos[1])
+}
+
+func TestTenant(t *testing.T) {
+	suite.Run(t, &_testTenant{})
+}
@@ -0,0 +1,169 @@
+package tenant
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// ErrInvalidToken the bearer token is malformed, not signed by the secret, expired or not valid yet
+var ErrInvalidToken = errors.New("invalid token")
+
+// FromHeader resolve the tenant id from the header name, e.g. X-Tenant-ID
+func FromHeader(name string) Resolver {
+	return func(ctx web.Context) (string, error) {
+		return strings.TrimSpace(ctx.Header(name)), nil
+	}
+}
+
+// FromSubdomain resolve the tenant id from the subdomain of domain, e.g. acme of acme.example.com.
+// The host is only matched against the suffix of domain, the nested subdomains like www.acme.example.com
+// and the other hosts resolve no tenant
+func FromSubdomain(domain string) Resolver {
+	domain = strings.ToLower(strings.Trim(domain, "."))
+	if domain == "" {
+		panic("tenant: no domain of the subdomains")
+	}
+	suffix := "." + domain
+	return func(ctx web.Context) (string, error) {
+		host := ctx.Request().Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return "", nil
+		}
+		return sub, nil
+	}
+}
+
+// ClaimsVerifier verify the bearer token and return its claims, e.g. by a JWT library. The claims are only
+// returned when the signature, the expiration and the not before time of the token are valid
+type ClaimsVerifier func(token string) (map[string]interface{}, error)
+
+// FromJWTClaim resolve the tenant id from the claim of the HS256 bearer token signed by secret
+func FromJWTClaim(claim string, secret []byte) Resolver {
+	return FromClaim(claim, HS256(secret))
+}
+
+// FromClaim resolve the tenant id from the claim of the bearer token verified by verify,
+// the claim is a string or an integer, any other value is an ErrInvalidToken
+func FromClaim(claim string, verify ClaimsVerifier) Resolver {
+	return func(ctx web.Context) (string, error) {
+		token, ok := strings.CutPrefix(ctx.Header("Authorization"), "Bearer ")
+		if !ok {
+			return "", nil
+		}
+		claims, err := verify(strings.TrimSpace(token))
+		if err != nil {
+			return "", err
+		}
+		value, ok := claims[claim]
+		if !ok || value == nil {
+			return "", nil
+		}
+		return claimString(value)
+	}
+}
+
+func claimString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String(), nil
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("%w: claim of %T", ErrInvalidToken, value)
+}
+
+// HS256 verify the HS256 token signed by secret, with its exp and nbf claims
+func HS256(secret []byte) ClaimsVerifier {
+	return func(token string) (map[string]interface{}, error) {
+		return parseHS256(token, secret, time.Now())
+	}
+}
+
+func parseHS256(token string, secret []byte, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidToken
+	}
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	exp, err := numericDate(claims, "exp")
+	if err != nil || (exp > 0 && now.Unix() >= exp) {
+		return nil, ErrInvalidToken
+	}
+	nbf, err := numericDate(claims, "nbf")
+	if err != nil || now.Unix() < nbf {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// numericDate the seconds of the date claim name, 0 when it is absent
+func numericDate(claims map[string]interface{}, name string) (int64, error) {
+	value, ok := claims[name]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	if v, err := n.Int64(); err == nil {
+		return v, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return int64(f), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
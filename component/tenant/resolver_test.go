@@ -0,0 +1,102 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func signHS256(t *testing.T, payload string, secret []byte) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + body))
+	return header + "." + body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func resolve(t *testing.T, resolver Resolver, r *http.Request) (string, error) {
+	t.Helper()
+	return resolver(web.NewContext(httptest.NewRecorder(), r, func(string) string { return "" }))
+}
+
+func TestFromSubdomain(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com:8080/", nil)
+	tenant, err := resolve(t, FromSubdomain("example.com"), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "acme", tenant)
+	r = httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	tenant, err = resolve(t, FromSubdomain("example.com"), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "", tenant)
+
+	for _, host := range []string{"www.acme.example.com", "acme.example.com.evil.net", "acmeexample.com", "acme.example.org"} {
+		r = httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		tenant, err = resolve(t, FromSubdomain("example.com"), r)
+		assert.NoError(t, err, host)
+		assert.EqualValues(t, "", tenant, host)
+	}
+	r = httptest.NewRequest(http.MethodGet, "http://ACME.Example.com./", nil)
+	tenant, err = resolve(t, FromSubdomain(".example.com"), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "acme", tenant)
+	assert.Panics(t, func() { FromSubdomain("") })
+}
+
+func TestFromJWTClaim(t *testing.T) {
+	secret := []byte("secret")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signHS256(t, `{"tid":42}`, secret))
+	tenant, err := resolve(t, FromJWTClaim("tid", secret), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "42", tenant)
+
+	_, err = resolve(t, FromJWTClaim("tid", []byte("other")), r)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	for _, payload := range []string{
+		`{"tid":42,"exp":1}`,
+		`{"tid":42,"exp":"never"}`,
+		`{"tid":42,"nbf":32503680000}`,
+		`{"tid":42.5}`,
+		`{"tid":{"id":42}}`,
+		`{"tid":["a","b"]}`,
+		`{"tid":true}`,
+	} {
+		r.Header.Set("Authorization", "Bearer "+signHS256(t, payload, secret))
+		_, err = resolve(t, FromJWTClaim("tid", secret), r)
+		assert.ErrorIs(t, err, ErrInvalidToken, payload)
+	}
+
+	r.Header.Set("Authorization", "Bearer "+signHS256(t, `{"tid":"acme","nbf":1,"exp":32503680000}`, secret))
+	tenant, err = resolve(t, FromJWTClaim("tid", secret), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "acme", tenant)
+}
+
+func TestFromClaim(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	verify := func(token string) (map[string]interface{}, error) {
+		if token != "token" {
+			return nil, ErrInvalidToken
+		}
+		return map[string]interface{}{"tid": float64(42), "org": int64(7)}, nil
+	}
+	tenant, err := resolve(t, FromClaim("tid", verify), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "42", tenant)
+	tenant, err = resolve(t, FromClaim("org", verify), r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "7", tenant)
+
+	r.Header.Set("Authorization", "Bearer forged")
+	_, err = resolve(t, FromClaim("tid", verify), r)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
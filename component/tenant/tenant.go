@@ -0,0 +1,50 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// ErrTenantRequired no tenant is resolved
+var ErrTenantRequired = errors.New("tenant required")
+
+type tenantKey struct{}
+
+// WithTenant carry the tenant id in ctx
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext the tenant id carried in ctx
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// Resolver resolve the tenant id of the request, empty means not resolved
+type Resolver func(ctx web.Context) (string, error)
+
+// Middleware carry the tenant id of the first resolved resolver in the request context,
+// it responds 400 when no resolver resolves the tenant
+func Middleware(resolvers ...Resolver) web.Middleware {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) {
+			for _, resolve := range resolvers {
+				tenant, err := resolve(ctx)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, err.Error())
+					return
+				}
+				if tenant != "" {
+					ctx.SetContext(WithTenant(ctx.Context(), tenant))
+					next(ctx)
+					return
+				}
+			}
+			ctx.JSON(http.StatusBadRequest, ErrTenantRequired.Error())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Chi Router over chi
+func Chi(router chi.Router) Router {
+	return &chiRouter{router: router}
+}
+
+type chiRouter struct {
+	router chi.Router
+}
+
+func (c *chiRouter) Handle(method, path string, handler HandlerFunc) {
+	c.router.MethodFunc(method, path, func(w http.ResponseWriter, r *http.Request) {
+		handler(NewContext(w, r, func(name string) string {
+			return chi.URLParam(r, name)
+		}))
+	})
+}
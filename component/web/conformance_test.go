@@ -0,0 +1,166 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type _testCtxKey struct{}
+
+type _testConformance struct {
+	suite.Suite
+	newServer func() (Router, http.Handler)
+	handler   http.Handler
+}
+
+func (t *_testConformance) SetupTest() {
+	router, handler := t.newServer()
+	t.handler = handler
+	router = WithMiddleware(router, func(next HandlerFunc) HandlerFunc {
+		return func(c Context) {
+			c.SetContext(context.WithValue(c.Context(), _testCtxKey{}, "value"))
+			next(c)
+		}
+	})
+	router.Handle(http.MethodGet, "/items", func(c Context) {
+		var req struct {
+			Page int `form:"page"`
+		}
+		if err := c.BindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, req.Page)
+	})
+	router.Handle(http.MethodPost, "/items", func(c Context) {
+		var req struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusCreated, req.Name)
+	})
+	router.Handle(http.MethodGet, "/items/{id}", func(c Context) {
+		c.SetHeader("X-Reply", "reply")
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"id":     c.Param("id"),
+			"q":      c.Query("q"),
+			"header": c.Header("X-Test"),
+			"ctx":    c.Context().Value(_testCtxKey{}),
+			"method": c.Request().Method,
+		})
+	})
+	router.Handle(http.MethodDelete, "/items/{id}", func(c Context) {
+		c.Status(http.StatusNoContent)
+	})
+	WithMiddleware(router, func(next HandlerFunc) HandlerFunc {
+		return func(c Context) {
+			c.JSON(http.StatusForbidden, "forbidden")
+		}
+	}).Handle(http.MethodGet, "/forbidden", func(c Context) {
+		c.JSON(http.StatusOK, "ok")
+	})
+}
+
+func (t *_testConformance) do(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testConformance) Test_PathQueryHeaderContext() {
+	w := t.do(http.MethodGet, "/items/12?q=search", "", map[string]string{"X-Test": "header"})
+	t.EqualValues(http.StatusOK, w.Code)
+	t.EqualValues("reply", w.Header().Get("X-Reply"))
+	t.Contains(w.Header().Get("Content-Type"), "application/json")
+	var res map[string]interface{}
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.EqualValues(map[string]interface{}{
+		"id":     "12",
+		"q":      "search",
+		"header": "header",
+		"ctx":    "value",
+		"method": http.MethodGet,
+	}, res)
+}
+
+func (t *_testConformance) Test_BindQuery() {
+	w := t.do(http.MethodGet, "/items?page=3", "", nil)
+	t.EqualValues(http.StatusOK, w.Code)
+	t.EqualValues("3", w.Body.String())
+}
+
+func (t *_testConformance) Test_BindJSON() {
+	w := t.do(http.MethodPost, "/items", `{"name":"foo"}`, nil)
+	t.EqualValues(http.StatusCreated, w.Code)
+	t.EqualValues(`"foo"`, w.Body.String())
+}
+
+func (t *_testConformance) Test_BindJSON_Validate() {
+	w := t.do(http.MethodPost, "/items", `{}`, nil)
+	t.EqualValues(http.StatusBadRequest, w.Code)
+}
+
+func (t *_testConformance) Test_Status() {
+	w := t.do(http.MethodDelete, "/items/1", "", nil)
+	t.EqualValues(http.StatusNoContent, w.Code)
+	t.Empty(w.Body.String())
+}
+
+func (t *_testConformance) Test_MiddlewareStop() {
+	w := t.do(http.MethodGet, "/forbidden", "", nil)
+	t.EqualValues(http.StatusForbidden, w.Code)
+	t.EqualValues(`"forbidden"`, w.Body.String())
+}
+
+func (t *_testConformance) Test_MethodNotMatch() {
+	w := t.do(http.MethodPut, "/items/1", "", nil)
+	t.True(w.Code == http.StatusNotFound || w.Code == http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite.Run(t, &_testConformance{newServer: func() (Router, http.Handler) {
+		engine := gin.New()
+		return Gin(engine.Group("/")), engine
+	}})
+}
+
+func TestServeMux(t *testing.T) {
+	suite.Run(t, &_testConformance{newServer: func() (Router, http.Handler) {
+		mux := http.NewServeMux()
+		return ServeMux(mux, ""), mux
+	}})
+}
+
+func TestChi(t *testing.T) {
+	suite.Run(t, &_testConformance{newServer: func() (Router, http.Handler) {
+		router := chi.NewRouter()
+		return Chi(router), router
+	}})
+}
+
+func TestEcho(t *testing.T) {
+	suite.Run(t, &_testConformance{newServer: func() (Router, http.Handler) {
+		e := echo.New()
+		return Echo(e), e
+	}})
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type httpContext struct {
+	writer  *responseWriter
+	request *http.Request
+	param   func(name string) string
+}
+
+// NewContext Context over net/http, param lookups the path parameters
+func NewContext(w http.ResponseWriter, r *http.Request, param func(name string) string) Context {
+	return &httpContext{
+		writer:  &responseWriter{ResponseWriter: w},
+		request: r,
+		param:   param,
+	}
+}
+
+func (c *httpContext) Context() context.Context {
+	return c.request.Context()
+}
+
+func (c *httpContext) SetContext(ctx context.Context) {
+	c.request = c.request.WithContext(ctx)
+}
+
+func (c *httpContext) Request() *http.Request {
+	return c.request
+}
+
+func (c *httpContext) Param(name string) string {
+	return c.param(name)
+}
+
+func (c *httpContext) Query(name string) string {
+	return c.request.URL.Query().Get(name)
+}
+
+func (c *httpContext) Header(name string) string {
+	return c.request.Header.Get(name)
+}
+
+func (c *httpContext) SetHeader(name, value string) {
+	c.writer.Header().Set(name, value)
+}
+
+func (c *httpContext) BindJSON(v interface{}) error {
+	return binding.JSON.Bind(c.request, v)
+}
+
+func (c *httpContext) BindQuery(v interface{}) error {
+	return binding.Query.Bind(c.request, v)
+}
+
+func (c *httpContext) JSON(code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.writer.WriteHeader(code)
+	_, _ = c.writer.Write(b)
+}
+
+func (c *httpContext) Status(code int) {
+	c.writer.WriteHeader(code)
+}
+
+func (c *httpContext) Written() bool {
+	return c.writer.written
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// DecodeJSON decode and validate the json b like Context.BindJSON, e.g. an item of a batch
+func DecodeJSON(b []byte, v interface{}) error {
+	return binding.JSON.BindBody(b, v)
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// EchoRoutes *echo.Echo or *echo.Group
+type EchoRoutes interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Echo Router over echo
+func Echo(routes EchoRoutes) Router {
+	return &echoRouter{routes: routes}
+}
+
+type echoRouter struct {
+	routes EchoRoutes
+}
+
+func (e *echoRouter) Handle(method, path string, handler HandlerFunc) {
+	e.routes.Add(method, colonPath(path), func(ctx echo.Context) error {
+		c := NewContext(ctx.Response(), ctx.Request(), ctx.Param)
+		handler(c)
+		ctx.SetRequest(c.Request())
+		return nil
+	})
+}
@@ -0,0 +1,22 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Gin Router over gin, e.g. Gin(engine.Group("/api"))
+func Gin(routes gin.IRoutes) Router {
+	return &ginRouter{routes: routes}
+}
+
+type ginRouter struct {
+	routes gin.IRoutes
+}
+
+func (g *ginRouter) Handle(method, path string, handler HandlerFunc) {
+	g.routes.Handle(method, colonPath(path), func(ctx *gin.Context) {
+		c := NewContext(ctx.Writer, ctx.Request, ctx.Param)
+		handler(c)
+		ctx.Request = c.Request()
+	})
+}
@@ -0,0 +1,21 @@
+package web
+
+import (
+	"net/http"
+)
+
+// ServeMux Router over the method and wildcard patterns of http.ServeMux, prefix is prepended to every path
+func ServeMux(mux *http.ServeMux, prefix string) Router {
+	return &serveMuxRouter{mux: mux, prefix: prefix}
+}
+
+type serveMuxRouter struct {
+	mux    *http.ServeMux
+	prefix string
+}
+
+func (s *serveMuxRouter) Handle(method, path string, handler HandlerFunc) {
+	s.mux.HandleFunc(method+" "+s.prefix+path, func(w http.ResponseWriter, r *http.Request) {
+		handler(NewContext(w, r, r.PathValue))
+	})
+}
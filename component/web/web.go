@@ -0,0 +1,75 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Context framework neutral request and response of a handler
+type Context interface {
+	// Context the context.Context of the request
+	Context() context.Context
+	// SetContext replace the context.Context of the request, e.g. to carry the principal
+	SetContext(ctx context.Context)
+	Request() *http.Request
+	// Param path parameter declared as {name} in the route path
+	Param(name string) string
+	Query(name string) string
+	Header(name string) string
+	SetHeader(name, value string)
+	// BindJSON decode and validate the json body
+	BindJSON(v interface{}) error
+	// BindQuery decode and validate the query with the form tag
+	BindQuery(v interface{}) error
+	JSON(code int, v interface{})
+	// Status write the status code without body
+	Status(code int)
+	// Written whether the response is written
+	Written() bool
+}
+
+// HandlerFunc framework neutral handler
+type HandlerFunc func(Context)
+
+// Middleware wrap a HandlerFunc, it does not call next to stop the chain
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Router register HandlerFunc to a web framework,
+// path parameters are declared as {name} whatever the framework is
+type Router interface {
+	Handle(method, path string, handler HandlerFunc)
+}
+
+// Chain wrap handler with middlewares, the first one is the outermost
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// WithMiddleware router applying middlewares to every handler
+func WithMiddleware(router Router, middlewares ...Middleware) Router {
+	return &middlewareRouter{router: router, middlewares: middlewares}
+}
+
+type middlewareRouter struct {
+	router      Router
+	middlewares []Middleware
+}
+
+func (m *middlewareRouter) Handle(method, path string, handler HandlerFunc) {
+	m.router.Handle(method, path, Chain(handler, m.middlewares...))
+}
+
+// colonPath convert {name} to :name
+func colonPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,97 @@
+package gestful
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-gosh/gestful/component/openapi"
+	"github.com/go-gosh/gestful/component/service"
+	"github.com/go-gosh/gestful/component/web"
+)
+
+// IntrospectionPath default path of Registry.Introspect
+const IntrospectionPath = "/_resources"
+
+// Entry resource of a Registry, e.g. a ResourceBuilder
+type Entry interface {
+	Name() string
+	Info() ResourceInfo
+	Register(router web.Router)
+	Describe(doc *openapi.Document)
+}
+
+// ResourceInfo introspection of a resource
+type ResourceInfo struct {
+	Name       string          `json:"name"`
+	Entity     string          `json:"entity"`
+	Operations []OperationInfo `json:"operations"`
+}
+
+type OperationInfo struct {
+	Operation service.Operation `json:"operation"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+}
+
+// Registry central registry of the resources, used for the route registration, the docs generation and the introspection
+type Registry struct {
+	mu        sync.RWMutex
+	resources []Entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add add the resources, it panics when a name is already registered
+func (r *Registry) Add(resources ...Entry) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, resource := range resources {
+		for _, registered := range r.resources {
+			if registered.Name() == resource.Name() {
+				panic(fmt.Sprintf("gestful: resource %s is already registered", resource.Name()))
+			}
+		}
+		r.resources = append(r.resources, resource)
+	}
+	return r
+}
+
+func (r *Registry) entries() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Entry(nil), r.resources...)
+}
+
+// Resources the introspection of the resources in the registration order
+func (r *Registry) Resources() []ResourceInfo {
+	entries := r.entries()
+	res := make([]ResourceInfo, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, entry.Info())
+	}
+	return res
+}
+
+// Register register the routes of the resources to router
+func (r *Registry) Register(router web.Router) {
+	for _, entry := range r.entries() {
+		entry.Register(router)
+	}
+}
+
+// Describe describe the routes of the resources into doc
+func (r *Registry) Describe(doc *openapi.Document) {
+	for _, entry := range r.entries() {
+		entry.Describe(doc)
+	}
+}
+
+// Introspect serve the introspection of the resources at path, e.g. IntrospectionPath
+func (r *Registry) Introspect(router web.Router, path string) {
+	router.Handle(http.MethodGet, path, func(ctx web.Context) {
+		ctx.JSON(http.StatusOK, r.Resources())
+	})
+}
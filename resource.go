@@ -0,0 +1,225 @@
+package gestful
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-gosh/gestful/component/idempotency"
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/observe"
+	"github.com/go-gosh/gestful/component/openapi"
+	"github.com/go-gosh/gestful/component/service"
+	"github.com/go-gosh/gestful/component/web"
+	"gorm.io/gorm"
+)
+
+// Operations of a resource
+const (
+	LIST   = service.OperationList
+	GET    = service.OperationGet
+	CREATE = service.OperationCreate
+	UPDATE = service.OperationUpdate
+	DELETE = service.OperationDelete
+)
+
+var allOperations = []service.Operation{LIST, CREATE, GET, UPDATE, DELETE}
+
+// ResourceBuilder configuration of a resource made by Resource, it is built by the first call of Service,
+// e.g. when it is registered, and it is not to be changed after that
+type ResourceBuilder[T any] struct {
+	name        string
+	mapper      mapper.BaseMapper[T]
+	crud        service.CrudService[T, uint]
+	create      service.CreateRequest[T]
+	page        service.PageRequest
+	update      service.UpdateRequest
+	operations  []service.Operation
+	middlewares []web.Middleware
+	observer    *observe.Observer
+	service     service.HTTPService[T, mapper.PageRes[T]]
+}
+
+// Resource builder of the resource of T served at /name,
+// its requests are the base ones and all its operations are enabled by default
+func Resource[T any](name string) *ResourceBuilder[T] {
+	return &ResourceBuilder[T]{
+		name:       name,
+		create:     service.BaseCreateRequest[T]{},
+		page:       service.BasePageRequest{},
+		update:     service.BaseUpdateRequest{},
+		operations: allOperations,
+	}
+}
+
+// Mapper the mapper of the entities
+func (r *ResourceBuilder[T]) Mapper(m mapper.BaseMapper[T]) *ResourceBuilder[T] {
+	r.mapper = m
+	return r
+}
+
+// DB the mapper of the entities in db, e.g. a named datasource of datasource.Sources
+func (r *ResourceBuilder[T]) DB(db *gorm.DB, opts ...mapper.Option) *ResourceBuilder[T] {
+	return r.Mapper(mapper.NewBaseMapper[T](db, opts...))
+}
+
+// Crud the crud service of the entities, it takes precedence over Mapper
+func (r *ResourceBuilder[T]) Crud(crud service.CrudService[T, uint]) *ResourceBuilder[T] {
+	r.crud = crud
+	return r
+}
+
+// Create the create request, its type is bound from the json body
+func (r *ResourceBuilder[T]) Create(req service.CreateRequest[T]) *ResourceBuilder[T] {
+	r.create = req
+	return r
+}
+
+// Page the page request, its type is bound from the query
+func (r *ResourceBuilder[T]) Page(req service.PageRequest) *ResourceBuilder[T] {
+	r.page = req
+	return r
+}
+
+// Update the update request, its type is bound from the json body
+func (r *ResourceBuilder[T]) Update(req service.UpdateRequest) *ResourceBuilder[T] {
+	r.update = req
+	return r
+}
+
+// ReadOnly only enable LIST and GET
+func (r *ResourceBuilder[T]) ReadOnly() *ResourceBuilder[T] {
+	return r.Only(LIST, GET)
+}
+
+// Only only enable operations
+func (r *ResourceBuilder[T]) Only(operations ...service.Operation) *ResourceBuilder[T] {
+	enabled := make([]service.Operation, 0, len(operations))
+	for _, op := range allOperations {
+		if hasOperation(operations, op) {
+			enabled = append(enabled, op)
+		}
+	}
+	r.operations = enabled
+	return r
+}
+
+// Except disable operations
+func (r *ResourceBuilder[T]) Except(operations ...service.Operation) *ResourceBuilder[T] {
+	enabled := make([]service.Operation, 0, len(r.operations))
+	for _, op := range r.operations {
+		if !hasOperation(operations, op) {
+			enabled = append(enabled, op)
+		}
+	}
+	r.operations = enabled
+	return r
+}
+
+// Middleware wrap the handlers of the resource
+func (r *ResourceBuilder[T]) Middleware(middlewares ...web.Middleware) *ResourceBuilder[T] {
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+// Idempotent make the POST, PUT and PATCH requests with an Idempotency-Key header idempotent, see idempotency.Middleware
+func (r *ResourceBuilder[T]) Idempotent(store idempotency.Store, opts ...idempotency.Option) *ResourceBuilder[T] {
+	return r.Middleware(idempotency.Middleware(store, opts...))
+}
+
+// Observe trace, measure and log the handlers of the operations and the mapper of the resource by o,
+// the handlers are observed outside the middlewares, so the requests they reject are observed too.
+// The mapper of a crud service can not be observed, so Service panics when both Crud and Observe are set,
+// observe the mapper of the crud service by observe.NewMapper instead
+func (r *ResourceBuilder[T]) Observe(o *observe.Observer) *ResourceBuilder[T] {
+	r.observer = o
+	return r
+}
+
+// Service the service of the resource, e.g. to register its hooks and policy,
+// it panics without a mapper or a crud service and with both a crud service and an observer
+func (r *ResourceBuilder[T]) Service() service.HTTPService[T, mapper.PageRes[T]] {
+	if r.service != nil {
+		return r.service
+	}
+	crud := r.crud
+	if crud != nil && r.observer != nil {
+		panic(fmt.Sprintf("gestful: resource %s can not observe the mapper of its crud service, observe it by observe.NewMapper", r.name))
+	}
+	if crud == nil {
+		if r.mapper == nil {
+			panic(fmt.Sprintf("gestful: resource %s has neither a mapper nor a crud service", r.name))
+		}
+		m := r.mapper
+		if r.observer != nil {
+			m = observe.NewMapper(m, r.name, r.observer)
+		}
+		crud = service.NewCrudService[T, uint](m)
+	}
+	restful := service.NewRestfulService[T, service.BaseCreateRequest[T], service.BasePageRequest, service.BaseUpdateRequest](crud)
+	r.service = service.NewHTTPService[T, mapper.PageRes[T]](restful, service.NewRequestBinder[T](r.create, r.page, r.update))
+	return r.service
+}
+
+func (r *ResourceBuilder[T]) Name() string {
+	return r.name
+}
+
+// Operations the enabled operations
+func (r *ResourceBuilder[T]) Operations() []service.Operation {
+	return r.operations
+}
+
+// Routes the routes of the enabled operations
+func (r *ResourceBuilder[T]) Routes() []service.Route {
+	if len(r.operations) == 0 {
+		return nil
+	}
+	return service.Routes(r.name, r.Service(), r.operations...)
+}
+
+// Register register the routes to router, the handlers are wrapped by the middlewares and then by the observer
+func (r *ResourceBuilder[T]) Register(router web.Router) {
+	for _, route := range r.Routes() {
+		handler := web.Chain(route.Handler, r.middlewares...)
+		if r.observer != nil {
+			handler = r.observer.Handler(r.name, string(route.Operation))(handler)
+		}
+		router.Handle(route.Method, route.Path, handler)
+	}
+}
+
+// Describe describe the routes into doc
+func (r *ResourceBuilder[T]) Describe(doc *openapi.Document) {
+	if len(r.operations) == 0 {
+		return
+	}
+	service.Describe(doc, r.name, service.RouteTypes{
+		Entity: reflect.TypeFor[T](),
+		Create: reflect.TypeOf(r.create),
+		Page:   reflect.TypeOf(r.page),
+		Update: reflect.TypeOf(r.update),
+		Result: reflect.TypeFor[mapper.PageRes[T]](),
+	}, r.operations...)
+}
+
+// Info the introspection of the resource
+func (r *ResourceBuilder[T]) Info() ResourceInfo {
+	info := ResourceInfo{
+		Name:       r.name,
+		Entity:     reflect.TypeFor[T]().String(),
+		Operations: make([]OperationInfo, 0, len(r.operations)),
+	}
+	for _, route := range r.Routes() {
+		info.Operations = append(info.Operations, OperationInfo{Operation: route.Operation, Method: route.Method, Path: route.Path})
+	}
+	return info
+}
+
+func hasOperation(operations []service.Operation, op service.Operation) bool {
+	for _, o := range operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
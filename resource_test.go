@@ -0,0 +1,171 @@
+package gestful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-gosh/gestful/component/mapper"
+	"github.com/go-gosh/gestful/component/observe"
+	"github.com/go-gosh/gestful/component/openapi"
+	"github.com/go-gosh/gestful/component/service"
+	"github.com/go-gosh/gestful/component/web"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type _testUser struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `json:"name"`
+}
+
+type _testUserCreate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func (c _testUserCreate) MakeCreate() (*_testUser, error) {
+	return &_testUser{Name: c.Name}, nil
+}
+
+type _testResource struct {
+	suite.Suite
+	db       *gorm.DB
+	registry *Registry
+	handler  http.Handler
+	called   int
+}
+
+func (t *_testResource) SetupTest() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(t.db.AutoMigrate(&_testUser{}))
+	t.called = 0
+	m := mapper.NewBaseMapper[_testUser](t.db)
+	t.registry = NewRegistry().Add(
+		Resource[_testUser]("users").Mapper(m).Create(_testUserCreate{}).Except(DELETE).
+			Middleware(func(next web.HandlerFunc) web.HandlerFunc {
+				return func(ctx web.Context) {
+					t.called++
+					next(ctx)
+				}
+			}),
+		Resource[_testUser]("readonly_users").Mapper(m).ReadOnly(),
+	)
+	mux := http.NewServeMux()
+	router := web.ServeMux(mux, "")
+	t.registry.Register(router)
+	t.registry.Introspect(router, IntrospectionPath)
+	t.handler = mux
+}
+
+func (t *_testResource) TearDownTest() {
+	db, err := t.db.DB()
+	t.Require().NoError(err)
+	t.Require().NoError(db.Close())
+}
+
+func (t *_testResource) do(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (t *_testResource) Test_Routes() {
+	t.NotEqualValues(http.StatusOK, t.do(http.MethodPost, "/users", `{}`).Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodPost, "/users", `{"name":"foo"}`).Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodGet, "/users/1", "").Code)
+	t.EqualValues(http.StatusOK, t.do(http.MethodGet, "/readonly_users/1", "").Code)
+	t.EqualValues(http.StatusMethodNotAllowed, t.do(http.MethodDelete, "/users/1", "").Code)
+	t.EqualValues(http.StatusMethodNotAllowed, t.do(http.MethodPost, "/readonly_users", `{"name":"bar"}`).Code)
+	t.Equal(3, t.called)
+}
+
+func (t *_testResource) Test_Introspect() {
+	w := t.do(http.MethodGet, IntrospectionPath, "")
+	t.Require().EqualValues(http.StatusOK, w.Code)
+	var res []ResourceInfo
+	t.Require().NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	t.Require().Len(res, 2)
+	t.Equal("users", res[0].Name)
+	t.Equal("gestful._testUser", res[0].Entity)
+	t.Equal([]OperationInfo{
+		{LIST, http.MethodGet, "/users"},
+		{CREATE, http.MethodPost, "/users"},
+		{CREATE, http.MethodPost, "/users/batch"},
+		{UPDATE, http.MethodPatch, "/users/batch"},
+		{LIST, http.MethodGet, "/users/_aggregate"},
+		{GET, http.MethodGet, "/users/{id}"},
+		{UPDATE, http.MethodPut, "/users/{id}"},
+	}, res[0].Operations)
+	t.Len(res[1].Operations, 3)
+}
+
+func (t *_testResource) Test_Describe() {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	t.registry.Describe(doc)
+	t.Nil(doc.Paths["/users/{id}"].Delete)
+	t.NotNil(doc.Paths["/users/{id}"].Put)
+	t.Equal(openapi.Ref("_testUserCreate"), doc.Paths["/users"].Post.RequestBody.Content["application/json"].Schema)
+	t.Nil(doc.Paths["/readonly_users"].Post)
+	t.NotNil(doc.Paths["/readonly_users"].Get)
+}
+
+func (t *_testResource) Test_Observe() {
+	metrics := observe.NewMetrics()
+	mux := http.NewServeMux()
+	Resource[_testUser]("observed_users").DB(t.db).Create(_testUserCreate{}).
+		Observe(observe.New(observe.WithMetrics(metrics))).Register(web.ServeMux(mux, ""))
+	req := httptest.NewRequest(http.MethodPost, "/observed_users", strings.NewReader(`{"name":"foo"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	t.EqualValues(http.StatusOK, w.Code)
+	t.NotEmpty(w.Header().Get(observe.RequestIDHeader))
+
+	count, _ := metrics.Count(observe.LayerHTTP, "observed_users", string(CREATE))
+	t.EqualValues(1, count)
+	count, _ = metrics.Count(observe.LayerMapper, "observed_users", "create")
+	t.EqualValues(1, count)
+}
+
+func (t *_testResource) Test_ObserveMiddlewares() {
+	metrics := observe.NewMetrics()
+	mux := http.NewServeMux()
+	Resource[_testUser]("guarded_users").DB(t.db).
+		Middleware(func(next web.HandlerFunc) web.HandlerFunc {
+			return func(ctx web.Context) {
+				ctx.JSON(http.StatusServiceUnavailable, "closed")
+			}
+		}).
+		Observe(observe.New(observe.WithMetrics(metrics))).Register(web.ServeMux(mux, ""))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/guarded_users", nil))
+	t.EqualValues(http.StatusServiceUnavailable, w.Code)
+	t.NotEmpty(w.Header().Get(observe.RequestIDHeader))
+	count, failed := metrics.Count(observe.LayerHTTP, "guarded_users", string(LIST))
+	t.EqualValues(1, count, "the requests rejected by a middleware are observed")
+	t.EqualValues(1, failed)
+}
+
+func (t *_testResource) Test_ObserveCrud() {
+	t.Panics(func() {
+		Resource[_testUser]("crud_users").Crud(service.NewCrudService[_testUser, uint](mapper.NewBaseMapper[_testUser](t.db))).
+			Observe(observe.New()).Service()
+	})
+}
+
+func (t *_testResource) Test_DuplicateName() {
+	t.Panics(func() {
+		t.registry.Add(Resource[_testUser]("users").Mapper(mapper.NewBaseMapper[_testUser](t.db)))
+	})
+}
+
+func TestResource(t *testing.T) {
+	suite.Run(t, &_testResource{})
+}